@@ -16,13 +16,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/v1/events/close": {
+        "/v1/events/chat/delete": {
             "post": {
                 "security": [
                     {
                         "UserAuth": []
                     }
                 ],
+                "description": "Для владельца, со-организаторов и модераторов. Удаление приходит в вебсокет как chatMessageDeleted",
                 "consumes": [
                     "application/json"
                 ],
@@ -32,7 +33,7 @@ const docTemplate = `{
                 "tags": [
                     "events"
                 ],
-                "summary": "Закрыть эвент",
+                "summary": "Удалить сообщение из чата эвента",
                 "parameters": [
                     {
                         "description": "body",
@@ -40,7 +41,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.eventIDRequest"
+                            "$ref": "#/definitions/v1.deleteChatMessageRequest"
                         }
                     }
                 ],
@@ -66,13 +67,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/events/create": {
+        "/v1/events/chat/history": {
             "post": {
                 "security": [
                     {
                         "UserAuth": []
                     }
                 ],
+                "description": "Сообщения возвращаются в хронологическом порядке. beforeId - сообщения до указанного, afterId - после, без курсора - последние\nАвторизация необязательна, доступ к закрытым эвентам как в /v1/events/get",
                 "consumes": [
                     "application/json"
                 ],
@@ -82,7 +84,7 @@ const docTemplate = `{
                 "tags": [
                     "events"
                 ],
-                "summary": "Создать эвент",
+                "summary": "Получить историю чата эвента",
                 "parameters": [
                     {
                         "description": "body",
@@ -90,7 +92,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.createEventRequest"
+                            "$ref": "#/definitions/v1.chatHistoryRequest"
                         }
                     }
                 ],
@@ -106,7 +108,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/v1.eventResponse"
+                                            "$ref": "#/definitions/v1.chatHistoryResponse"
                                         }
                                     }
                                 }
@@ -116,8 +118,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/events/get": {
+        "/v1/events/chat/send": {
             "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Для клиентов без вебсокета, сообщение приходит подписчикам как chatMessage. replyToId и attachments (до 10 id медиа) необязательны.\nДоступ к закрытым эвентам как в /v1/events/get",
                 "consumes": [
                     "application/json"
                 ],
@@ -127,7 +135,7 @@ const docTemplate = `{
                 "tags": [
                     "events"
                 ],
-                "summary": "Получить эвент по идентификатору",
+                "summary": "Отправить сообщение в чат эвента",
                 "parameters": [
                     {
                         "description": "body",
@@ -135,7 +143,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.eventIDRequest"
+                            "$ref": "#/definitions/v1.sendChatMessageRequest"
                         }
                     }
                 ],
@@ -151,7 +159,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/v1.eventResponse"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -161,7 +169,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/events/media/add": {
+        "/v1/events/close": {
             "post": {
                 "security": [
                     {
@@ -169,7 +177,7 @@ const docTemplate = `{
                     }
                 ],
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
@@ -177,21 +185,16 @@ const docTemplate = `{
                 "tags": [
                     "events"
                 ],
-                "summary": "добавить медиа к евенту",
+                "summary": "Закрыть эвент",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "event id",
-                        "name": "eventId",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "file",
-                        "name": "media",
-                        "in": "formData",
-                        "required": true
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.eventIDRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -216,13 +219,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/events/media/remove": {
+        "/v1/events/clusters": {
             "post": {
-                "security": [
-                    {
-                        "UserAuth": []
-                    }
-                ],
+                "description": "Эвенты, попавшие в одну ячейку сетки, объединяются в кластер с центром масс, кол-вом эвентов и суммой пользователей.\nОдиночные эвенты и все эвенты начиная с зума 15 приходят в events",
                 "consumes": [
                     "application/json"
                 ],
@@ -232,7 +231,7 @@ const docTemplate = `{
                 "tags": [
                     "events"
                 ],
-                "summary": "удалить медиа из евента",
+                "summary": "Получить эвенты по области, сгруппированные в кластеры по уровню зума карты",
                 "parameters": [
                     {
                         "description": "body",
@@ -240,7 +239,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.removeMediaRequest"
+                            "$ref": "#/definitions/v1.getClustersRequest"
                         }
                     }
                 ],
@@ -256,7 +255,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/v1.eventClusters"
                                         }
                                     }
                                 }
@@ -266,8 +265,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/events/range": {
+        "/v1/events/create": {
             "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Если startsAt в будущем, эвент создается в состоянии 2 (запланирован) и открывается автоматически.\nПо наступлению endsAt или после долгого бездействия эвент закрывается автоматически.\nvisibility: 0 - публичный (по умолчанию), 1 - по ссылке-приглашению, 2 - по одобрению владельца\ncoordinates.x - долгота от -180 до 180, coordinates.y - широта от -90 до 90, иначе CoordinatesInvalid",
                 "consumes": [
                     "application/json"
                 ],
@@ -277,7 +282,7 @@ const docTemplate = `{
                 "tags": [
                     "events"
                 ],
-                "summary": "Получить эвенты по области",
+                "summary": "Создать эвент",
                 "parameters": [
                     {
                         "description": "body",
@@ -285,7 +290,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.getByRangeRequest"
+                            "$ref": "#/definitions/v1.createEventRequest"
                         }
                     }
                 ],
@@ -301,7 +306,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/v1.eventRangeData"
+                                            "$ref": "#/definitions/v1.eventResponse"
                                         }
                                     }
                                 }
@@ -311,13 +316,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/events/update": {
+        "/v1/events/get": {
             "post": {
                 "security": [
                     {
                         "UserAuth": []
                     }
                 ],
+                "description": "Авторизация необязательна. Закрытый эвент доступен владельцу, участникам и, если он по ссылке, по inviteToken",
                 "consumes": [
                     "application/json"
                 ],
@@ -327,7 +333,7 @@ const docTemplate = `{
                 "tags": [
                     "events"
                 ],
-                "summary": "Обновить эвент",
+                "summary": "Получить эвент по идентификатору",
                 "parameters": [
                     {
                         "description": "body",
@@ -335,7 +341,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.updateEventRequest"
+                            "$ref": "#/definitions/v1.getEventRequest"
                         }
                     }
                 ],
@@ -351,7 +357,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/v1.eventResponse"
                                         }
                                     }
                                 }
@@ -361,25 +367,33 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/media": {
+        "/v1/events/invite": {
             "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Только для эвентов с visibility 1. Токен передается в inviteToken при получении эвента и подключении к вебсокету",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "media"
+                    "events"
                 ],
-                "summary": "Загрузить медиафайл",
+                "summary": "Создать ссылку-приглашение в эвент",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "file",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.eventIDRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -394,7 +408,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/v1.inviteResponse"
                                         }
                                     }
                                 }
@@ -404,25 +418,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/media/metadata/{id}": {
-            "get": {
+        "/v1/events/join/approve": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
                 "consumes": [
-                    "*/*"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "media"
+                    "events"
                 ],
-                "summary": "Получить метаинформацию о файле",
+                "summary": "Одобрить заявку на вступление в эвент",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "media ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.reviewJoinRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -437,7 +458,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/v1.fileMetadataResponse"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -447,54 +468,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/media/{id}": {
-            "get": {
-                "consumes": [
-                    "*/*"
-                ],
-                "tags": [
-                    "media"
-                ],
-                "summary": "Получить медиафайл",
-                "parameters": [
+        "/v1/events/join/reject": {
+            "post": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "media ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "UserAuth": []
                     }
                 ],
-                "responses": {
-                    "200": {
-                        "description": ""
-                    },
-                    "400": {
-                        "description": ""
-                    },
-                    "404": {
-                        "description": ""
-                    }
-                }
-            },
-            "delete": {
                 "consumes": [
-                    "*/*"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "media"
+                    "events"
                 ],
-                "summary": "Удалить файл",
+                "summary": "Отклонить заявку на вступление в эвент",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "media ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.reviewJoinRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -519,8 +518,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/users/create": {
+        "/v1/events/join/request": {
             "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Только для эвентов с visibility 2",
                 "consumes": [
                     "application/json"
                 ],
@@ -528,9 +533,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "events"
                 ],
-                "summary": "Создать пользователя",
+                "summary": "Отправить заявку на вступление в эвент",
                 "parameters": [
                     {
                         "description": "body",
@@ -538,7 +543,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.createUserRequest"
+                            "$ref": "#/definitions/v1.eventIDRequest"
                         }
                     }
                 ],
@@ -554,7 +559,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/v1.loginResponse"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -564,8 +569,13 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/users/login": {
+        "/v1/events/join/requests": {
             "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
                 "consumes": [
                     "application/json"
                 ],
@@ -573,9 +583,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "events"
                 ],
-                "summary": "Войти в систему",
+                "summary": "Получить заявки на вступление в эвент",
                 "parameters": [
                     {
                         "description": "body",
@@ -583,7 +593,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.loginUserRequest"
+                            "$ref": "#/definitions/v1.eventIDRequest"
                         }
                     }
                 ],
@@ -599,7 +609,10 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/v1.loginResponse"
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/v1.joinRequest"
+                                            }
                                         }
                                     }
                                 }
@@ -609,13 +622,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/users/media/set": {
+        "/v1/events/media/add": {
             "post": {
                 "security": [
                     {
                         "UserAuth": []
                     }
                 ],
+                "description": "Картинка (jpeg, png, gif, webp, heic) или видео (mp4, mov, webm), тип определяется по содержимому файла.\nОшибки: UnsupportedMediaType - другой тип, FileTooLarge - больше MEDIA_MAX_IMAGE_SIZE_MB для картинок\nили MEDIA_MAX_VIDEO_SIZE_MB для видео, FileIsEmpty - пустой файл, FileIsMissing - нет файла в форме\nИз картинок удаляются метаданные (EXIF с геолокацией и устройством), кроме ориентации, если не MEDIA_STRIP_METADATA=false",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -623,14 +637,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "events"
                 ],
-                "summary": "Установить пользователю картинку",
+                "summary": "добавить медиа к евенту",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "event id",
+                        "name": "eventId",
+                        "in": "formData",
+                        "required": true
+                    },
                     {
                         "type": "file",
                         "description": "file",
-                        "name": "image",
+                        "name": "media",
                         "in": "formData",
                         "required": true
                     }
@@ -647,7 +668,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/v1.setImageResponse"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -657,7 +678,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/users/password/change": {
+        "/v1/events/media/remove": {
             "post": {
                 "security": [
                     {
@@ -671,9 +692,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "events"
                 ],
-                "summary": "Изменить пароль",
+                "summary": "удалить медиа из евента",
                 "parameters": [
                     {
                         "description": "body",
@@ -681,7 +702,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.changePasswordRequest"
+                            "$ref": "#/definitions/v1.removeMediaRequest"
                         }
                     }
                 ],
@@ -697,7 +718,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/v1.tokenResponse"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -707,13 +728,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/v1/users/update": {
+        "/v1/events/nearby": {
             "post": {
-                "security": [
-                    {
-                        "UserAuth": []
-                    }
-                ],
+                "description": "coordinates.x - долгота, coordinates.y - широта, distance - радиус в метрах. В ответе distance - расстояние до эвента в метрах",
                 "consumes": [
                     "application/json"
                 ],
@@ -721,16 +738,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "events"
                 ],
-                "summary": "Обновить информацию о пользователе",
+                "summary": "Получить эвенты в радиусе от точки, отсортированные по расстоянию",
                 "parameters": [
                     {
                         "description": "body",
                         "name": "request",
                         "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.updateUserRequest"
+                            "$ref": "#/definitions/v1.getNearbyRequest"
                         }
                     }
                 ],
@@ -746,7 +764,10 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "result": {
-                                            "$ref": "#/definitions/v1.tokenResponse"
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/v1.eventRangeData"
+                                            }
                                         }
                                     }
                                 }
@@ -755,41 +776,1204 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "v1.apiResponse": {
-            "type": "object",
-            "properties": {
-                "errors": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/v1.errorResponse"
-                    }
-                },
-                "isSuccess": {
-                    "type": "boolean"
-                },
-                "result": {}
-            }
         },
-        "v1.changePasswordRequest": {
-            "type": "object",
-            "properties": {
-                "confirmPassword": {
-                    "type": "string"
-                },
-                "password": {
+        "/v1/events/range": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Получить эвенты по области",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.getByRangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "$ref": "#/definitions/v1.eventRangeData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/events/roles/grant": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Только для владельца. role: 1 - модератор, 2 - со-организатор. Со-организатор может редактировать эвент,\nуправлять медиа и доступом, модератор - удалять медиа. Изменение приходит в вебсокет как roleChanged",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Выдать роль в эвенте",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.grantRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/events/roles/revoke": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Только для владельца, пользователь становится гостем",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Отозвать роль в эвенте",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.revokeRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/events/stream": {
+            "get": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Замена вебсокету эвента для клиентов, у которых он недоступен. События eventUpdated, chatMessage, mediaAdded,\nmediaRemoved и closeEvent, id события - номер сообщения эвента, data - json как в payload вебсокета v2.\nПри переподключении с заголовком Last-Event-ID досылаются пропущенные события, если их уже нет - приходит resync.\nАвторизация необязательна, токен можно передать в accessToken, доступ к закрытым эвентам как в /v1/events/get.\nСообщения в чат отправляются через /v1/events/chat/send",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Обновления эвента через Server-Sent Events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id эвента",
+                        "name": "eventId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "токен приглашения",
+                        "name": "inviteToken",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "jwt токен, если нельзя передать заголовок",
+                        "name": "accessToken",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/v1/events/update": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "coordinates.x - долгота от -180 до 180, coordinates.y - широта от -90 до 90, иначе CoordinatesInvalid.\nЭвенты, созданные до проверки с координатами вне этих границ, не попадают в поиск по области, кластеры и nearby,\nпока координаты не обновят этим методом",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Обновить эвент",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.updateEventRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/events/users/ban": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Для владельца, со-организаторов и модераторов, только пользователям с ролью ниже своей.\nПользователь сразу убирается из списка пользователей, теряет участие, роль и заявку на вступление,\nполучает userBanned в вебсокет и отключается,\nповторное подключение и получение эвента возвращают UserBanned",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Забанить пользователя в эвенте",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.moderateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/events/users/kick": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Для владельца, со-организаторов и модераторов, только пользователям с ролью ниже своей.\nПользователь получает userKicked в вебсокет и отключается, но может подключиться снова",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Выгнать пользователя из эвента",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.moderateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/events/users/mute": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Для владельца, со-организаторов и модераторов, только пользователям с ролью ниже своей. minutes - от 1 до 10080,\nповторный вызов заменяет срок. Пока срок не истек, сообщения пользователя отклоняются с UserMuted.\nМьют приходит в вебсокет как userMuted",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Запретить пользователю писать в чат эвента",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.muteUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/media": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Картинка (jpeg, png, gif, webp, heic) или видео (mp4, mov, webm), тип определяется по содержимому файла.\nОшибки: UnsupportedMediaType - другой тип, FileTooLarge - больше MEDIA_MAX_IMAGE_SIZE_MB для картинок\nили MEDIA_MAX_VIDEO_SIZE_MB для видео, FileIsEmpty - пустой файл, FileIsMissing - нет файла в форме\nИз картинок удаляются метаданные (EXIF с геолокацией и устройством), кроме ориентации, если не MEDIA_STRIP_METADATA=false\nВложением в чат можно отправить только файл, загруженный с авторизацией этим же пользователем, или медиа эвента",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Загрузить медиафайл",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/media/metadata/{id}": {
+            "get": {
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Получить метаинформацию о файле",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "$ref": "#/definitions/v1.fileMetadataResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/media/{id}": {
+            "get": {
+                "description": "Поддерживает Range (перемотка видео) и If-Modified-Since.\nЕсли включены presigned ссылки хранилища S3, отвечает редиректом 302 на файл в хранилище,\nкроме старых файлов не медиа-типов, которые отдаются через API только для скачивания.\nДля картинок size отдает уменьшенную копию в jpeg (64, 256 или 1024 px по большей стороне):\nнаименьшую не меньше size, если такой нет - оригинал. Доступные копии - в variants метаинформации",
+                "consumes": [
+                    "*/*"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Получить медиафайл",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "размер копии картинки в px",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "диапазон байт, например bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": ""
+                    },
+                    "206": {
+                        "description": ""
+                    },
+                    "302": {
+                        "description": ""
+                    },
+                    "304": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "416": {
+                        "description": ""
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Удалить файл",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/create": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Создать пользователя",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.createUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "$ref": "#/definitions/v1.loginResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/login": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Войти в систему",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.loginUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "$ref": "#/definitions/v1.loginResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/logout": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Выйти из системы (отозвать текущую сессию)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/media/set": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "description": "Только картинка (jpeg, png, gif, webp, heic), тип определяется по содержимому файла.\nОшибки: UnsupportedMediaType - другой тип, FileTooLarge - больше MEDIA_MAX_IMAGE_SIZE_MB,\nFileIsEmpty - пустой файл, FileIsMissing - нет файла в форме\nИз картинок удаляются метаданные (EXIF с геолокацией и устройством), кроме ориентации, если не MEDIA_STRIP_METADATA=false",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Установить пользователю картинку",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "file",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "$ref": "#/definitions/v1.setImageResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/password/change": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Изменить пароль",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.changePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "$ref": "#/definitions/v1.tokenResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/password/reset/request": {
+            "post": {
+                "description": "Ответ не зависит от того, зарегистрирован ли номер. На номер - не чаще раза в минуту и не больше 5 раз в час,\nиначе OtpRequestsLimitExceeded. На код дается 5 попыток, дальше OtpAttemptsLimitExceeded",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Запросить код для восстановления пароля по смс",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.passwordResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/password/reset/verify": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Установить новый пароль по коду из смс (все сессии пользователя отзываются)",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.resetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Получить активные сессии пользователя",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/v1.sessionResponse"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/sessions/revoke": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Отозвать сессию пользователя",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.sessionIDRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/token/refresh": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Обновить access токен по refresh токену",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.refreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "$ref": "#/definitions/v1.tokenPairResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/update": {
+            "post": {
+                "security": [
+                    {
+                        "UserAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Обновить информацию о пользователе",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/v1.updateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.apiResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "result": {
+                                            "$ref": "#/definitions/v1.tokenResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "domain.MediaVariant": {
+            "type": "object",
+            "properties": {
+                "contentType": {
+                    "type": "string"
+                },
+                "fileSize": {
+                    "type": "integer"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "v1.apiResponse": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.errorResponse"
+                    }
+                },
+                "isSuccess": {
+                    "type": "boolean"
+                },
+                "result": {}
+            }
+        },
+        "v1.changePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "confirmPassword": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.chatHistoryRequest": {
+            "type": "object",
+            "properties": {
+                "afterId": {
+                    "type": "string"
+                },
+                "beforeId": {
+                    "type": "string"
+                },
+                "eventId": {
                     "type": "string"
+                },
+                "inviteToken": {
+                    "type": "string"
+                },
+                "limit": {
+                    "type": "integer"
+                }
+            }
+        },
+        "v1.chatHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "chatMessages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.chatMessage"
+                    }
+                },
+                "hasMore": {
+                    "type": "boolean"
                 }
             }
         },
         "v1.chatMessage": {
             "type": "object",
             "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.mediaInfo"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "edits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.chatMessageEdit"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
                 "message": {
                     "type": "string"
                 },
+                "reactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.chatReaction"
+                    }
+                },
+                "replyToId": {
+                    "type": "string"
+                },
                 "userId": {
                     "type": "string"
                 },
@@ -801,6 +1985,28 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.chatMessageEdit": {
+            "type": "object",
+            "properties": {
+                "editedAt": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.chatReaction": {
+            "type": "object",
+            "properties": {
+                "emoji": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
         "v1.coordinates": {
             "type": "object",
             "properties": {
@@ -815,30 +2021,67 @@ const docTemplate = `{
         "v1.createEventRequest": {
             "type": "object",
             "properties": {
-                "address": {
+                "address": {
+                    "type": "string"
+                },
+                "coordinates": {
+                    "$ref": "#/definitions/v1.coordinates"
+                },
+                "endsAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "startsAt": {
+                    "type": "string"
+                },
+                "visibility": {
+                    "type": "integer"
+                }
+            }
+        },
+        "v1.createUserRequest": {
+            "type": "object",
+            "properties": {
+                "confirmPassword": {
+                    "type": "string"
+                },
+                "deviceName": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.deleteChatMessageRequest": {
+            "type": "object",
+            "properties": {
+                "eventId": {
                     "type": "string"
                 },
-                "coordinates": {
-                    "$ref": "#/definitions/v1.coordinates"
-                },
-                "name": {
+                "messageId": {
                     "type": "string"
                 }
             }
         },
-        "v1.createUserRequest": {
+        "v1.deviceResponse": {
             "type": "object",
             "properties": {
-                "confirmPassword": {
+                "ipAddress": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
-                "password": {
-                    "type": "string"
-                },
-                "phone": {
+                "userAgent": {
                     "type": "string"
                 }
             }
@@ -851,6 +2094,37 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.eventCluster": {
+            "type": "object",
+            "properties": {
+                "coordinates": {
+                    "$ref": "#/definitions/v1.coordinates"
+                },
+                "eventsCount": {
+                    "type": "integer"
+                },
+                "usersCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "v1.eventClusters": {
+            "type": "object",
+            "properties": {
+                "clusters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.eventCluster"
+                    }
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.eventRangeData"
+                    }
+                }
+            }
+        },
         "v1.eventIDRequest": {
             "type": "object",
             "properties": {
@@ -865,12 +2139,21 @@ const docTemplate = `{
                 "coordinates": {
                     "$ref": "#/definitions/v1.coordinates"
                 },
+                "distance": {
+                    "type": "number"
+                },
                 "id": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
+                "startsAt": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "integer"
+                },
                 "usersCount": {
                     "type": "integer"
                 }
@@ -891,6 +2174,9 @@ const docTemplate = `{
                 "coordinates": {
                     "$ref": "#/definitions/v1.coordinates"
                 },
+                "endsAt": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -906,8 +2192,34 @@ const docTemplate = `{
                 "ownerId": {
                     "type": "string"
                 },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.eventRole"
+                    }
+                },
+                "startsAt": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "integer"
+                },
                 "usersCount": {
                     "type": "integer"
+                },
+                "visibility": {
+                    "type": "integer"
+                }
+            }
+        },
+        "v1.eventRole": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "string"
                 }
             }
         },
@@ -925,6 +2237,12 @@ const docTemplate = `{
                 },
                 "size": {
                     "type": "integer"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.MediaVariant"
+                    }
                 }
             }
         },
@@ -942,6 +2260,84 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.getClustersRequest": {
+            "type": "object",
+            "properties": {
+                "coordinates": {
+                    "$ref": "#/definitions/v1.coordinates"
+                },
+                "horizontalRange": {
+                    "type": "number"
+                },
+                "verticalRange": {
+                    "type": "number"
+                },
+                "zoom": {
+                    "type": "integer"
+                }
+            }
+        },
+        "v1.getEventRequest": {
+            "type": "object",
+            "properties": {
+                "eventId": {
+                    "type": "string"
+                },
+                "inviteToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.getNearbyRequest": {
+            "type": "object",
+            "properties": {
+                "coordinates": {
+                    "$ref": "#/definitions/v1.coordinates"
+                },
+                "distance": {
+                    "type": "number"
+                }
+            }
+        },
+        "v1.grantRoleRequest": {
+            "type": "object",
+            "properties": {
+                "eventId": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.inviteResponse": {
+            "type": "object",
+            "properties": {
+                "inviteToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.joinRequest": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                },
+                "userImageId": {
+                    "type": "string"
+                },
+                "userName": {
+                    "type": "string"
+                }
+            }
+        },
         "v1.loginResponse": {
             "type": "object",
             "properties": {
@@ -962,12 +2358,18 @@ const docTemplate = `{
                 },
                 "phone": {
                     "type": "string"
+                },
+                "refreshToken": {
+                    "type": "string"
                 }
             }
         },
         "v1.loginUserRequest": {
             "type": "object",
             "properties": {
+                "deviceName": {
+                    "type": "string"
+                },
                 "password": {
                     "type": "string"
                 },
@@ -987,6 +2389,47 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.moderateUserRequest": {
+            "type": "object",
+            "properties": {
+                "eventId": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.muteUserRequest": {
+            "type": "object",
+            "properties": {
+                "eventId": {
+                    "type": "string"
+                },
+                "minutes": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.passwordResetRequest": {
+            "type": "object",
+            "properties": {
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.refreshTokenRequest": {
+            "type": "object",
+            "properties": {
+                "refreshToken": {
+                    "type": "string"
+                }
+            }
+        },
         "v1.removeMediaRequest": {
             "type": "object",
             "properties": {
@@ -998,6 +2441,99 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.resetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "confirmPassword": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.reviewJoinRequest": {
+            "type": "object",
+            "properties": {
+                "eventId": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.revokeRoleRequest": {
+            "type": "object",
+            "properties": {
+                "eventId": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.sendChatMessageRequest": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "eventId": {
+                    "type": "string"
+                },
+                "inviteToken": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "replyToId": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.sessionIDRequest": {
+            "type": "object",
+            "properties": {
+                "sessionId": {
+                    "type": "string"
+                }
+            }
+        },
+        "v1.sessionResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "device": {
+                    "$ref": "#/definitions/v1.deviceResponse"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "isCurrent": {
+                    "type": "boolean"
+                },
+                "lastUsedAt": {
+                    "type": "string"
+                }
+            }
+        },
         "v1.setImageResponse": {
             "type": "object",
             "properties": {
@@ -1009,6 +2545,17 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.tokenPairResponse": {
+            "type": "object",
+            "properties": {
+                "accessToken": {
+                    "type": "string"
+                },
+                "refreshToken": {
+                    "type": "string"
+                }
+            }
+        },
         "v1.tokenResponse": {
             "type": "object",
             "properties": {
@@ -17,8 +17,10 @@ import (
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/server"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/broker"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/hash"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/sms"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/storage"
 )
 
@@ -44,32 +46,73 @@ func Run() {
 		return
 	}
 
-	repositories, _, err := repository.NewRepositories(configuration.DbConnection, configuration.DbName)
+	repositories, _, err := repository.NewRepositories(configuration.DbDriver, configuration.DbConnection, configuration.DbName)
 	if err != nil {
 		appLogger.LogError(err)
 		return
 	}
 
 	jwtDuration := time.Hour * 24 * 3
+	refreshTokenDuration := time.Hour * 24 * time.Duration(configuration.RefreshTokenLifeTimeDays)
 	jwtTokenManager := auth.NewJwtManager(configuration.JWTKey, configuration.JWTIssuer, configuration.JWTAudience, jwtDuration)
-	passwordManager, err := hash.NewPasswordHashManager(configuration.HashKey)
+	legacyPasswordManager, err := hash.NewHMACPasswordHashManager(configuration.HashKey)
 	if err != nil {
 		appLogger.LogError(err)
 		return
 	}
 
-	fileStorage, err := storage.NewLocalFileStorage("media")
+	passwordManager := hash.NewArgon2idPasswordHashManager(configuration.PasswordHashParams(), legacyPasswordManager)
+
+	fileStorage, err := storage.NewFileStorage(configuration.StorageDriver, configuration.StoragePath, storage.S3Options{
+		Endpoint:          configuration.S3Endpoint,
+		AccessKey:         configuration.S3AccessKey,
+		SecretKey:         configuration.S3SecretKey,
+		Bucket:            configuration.S3Bucket,
+		Region:            configuration.S3Region,
+		UseSSL:            configuration.S3UseSSL,
+		PresignExpiration: time.Minute * time.Duration(configuration.S3PresignExpirationMinutes),
+	})
+	if err != nil {
+		appLogger.LogError(err)
+		return
+	}
+
+	smsSender, err := sms.NewSMSSender(configuration.SMSDriver, configuration.SMSFilePath, appLogger)
+	if err != nil {
+		appLogger.LogError(err)
+		return
+	}
+
+	messageBroker, err := broker.NewBroker(configuration.BrokerDriver, configuration.BrokerConnection, configuration.BrokerChannel)
 	if err != nil {
 		appLogger.LogError(err)
 		return
 	}
 
-	services, err := service.NewServices(appLogger, repositories, passwordManager, jwtTokenManager, fileStorage)
+	services, err := service.NewServices(appLogger, repositories, passwordManager, jwtTokenManager, fileStorage, smsSender, refreshTokenDuration, messageBroker, service.PublisherOptions{
+		QueueSize:      configuration.PublisherQueueSize,
+		OverflowPolicy: configuration.PublisherOverflowPolicy,
+		ReplaySize:     configuration.PublisherReplaySize,
+		ReplayTTL:      time.Minute * time.Duration(configuration.PublisherReplayTTLMinutes),
+	}, service.MediaOptions{
+		MaxImageSize: configuration.MediaMaxImageSizeMB << 20,
+		MaxVideoSize: configuration.MediaMaxVideoSizeMB << 20,
+		KeepMetadata: !configuration.MediaStripMetadata,
+	}, time.Second*time.Duration(configuration.ReconnectGraceSeconds))
 	if err != nil {
 		appLogger.LogError(err)
 		return
 	}
 
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	scheduler := newEventScheduler(appLogger, services.Events,
+		time.Second*time.Duration(configuration.EventSchedulerIntervalSeconds),
+		time.Minute*time.Duration(configuration.EventInactivityTimeoutMinutes))
+	if configuration.EventSchedulerIntervalSeconds > 0 {
+		go scheduler.Run(schedulerCtx)
+	}
+
 	handler := appHttp.NewHandler(services, appLogger, jwtTokenManager, configuration.LoggingTraceRequests)
 	httpServer := server.NewHttpServer(configuration.ServerPort, handler)
 
@@ -82,6 +125,9 @@ func Run() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
+	stopScheduler()
+	// presence leaves the users through the publisher and the broker, so it is closed before them
+	services.Presence.Close()
 	services.Publisher.CloseAll()
 	appLogger.LogInfo("Shutting down server...")
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
@@ -89,6 +135,10 @@ func Run() {
 
 	if err = httpServer.Stop(ctx); err != nil {
 		appLogger.LogError(err)
+	}
+
+	if err = messageBroker.Close(); err != nil {
+		appLogger.LogError(err)
 		return
 	}
 
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+)
+
+type eventScheduler struct {
+	logger            logger.Logger
+	events            service.Events
+	interval          time.Duration
+	inactivityTimeout time.Duration
+}
+
+func newEventScheduler(logger logger.Logger, events service.Events, interval time.Duration, inactivityTimeout time.Duration) *eventScheduler {
+	return &eventScheduler{
+		logger:            logger,
+		events:            events,
+		interval:          interval,
+		inactivityTimeout: inactivityTimeout,
+	}
+}
+
+func (s *eventScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *eventScheduler) tick(ctx context.Context) {
+	now := time.Now()
+
+	if err := s.events.OpenScheduled(ctx, now); err != nil && ctx.Err() == nil {
+		s.logger.LogError(err)
+	}
+
+	if err := s.events.CloseExpired(ctx, now, s.inactivityTimeout); err != nil && ctx.Err() == nil {
+		s.logger.LogError(err)
+	}
+}
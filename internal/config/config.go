@@ -2,18 +2,48 @@ package config
 
 import (
 	"github.com/caarlos0/env/v6"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/hash"
 )
 
 type Configuration struct {
-	ServerPort           int    `env:"SERVER_PORT" envDefault:"5000"`
-	DbConnection         string `env:"DB_CONNECTION" envDefault:"mongodb://localhost:27017"`
-	DbName               string `env:"DB_NAME" envDefault:"vpiska"`
-	JWTKey               string `env:"JWT_KEY" envDefault:"vpiska_secretkey!123"`
-	JWTIssuer            string `env:"JWT_ISSUER" envDefault:"VpiskaServer"`
-	JWTAudience          string `env:"JWT_AUDIENCE" envDefault:"VpiskaClient"`
-	JWTLifeTimeDays      int    `env:"JWT_LIFETIME_DAYS" envDefault:"3"`
-	HashKey              string `env:"HASH_KEY" envDefault:"fbac497e4b44564f831f78d539b81a0c"`
-	LoggingTraceRequests bool   `env:"LOGGING_TRACE_REQUESTS" envDefault:"false"`
+	ServerPort                    int    `env:"SERVER_PORT" envDefault:"5000"`
+	DbDriver                      string `env:"DB_DRIVER" envDefault:"mongo"`
+	DbConnection                  string `env:"DB_CONNECTION" envDefault:"mongodb://localhost:27017"`
+	DbName                        string `env:"DB_NAME" envDefault:"vpiska"`
+	BrokerDriver                  string `env:"BROKER_DRIVER" envDefault:"memory"`
+	BrokerConnection              string `env:"BROKER_CONNECTION" envDefault:"redis://localhost:6379/0"`
+	BrokerChannel                 string `env:"BROKER_CHANNEL" envDefault:"vpiska:events"`
+	PublisherQueueSize            int    `env:"PUBLISHER_QUEUE_SIZE" envDefault:"64"`
+	PublisherOverflowPolicy       string `env:"PUBLISHER_OVERFLOW_POLICY" envDefault:"drop_oldest"`
+	PublisherReplaySize           int    `env:"PUBLISHER_REPLAY_SIZE" envDefault:"256"`
+	PublisherReplayTTLMinutes     int    `env:"PUBLISHER_REPLAY_TTL_MINUTES" envDefault:"10"`
+	ReconnectGraceSeconds         int    `env:"RECONNECT_GRACE_SECONDS" envDefault:"30"`
+	JWTKey                        string `env:"JWT_KEY" envDefault:"vpiska_secretkey!123"`
+	JWTIssuer                     string `env:"JWT_ISSUER" envDefault:"VpiskaServer"`
+	JWTAudience                   string `env:"JWT_AUDIENCE" envDefault:"VpiskaClient"`
+	JWTLifeTimeDays               int    `env:"JWT_LIFETIME_DAYS" envDefault:"3"`
+	RefreshTokenLifeTimeDays      int    `env:"REFRESH_TOKEN_LIFETIME_DAYS" envDefault:"30"`
+	HashKey                       string `env:"HASH_KEY" envDefault:"fbac497e4b44564f831f78d539b81a0c"`
+	PasswordHashMemory            uint32 `env:"PASSWORD_HASH_MEMORY_KB" envDefault:"65536"`
+	PasswordHashIterations        uint32 `env:"PASSWORD_HASH_ITERATIONS" envDefault:"3"`
+	PasswordHashParallelism       uint8  `env:"PASSWORD_HASH_PARALLELISM" envDefault:"2"`
+	StorageDriver                 string `env:"STORAGE_DRIVER" envDefault:"local"`
+	StoragePath                   string `env:"STORAGE_PATH" envDefault:"media"`
+	S3Endpoint                    string `env:"S3_ENDPOINT" envDefault:"localhost:9000"`
+	S3AccessKey                   string `env:"S3_ACCESS_KEY" envDefault:""`
+	S3SecretKey                   string `env:"S3_SECRET_KEY" envDefault:""`
+	S3Bucket                      string `env:"S3_BUCKET" envDefault:"vpiska-media"`
+	S3Region                      string `env:"S3_REGION" envDefault:"us-east-1"`
+	S3UseSSL                      bool   `env:"S3_USE_SSL" envDefault:"false"`
+	S3PresignExpirationMinutes    int    `env:"S3_PRESIGN_EXPIRATION_MINUTES" envDefault:"0"`
+	MediaMaxImageSizeMB           int64  `env:"MEDIA_MAX_IMAGE_SIZE_MB" envDefault:"10"`
+	MediaMaxVideoSizeMB           int64  `env:"MEDIA_MAX_VIDEO_SIZE_MB" envDefault:"100"`
+	MediaStripMetadata            bool   `env:"MEDIA_STRIP_METADATA" envDefault:"true"`
+	SMSDriver                     string `env:"SMS_DRIVER" envDefault:"log"`
+	SMSFilePath                   string `env:"SMS_FILE_PATH" envDefault:"sms/messages.log"`
+	EventSchedulerIntervalSeconds int    `env:"EVENT_SCHEDULER_INTERVAL_SECONDS" envDefault:"30"`
+	EventInactivityTimeoutMinutes int    `env:"EVENT_INACTIVITY_TIMEOUT_MINUTES" envDefault:"720"`
+	LoggingTraceRequests          bool   `env:"LOGGING_TRACE_REQUESTS" envDefault:"false"`
 }
 
 func Parse() (*Configuration, error) {
@@ -24,5 +54,17 @@ func Parse() (*Configuration, error) {
 		return nil, err
 	}
 
+	if err = configuration.PasswordHashParams().Validate(); err != nil {
+		return nil, err
+	}
+
 	return configuration, err
 }
+
+func (c *Configuration) PasswordHashParams() hash.Argon2idParams {
+	params := hash.DefaultArgon2idParams()
+	params.Memory = c.PasswordHashMemory
+	params.Iterations = c.PasswordHashIterations
+	params.Parallelism = c.PasswordHashParallelism
+	return params
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"encoding/json"
 	"net/http"
 
 	v1 "github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/http/v1"
@@ -18,11 +19,18 @@ func NewHandler(services *service.Services, logger logger.Logger, tokenManager a
 		writer.WriteHeader(http.StatusOK)
 	})
 
+	mux.HandleFunc("/metrics", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(writer).Encode(services.Publisher.Metrics()); err != nil {
+			logger.LogError(err)
+		}
+	})
+
 	swaggerHandler := httpSwagger.Handler(httpSwagger.URL("doc.json"))
 	mux.Handle("/swagger/", swaggerHandler)
 	handler := v1.NewHandler(logger, services, tokenManager)
 	handler.InitAPI(mux)
-	websocket.InitWebsocketsRoutes(mux, logger, tokenManager, services.Events, services.Publisher)
+	websocket.InitWebsocketsRoutes(mux, logger, tokenManager, services.Events, services.Presence, services.Sessions, services.Publisher)
 	if traceLoggingRequestEnable {
 		return handler.Recover(handler.Logging(mux))
 	}
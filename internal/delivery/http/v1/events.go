@@ -2,6 +2,7 @@ package v1
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
@@ -12,16 +13,51 @@ const (
 	emptyCoordinatesError     = "CoordinatesIsEmpty"
 	emptyHorizontalRangeError = "HorizontalRangeIsEmpty"
 	emptyVerticalRangeError   = "VerticalRangeIsEmpty"
+	invalidChatLimitError     = "ChatHistoryLimitInvalid"
+	chatCursorConflictError   = "ChatHistoryCursorConflict"
+	invalidEndsAtError        = "EndsAtInvalid"
+	invalidCoordinatesError   = "CoordinatesInvalid"
+	emptyDistanceError        = "DistanceIsEmpty"
+	invalidDistanceError      = "DistanceInvalid"
+	emptyZoomError            = "ZoomIsEmpty"
+	invalidZoomError          = "ZoomInvalid"
+	invalidVisibilityError    = "VisibilityInvalid"
+	invalidRoleError          = "RoleInvalid"
+	invalidMuteMinutesError   = "MinutesInvalid"
+	emptyChatMessageError     = "MessageIsEmpty"
+	tooManyAttachmentsError   = "AttachmentsLimitExceeded"
+	invalidLastEventIdError   = "LastEventIdInvalid"
+
+	maxChatHistoryLimit = 100
+	maxZoom             = 22
+	maxMuteMinutes      = 7 * 24 * 60
+	maxChatAttachments  = 10
 )
 
 func (h *Handler) initEventsAPI(mux *http.ServeMux) {
-	mux.HandleFunc("/api/v1/events/get", h.POST(h.getEventByID))
+	mux.HandleFunc("/api/v1/events/get", h.optionalJwtAuth(h.POST(h.getEventByID)))
 	mux.HandleFunc("/api/v1/events/range", h.POST(h.getEventsByRange))
+	mux.HandleFunc("/api/v1/events/nearby", h.POST(h.getEventsNearby))
+	mux.HandleFunc("/api/v1/events/clusters", h.POST(h.getEventClusters))
+	mux.HandleFunc("/api/v1/events/chat/history", h.optionalJwtAuth(h.POST(h.getChatHistory)))
+	mux.HandleFunc("/api/v1/events/chat/send", h.jwtAuth(h.POST(h.sendChatMessage)))
+	mux.HandleFunc("/api/v1/events/stream", h.queryAccessToken(h.optionalJwtAuth(h.GET(h.streamEvent))))
 	mux.HandleFunc("/api/v1/events/create", h.jwtAuth(h.POST(h.createEvent)))
 	mux.HandleFunc("/api/v1/events/update", h.jwtAuth(h.POST(h.updateEvent)))
 	mux.HandleFunc("/api/v1/events/close", h.jwtAuth(h.POST(h.closeEvent)))
 	mux.HandleFunc("/api/v1/events/media/add", h.jwtAuth(h.POST(h.addMediaToEvent)))
 	mux.HandleFunc("/api/v1/events/media/remove", h.jwtAuth(h.POST(h.removeMediaFromEvent)))
+	mux.HandleFunc("/api/v1/events/invite", h.jwtAuth(h.POST(h.createEventInvite)))
+	mux.HandleFunc("/api/v1/events/join/request", h.jwtAuth(h.POST(h.requestJoinEvent)))
+	mux.HandleFunc("/api/v1/events/join/requests", h.jwtAuth(h.POST(h.getJoinRequests)))
+	mux.HandleFunc("/api/v1/events/join/approve", h.jwtAuth(h.POST(h.approveJoinRequest)))
+	mux.HandleFunc("/api/v1/events/join/reject", h.jwtAuth(h.POST(h.rejectJoinRequest)))
+	mux.HandleFunc("/api/v1/events/roles/grant", h.jwtAuth(h.POST(h.grantEventRole)))
+	mux.HandleFunc("/api/v1/events/roles/revoke", h.jwtAuth(h.POST(h.revokeEventRole)))
+	mux.HandleFunc("/api/v1/events/chat/delete", h.jwtAuth(h.POST(h.deleteChatMessage)))
+	mux.HandleFunc("/api/v1/events/users/mute", h.jwtAuth(h.POST(h.muteEventUser)))
+	mux.HandleFunc("/api/v1/events/users/kick", h.jwtAuth(h.POST(h.kickEventUser)))
+	mux.HandleFunc("/api/v1/events/users/ban", h.jwtAuth(h.POST(h.banEventUser)))
 }
 
 type coordinates struct {
@@ -34,11 +70,32 @@ type mediaInfo struct {
 	ContentType string `json:"contentType"`
 }
 
+type chatReaction struct {
+	UserID string `json:"userId"`
+	Emoji  string `json:"emoji"`
+}
+
+type chatMessageEdit struct {
+	Message  string    `json:"message"`
+	EditedAt time.Time `json:"editedAt"`
+}
+
 type chatMessage struct {
-	UserID      string `json:"userId"`
-	UserName    string `json:"userName"`
-	UserImageID string `json:"userImageId"`
-	Message     string `json:"message"`
+	ID          string            `json:"id"`
+	UserID      string            `json:"userId"`
+	UserName    string            `json:"userName"`
+	UserImageID string            `json:"userImageId"`
+	Message     string            `json:"message"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	ReplyToID   string            `json:"replyToId,omitempty"`
+	Attachments []mediaInfo       `json:"attachments,omitempty"`
+	Reactions   []chatReaction    `json:"reactions,omitempty"`
+	Edits       []chatMessageEdit `json:"edits,omitempty"`
+}
+
+type eventRole struct {
+	UserID string `json:"userId"`
+	Role   int    `json:"role"`
 }
 
 type eventResponse struct {
@@ -46,9 +103,14 @@ type eventResponse struct {
 	OwnerID      string        `json:"ownerId"`
 	Name         string        `json:"name"`
 	Address      string        `json:"address"`
+	State        int           `json:"state"`
+	Visibility   int           `json:"visibility"`
+	StartsAt     *time.Time    `json:"startsAt,omitempty"`
+	EndsAt       *time.Time    `json:"endsAt,omitempty"`
 	Coordinates  coordinates   `json:"coordinates"`
 	UsersCount   int           `json:"usersCount"`
 	Media        []mediaInfo   `json:"media"`
+	Roles        []eventRole   `json:"roles"`
 	ChatMessages []chatMessage `json:"chatMessages"`
 }
 
@@ -56,6 +118,9 @@ type createEventRequest struct {
 	Name        string       `json:"name"`
 	Address     string       `json:"address"`
 	Coordinates *coordinates `json:"coordinates"`
+	StartsAt    *time.Time   `json:"startsAt"`
+	EndsAt      *time.Time   `json:"endsAt"`
+	Visibility  *int         `json:"visibility"`
 }
 
 func (r createEventRequest) Validate() ([]string, error) {
@@ -69,10 +134,16 @@ func (r createEventRequest) Validate() ([]string, error) {
 		validationErrors = append(validationErrors, emptyAddressError)
 	}
 
-	if r.Coordinates == nil {
-		validationErrors = append(validationErrors, emptyCoordinatesError)
-	} else if r.Coordinates.X == nil || r.Coordinates.Y == nil {
-		validationErrors = append(validationErrors, emptyCoordinatesError)
+	validationErrors = append(validationErrors, validateCoordinates(r.Coordinates)...)
+
+	if r.EndsAt != nil {
+		if !r.EndsAt.After(time.Now()) || (r.StartsAt != nil && !r.EndsAt.After(*r.StartsAt)) {
+			validationErrors = append(validationErrors, invalidEndsAtError)
+		}
+	}
+
+	if r.Visibility != nil && (*r.Visibility < domain.EventVisibilityPublic || *r.Visibility > domain.EventVisibilityApproval) {
+		validationErrors = append(validationErrors, invalidVisibilityError)
 	}
 
 	return validationErrors, nil
@@ -80,6 +151,10 @@ func (r createEventRequest) Validate() ([]string, error) {
 
 // CreateEvent godoc
 // @Summary      Создать эвент
+// @Description  Если startsAt в будущем, эвент создается в состоянии 2 (запланирован) и открывается автоматически.
+// @Description  По наступлению endsAt или после долгого бездействия эвент закрывается автоматически.
+// @Description  visibility: 0 - публичный (по умолчанию), 1 - по ссылке-приглашению, 2 - по одобрению владельца
+// @Description  coordinates.x - долгота от -180 до 180, coordinates.y - широта от -90 до 90, иначе CoordinatesInvalid
 // @Security     UserAuth
 // @Tags         events
 // @Accept       json
@@ -95,7 +170,7 @@ func (h *Handler) createEvent(writer http.ResponseWriter, request *http.Request)
 		return
 	}
 
-	result, err := h.services.Events.Create(request.Context(), service.CreateEventInput{
+	input := service.CreateEventInput{
 		OwnerID: getUserID(request),
 		Name:    reqBody.Name,
 		Address: reqBody.Address,
@@ -103,7 +178,15 @@ func (h *Handler) createEvent(writer http.ResponseWriter, request *http.Request)
 			X: *reqBody.Coordinates.X,
 			Y: *reqBody.Coordinates.Y,
 		},
-	})
+		StartsAt: reqBody.StartsAt,
+		EndsAt:   reqBody.EndsAt,
+	}
+
+	if reqBody.Visibility != nil {
+		input.Visibility = domain.EventVisibility(*reqBody.Visibility)
+	}
+
+	result, err := h.services.Events.Create(request.Context(), input)
 
 	if err != nil {
 		h.writeError(writer, err)
@@ -121,23 +204,38 @@ func (r eventIDRequest) Validate() ([]string, error) {
 	return validateId(r.EventID)
 }
 
+type getEventRequest struct {
+	EventID     string `json:"eventId"`
+	InviteToken string `json:"inviteToken"`
+}
+
+func (r getEventRequest) Validate() ([]string, error) {
+	return validateId(r.EventID)
+}
+
 // GetEvent godoc
 // @Summary      Получить эвент по идентификатору
+// @Description  Авторизация необязательна. Закрытый эвент доступен владельцу, участникам и, если он по ссылке, по inviteToken
+// @Security     UserAuth
 // @Tags         events
 // @Accept       json
 // @Produce      json
 // @Content-Type application/json
-// @param        request body eventIDRequest true "body"
+// @param        request body getEventRequest true "body"
 // @Success      200 {object} apiResponse{result=eventResponse}
 // @Router       /v1/events/get [post]
 func (h *Handler) getEventByID(writer http.ResponseWriter, request *http.Request) {
-	reqBody := eventIDRequest{}
+	reqBody := getEventRequest{}
 
 	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
 		return
 	}
 
-	event, err := h.services.Events.GetByID(request.Context(), reqBody.EventID)
+	event, err := h.services.Events.GetByID(request.Context(), service.EventAccessInput{
+		EventID:     reqBody.EventID,
+		UserID:      getUserID(request),
+		InviteToken: reqBody.InviteToken,
+	})
 
 	if err != nil {
 		h.writeError(writer, err)
@@ -150,8 +248,11 @@ func (h *Handler) getEventByID(writer http.ResponseWriter, request *http.Request
 type eventRangeData struct {
 	ID          string      `json:"id"`
 	Name        string      `json:"name"`
+	State       int         `json:"state"`
+	StartsAt    *time.Time  `json:"startsAt,omitempty"`
 	UsersCount  int         `json:"usersCount"`
 	Coordinates coordinates `json:"coordinates"`
+	Distance    *float64    `json:"distance,omitempty"`
 }
 
 type getByRangeRequest struct {
@@ -213,6 +314,301 @@ func (h *Handler) getEventsByRange(writer http.ResponseWriter, request *http.Req
 	h.writeJSONResponse(writer, newSuccessResponse(result))
 }
 
+type eventCluster struct {
+	Coordinates coordinates `json:"coordinates"`
+	EventsCount int         `json:"eventsCount"`
+	UsersCount  int         `json:"usersCount"`
+}
+
+type eventClusters struct {
+	Clusters []eventCluster   `json:"clusters"`
+	Events   []eventRangeData `json:"events"`
+}
+
+type getClustersRequest struct {
+	getByRangeRequest
+	Zoom *int `json:"zoom"`
+}
+
+func (r getClustersRequest) Validate() ([]string, error) {
+	validationErrors, err := r.getByRangeRequest.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Zoom == nil {
+		validationErrors = append(validationErrors, emptyZoomError)
+	} else if *r.Zoom < 0 || *r.Zoom > maxZoom {
+		validationErrors = append(validationErrors, invalidZoomError)
+	}
+
+	return validationErrors, nil
+}
+
+// GetEventClusters godoc
+// @Summary      Получить эвенты по области, сгруппированные в кластеры по уровню зума карты
+// @Description  Эвенты, попавшие в одну ячейку сетки, объединяются в кластер с центром масс, кол-вом эвентов и суммой пользователей.
+// @Description  Одиночные эвенты и все эвенты начиная с зума 15 приходят в events
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body getClustersRequest true "body"
+// @Success      200 {object} apiResponse{result=eventClusters}
+// @Router       /v1/events/clusters [post]
+func (h *Handler) getEventClusters(writer http.ResponseWriter, request *http.Request) {
+	reqBody := getClustersRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	result, err := h.services.Events.GetClusters(request.Context(), service.GetClustersInput{
+		HorizontalRange: *reqBody.HorizontalRange,
+		VerticalRange:   *reqBody.VerticalRange,
+		Coordinates: domain.Coordinates{
+			X: *reqBody.Coordinates.X,
+			Y: *reqBody.Coordinates.Y,
+		},
+		Zoom: *reqBody.Zoom,
+	})
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(result))
+}
+
+type getNearbyRequest struct {
+	Coordinates *coordinates `json:"coordinates"`
+	Distance    *float64     `json:"distance"`
+}
+
+func (r getNearbyRequest) Validate() ([]string, error) {
+	var validationErrors []string
+
+	validationErrors = append(validationErrors, validateCoordinates(r.Coordinates)...)
+
+	if r.Distance == nil {
+		validationErrors = append(validationErrors, emptyDistanceError)
+	} else if *r.Distance <= 0 {
+		validationErrors = append(validationErrors, invalidDistanceError)
+	}
+
+	return validationErrors, nil
+}
+
+// GetEventsNearby godoc
+// @Summary      Получить эвенты в радиусе от точки, отсортированные по расстоянию
+// @Description  coordinates.x - долгота, coordinates.y - широта, distance - радиус в метрах. В ответе distance - расстояние до эвента в метрах
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body getNearbyRequest true "body"
+// @Success      200 {object} apiResponse{result=[]eventRangeData}
+// @Router       /v1/events/nearby [post]
+func (h *Handler) getEventsNearby(writer http.ResponseWriter, request *http.Request) {
+	reqBody := getNearbyRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	result, err := h.services.Events.GetNearby(request.Context(), service.GetNearbyInput{
+		Coordinates: domain.Coordinates{
+			X: *reqBody.Coordinates.X,
+			Y: *reqBody.Coordinates.Y,
+		},
+		Distance: *reqBody.Distance,
+	})
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(result))
+}
+
+type chatHistoryRequest struct {
+	EventID     string `json:"eventId"`
+	InviteToken string `json:"inviteToken"`
+	BeforeID    string `json:"beforeId"`
+	AfterID     string `json:"afterId"`
+	Limit       *int   `json:"limit"`
+}
+
+func (r chatHistoryRequest) Validate() ([]string, error) {
+	validationErrors, err := validateId(r.EventID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cursor := range []string{r.BeforeID, r.AfterID} {
+		if cursor == "" {
+			continue
+		}
+
+		cursorErrs, err := validateId(cursor)
+
+		if err != nil {
+			return nil, err
+		}
+
+		validationErrors = append(validationErrors, cursorErrs...)
+	}
+
+	if r.BeforeID != "" && r.AfterID != "" {
+		validationErrors = append(validationErrors, chatCursorConflictError)
+	}
+
+	if r.Limit != nil && (*r.Limit < 1 || *r.Limit > maxChatHistoryLimit) {
+		validationErrors = append(validationErrors, invalidChatLimitError)
+	}
+
+	return validationErrors, nil
+}
+
+type chatHistoryResponse struct {
+	ChatMessages []chatMessage `json:"chatMessages"`
+	HasMore      bool          `json:"hasMore"`
+}
+
+// GetChatHistory godoc
+// @Summary      Получить историю чата эвента
+// @Description  Сообщения возвращаются в хронологическом порядке. beforeId - сообщения до указанного, afterId - после, без курсора - последние
+// @Description  Авторизация необязательна, доступ к закрытым эвентам как в /v1/events/get
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body chatHistoryRequest true "body"
+// @Success      200 {object} apiResponse{result=chatHistoryResponse}
+// @Router       /v1/events/chat/history [post]
+func (h *Handler) getChatHistory(writer http.ResponseWriter, request *http.Request) {
+	reqBody := chatHistoryRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	input := service.ChatHistoryInput{
+		EventID:     reqBody.EventID,
+		UserID:      getUserID(request),
+		InviteToken: reqBody.InviteToken,
+		BeforeID:    reqBody.BeforeID,
+		AfterID:     reqBody.AfterID,
+	}
+
+	if reqBody.Limit != nil {
+		input.Limit = *reqBody.Limit
+	}
+
+	result, err := h.services.Events.GetChatHistory(request.Context(), input)
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(result))
+}
+
+type sendChatMessageRequest struct {
+	EventID     string   `json:"eventId"`
+	InviteToken string   `json:"inviteToken"`
+	Message     string   `json:"message"`
+	ReplyToID   string   `json:"replyToId"`
+	Attachments []string `json:"attachments"`
+}
+
+func (r sendChatMessageRequest) Validate() ([]string, error) {
+	validationErrors, err := validateId(r.EventID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range append([]string{r.ReplyToID}, r.Attachments...) {
+		if id == "" {
+			continue
+		}
+
+		idErrs, err := validateId(id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		validationErrors = append(validationErrors, idErrs...)
+	}
+
+	if r.Message == "" && len(r.Attachments) == 0 {
+		validationErrors = append(validationErrors, emptyChatMessageError)
+	}
+
+	if len(r.Attachments) > maxChatAttachments {
+		validationErrors = append(validationErrors, tooManyAttachmentsError)
+	}
+
+	return validationErrors, nil
+}
+
+// SendChatMessage godoc
+// @Summary      Отправить сообщение в чат эвента
+// @Description  Для клиентов без вебсокета, сообщение приходит подписчикам как chatMessage. replyToId и attachments (до 10 id медиа) необязательны.
+// @Description  Доступ к закрытым эвентам как в /v1/events/get
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body sendChatMessageRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/events/chat/send [post]
+func (h *Handler) sendChatMessage(writer http.ResponseWriter, request *http.Request) {
+	reqBody := sendChatMessageRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	userId := getUserID(request)
+	err := h.services.Events.CheckAccess(request.Context(), service.EventAccessInput{
+		EventID:     reqBody.EventID,
+		UserID:      userId,
+		InviteToken: reqBody.InviteToken,
+	})
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	err = h.services.Events.SendChatMessage(request.Context(), service.ChatMessageInput{
+		EventID:       reqBody.EventID,
+		UserID:        userId,
+		UserName:      getUserName(request),
+		UserImageID:   getUserImageID(request),
+		Message:       reqBody.Message,
+		ReplyToID:     reqBody.ReplyToID,
+		AttachmentIDs: reqBody.Attachments,
+	})
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
 type updateEventRequest struct {
 	EventID     string       `json:"eventId"`
 	Address     string       `json:"address"`
@@ -230,17 +626,16 @@ func (r updateEventRequest) Validate() ([]string, error) {
 		validationErrors = append(validationErrors, emptyAddressError)
 	}
 
-	if r.Coordinates == nil {
-		validationErrors = append(validationErrors, emptyCoordinatesError)
-	} else if r.Coordinates.X == nil || r.Coordinates.Y == nil {
-		validationErrors = append(validationErrors, emptyCoordinatesError)
-	}
+	validationErrors = append(validationErrors, validateCoordinates(r.Coordinates)...)
 
 	return validationErrors, nil
 }
 
 // UpdateEvent godoc
 // @Summary      Обновить эвент
+// @Description  coordinates.x - долгота от -180 до 180, coordinates.y - широта от -90 до 90, иначе CoordinatesInvalid.
+// @Description  Эвенты, созданные до проверки с координатами вне этих границ, не попадают в поиск по области, кластеры и nearby,
+// @Description  пока координаты не обновят этим методом
 // @Security     UserAuth
 // @Tags         events
 // @Accept       json
@@ -299,6 +694,10 @@ func (h *Handler) closeEvent(writer http.ResponseWriter, request *http.Request)
 
 // AddMediaToEvent godoc
 // @Summary      добавить медиа к евенту
+// @Description  Картинка (jpeg, png, gif, webp, heic) или видео (mp4, mov, webm), тип определяется по содержимому файла.
+// @Description  Ошибки: UnsupportedMediaType - другой тип, FileTooLarge - больше MEDIA_MAX_IMAGE_SIZE_MB для картинок
+// @Description  или MEDIA_MAX_VIDEO_SIZE_MB для видео, FileIsEmpty - пустой файл, FileIsMissing - нет файла в форме
+// @Description  Из картинок удаляются метаданные (EXIF с геолокацией и устройством), кроме ориентации, если не MEDIA_STRIP_METADATA=false
 // @Security     UserAuth
 // @Tags         events
 // @Accept       multipart/form-data
@@ -309,12 +708,13 @@ func (h *Handler) closeEvent(writer http.ResponseWriter, request *http.Request)
 // @Success      200 {object} apiResponse{result=string}
 // @Router       /v1/events/media/add [post]
 func (h *Handler) addMediaToEvent(writer http.ResponseWriter, request *http.Request) {
-	data, header, isOk := h.parseFormFile(writer, request, "media")
+	file, header, isOk := h.parseFormFile(writer, request, "media", h.services.Media.MaxSize(service.MediaContentTypes))
 
 	if !isOk {
 		return
 	}
 
+	defer file.Close()
 	eventId := request.PostFormValue("eventId")
 	validationErrs, err := validateId(eventId)
 
@@ -335,7 +735,7 @@ func (h *Handler) addMediaToEvent(writer http.ResponseWriter, request *http.Requ
 		FileName:    header.Filename,
 		ContentType: header.Header.Get("Content-Type"),
 		FileSize:    header.Size,
-		FileData:    data,
+		FileData:    file,
 	}); err != nil {
 		h.writeError(writer, err)
 		return
@@ -388,3 +788,447 @@ func (h *Handler) removeMediaFromEvent(writer http.ResponseWriter, request *http
 
 	h.writeJSONResponse(writer, newSuccessResponse(nil))
 }
+
+type inviteResponse struct {
+	InviteToken string `json:"inviteToken"`
+}
+
+// CreateEventInvite godoc
+// @Summary      Создать ссылку-приглашение в эвент
+// @Description  Только для эвентов с visibility 1. Токен передается в inviteToken при получении эвента и подключении к вебсокету
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body eventIDRequest true "body"
+// @Success      200 {object} apiResponse{result=inviteResponse}
+// @Router       /v1/events/invite [post]
+func (h *Handler) createEventInvite(writer http.ResponseWriter, request *http.Request) {
+	reqBody := eventIDRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	inviteToken, err := h.services.Events.CreateInvite(request.Context(), reqBody.EventID, getUserID(request))
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(inviteResponse{InviteToken: inviteToken}))
+}
+
+// RequestJoinEvent godoc
+// @Summary      Отправить заявку на вступление в эвент
+// @Description  Только для эвентов с visibility 2
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body eventIDRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/events/join/request [post]
+func (h *Handler) requestJoinEvent(writer http.ResponseWriter, request *http.Request) {
+	reqBody := eventIDRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Events.RequestJoin(request.Context(), service.JoinRequestInput{
+		EventID:     reqBody.EventID,
+		UserID:      getUserID(request),
+		UserName:    getUserName(request),
+		UserImageID: getUserImageID(request),
+	}); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
+type joinRequest struct {
+	UserID      string    `json:"userId"`
+	UserName    string    `json:"userName"`
+	UserImageID string    `json:"userImageId"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// GetJoinRequests godoc
+// @Summary      Получить заявки на вступление в эвент
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body eventIDRequest true "body"
+// @Success      200 {object} apiResponse{result=[]joinRequest}
+// @Router       /v1/events/join/requests [post]
+func (h *Handler) getJoinRequests(writer http.ResponseWriter, request *http.Request) {
+	reqBody := eventIDRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	result, err := h.services.Events.GetJoinRequests(request.Context(), reqBody.EventID, getUserID(request))
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(result))
+}
+
+type reviewJoinRequest struct {
+	EventID string `json:"eventId"`
+	UserID  string `json:"userId"`
+}
+
+func (r reviewJoinRequest) Validate() ([]string, error) {
+	validationErrs, err := validateId(r.EventID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	userIdErrs, err := validateId(r.UserID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(validationErrs, userIdErrs...), nil
+}
+
+// ApproveJoinRequest godoc
+// @Summary      Одобрить заявку на вступление в эвент
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body reviewJoinRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/events/join/approve [post]
+func (h *Handler) approveJoinRequest(writer http.ResponseWriter, request *http.Request) {
+	reqBody := reviewJoinRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Events.ApproveJoinRequest(request.Context(), service.ReviewJoinRequestInput{
+		EventID:     reqBody.EventID,
+		UserID:      getUserID(request),
+		RequesterID: reqBody.UserID,
+	}); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
+// RejectJoinRequest godoc
+// @Summary      Отклонить заявку на вступление в эвент
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body reviewJoinRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/events/join/reject [post]
+func (h *Handler) rejectJoinRequest(writer http.ResponseWriter, request *http.Request) {
+	reqBody := reviewJoinRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Events.RejectJoinRequest(request.Context(), service.ReviewJoinRequestInput{
+		EventID:     reqBody.EventID,
+		UserID:      getUserID(request),
+		RequesterID: reqBody.UserID,
+	}); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
+type grantRoleRequest struct {
+	EventID string `json:"eventId"`
+	UserID  string `json:"userId"`
+	Role    *int   `json:"role"`
+}
+
+func (r grantRoleRequest) Validate() ([]string, error) {
+	validationErrs, err := reviewJoinRequest{EventID: r.EventID, UserID: r.UserID}.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Role == nil || (*r.Role != domain.EventRoleModerator && *r.Role != domain.EventRoleCoHost) {
+		validationErrs = append(validationErrs, invalidRoleError)
+	}
+
+	return validationErrs, nil
+}
+
+// GrantEventRole godoc
+// @Summary      Выдать роль в эвенте
+// @Description  Только для владельца. role: 1 - модератор, 2 - со-организатор. Со-организатор может редактировать эвент,
+// @Description  управлять медиа и доступом, модератор - удалять медиа. Изменение приходит в вебсокет как roleChanged
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body grantRoleRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/events/roles/grant [post]
+func (h *Handler) grantEventRole(writer http.ResponseWriter, request *http.Request) {
+	reqBody := grantRoleRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Events.GrantRole(request.Context(), service.EventRoleInput{
+		EventID:      reqBody.EventID,
+		UserID:       getUserID(request),
+		TargetUserID: reqBody.UserID,
+		Role:         domain.EventRole(*reqBody.Role),
+	}); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
+type revokeRoleRequest struct {
+	EventID string `json:"eventId"`
+	UserID  string `json:"userId"`
+}
+
+func (r revokeRoleRequest) Validate() ([]string, error) {
+	return reviewJoinRequest{EventID: r.EventID, UserID: r.UserID}.Validate()
+}
+
+// RevokeEventRole godoc
+// @Summary      Отозвать роль в эвенте
+// @Description  Только для владельца, пользователь становится гостем
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body revokeRoleRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/events/roles/revoke [post]
+func (h *Handler) revokeEventRole(writer http.ResponseWriter, request *http.Request) {
+	reqBody := revokeRoleRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Events.RevokeRole(request.Context(), service.EventRoleInput{
+		EventID:      reqBody.EventID,
+		UserID:       getUserID(request),
+		TargetUserID: reqBody.UserID,
+	}); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
+type deleteChatMessageRequest struct {
+	EventID   string `json:"eventId"`
+	MessageID string `json:"messageId"`
+}
+
+func (r deleteChatMessageRequest) Validate() ([]string, error) {
+	validationErrs, err := validateId(r.EventID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	messageIdErrs, err := validateId(r.MessageID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(validationErrs, messageIdErrs...), nil
+}
+
+// DeleteChatMessage godoc
+// @Summary      Удалить сообщение из чата эвента
+// @Description  Для владельца, со-организаторов и модераторов. Удаление приходит в вебсокет как chatMessageDeleted
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body deleteChatMessageRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/events/chat/delete [post]
+func (h *Handler) deleteChatMessage(writer http.ResponseWriter, request *http.Request) {
+	reqBody := deleteChatMessageRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Events.DeleteChatMessage(request.Context(), service.DeleteChatMessageInput{
+		EventID:   reqBody.EventID,
+		UserID:    getUserID(request),
+		MessageID: reqBody.MessageID,
+	}); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
+type muteUserRequest struct {
+	EventID string `json:"eventId"`
+	UserID  string `json:"userId"`
+	Minutes int    `json:"minutes"`
+}
+
+func (r muteUserRequest) Validate() ([]string, error) {
+	validationErrs, err := reviewJoinRequest{EventID: r.EventID, UserID: r.UserID}.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Minutes <= 0 || r.Minutes > maxMuteMinutes {
+		validationErrs = append(validationErrs, invalidMuteMinutesError)
+	}
+
+	return validationErrs, nil
+}
+
+// MuteEventUser godoc
+// @Summary      Запретить пользователю писать в чат эвента
+// @Description  Для владельца, со-организаторов и модераторов, только пользователям с ролью ниже своей. minutes - от 1 до 10080,
+// @Description  повторный вызов заменяет срок. Пока срок не истек, сообщения пользователя отклоняются с UserMuted.
+// @Description  Мьют приходит в вебсокет как userMuted
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body muteUserRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/events/users/mute [post]
+func (h *Handler) muteEventUser(writer http.ResponseWriter, request *http.Request) {
+	reqBody := muteUserRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Events.MuteUser(request.Context(), service.MuteUserInput{
+		EventID:      reqBody.EventID,
+		UserID:       getUserID(request),
+		TargetUserID: reqBody.UserID,
+		Duration:     time.Duration(reqBody.Minutes) * time.Minute,
+	}); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
+type moderateUserRequest struct {
+	EventID string `json:"eventId"`
+	UserID  string `json:"userId"`
+}
+
+func (r moderateUserRequest) Validate() ([]string, error) {
+	return reviewJoinRequest{EventID: r.EventID, UserID: r.UserID}.Validate()
+}
+
+// KickEventUser godoc
+// @Summary      Выгнать пользователя из эвента
+// @Description  Для владельца, со-организаторов и модераторов, только пользователям с ролью ниже своей.
+// @Description  Пользователь получает userKicked в вебсокет и отключается, но может подключиться снова
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body moderateUserRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/events/users/kick [post]
+func (h *Handler) kickEventUser(writer http.ResponseWriter, request *http.Request) {
+	reqBody := moderateUserRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Events.KickUser(request.Context(), service.ModerateUserInput{
+		EventID:      reqBody.EventID,
+		UserID:       getUserID(request),
+		TargetUserID: reqBody.UserID,
+	}); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
+// BanEventUser godoc
+// @Summary      Забанить пользователя в эвенте
+// @Description  Для владельца, со-организаторов и модераторов, только пользователям с ролью ниже своей.
+// @Description  Пользователь сразу убирается из списка пользователей, теряет участие, роль и заявку на вступление,
+// @Description  получает userBanned в вебсокет и отключается,
+// @Description  повторное подключение и получение эвента возвращают UserBanned
+// @Security     UserAuth
+// @Tags         events
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body moderateUserRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/events/users/ban [post]
+func (h *Handler) banEventUser(writer http.ResponseWriter, request *http.Request) {
+	reqBody := moderateUserRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Events.BanUser(request.Context(), service.ModerateUserInput{
+		EventID:      reqBody.EventID,
+		UserID:       getUserID(request),
+		TargetUserID: reqBody.UserID,
+	}); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
@@ -1,18 +1,34 @@
 package v1
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
 )
 
-func TestEvents(t *testing.T) {
-	t.Run("create", TestCreateEvent)
-	t.Run("get", TestGetEvent)
-	t.Run("range", TestRangeEvents)
+func testEvents(t *testing.T) {
+	t.Run("create", testCreateEvent)
+	t.Run("get", testGetEvent)
+	t.Run("range", testRangeEvents)
+	t.Run("nearby", testNearbyEvents)
+	t.Run("clusters", testEventClusters)
+	t.Run("chat history", testChatHistory)
+	t.Run("visibility", testEventVisibility)
+	t.Run("roles", testEventRoles)
+	t.Run("moderation", testEventModeration)
+	t.Run("stream", testEventStream)
 }
 
-func TestCreateEvent(t *testing.T) {
+func testCreateEvent(t *testing.T) {
 	tests := []testData{
 		{
 			Name:                "unauthorized",
@@ -40,7 +56,7 @@ func TestCreateEvent(t *testing.T) {
 			Handler:             testHandler.jwtAuth(testHandler.createEvent),
 		},
 		{
-			Name:                "success",
+			Name:                "invalid coordinates",
 			Url:                 "/api/v1/events/create",
 			Method:              http.MethodPost,
 			Body:                `{"name":"test_events_create","address":"test_events_create","coordinates":{"x":99999,"y":99999}}`,
@@ -48,6 +64,45 @@ func TestCreateEvent(t *testing.T) {
 			RequestContentType:  contentTypeJSON,
 			ResponseContentType: contentTypeJSON,
 			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"CoordinatesInvalid"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.createEvent),
+		},
+		{
+			Name:                "ends before start",
+			Url:                 "/api/v1/events/create",
+			Method:              http.MethodPost,
+			Body:                `{"name":"test_events_create","address":"test_events_create","coordinates":{"x":0,"y":0},"startsAt":"2099-06-01T20:00:00Z","endsAt":"2099-06-01T18:00:00Z"}`,
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"EndsAtInvalid"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.createEvent),
+		},
+		{
+			Name:                "ended in the past",
+			Url:                 "/api/v1/events/create",
+			Method:              http.MethodPost,
+			Body:                `{"name":"test_events_create","address":"test_events_create","coordinates":{"x":0,"y":0},"endsAt":"2022-06-01T18:00:00Z"}`,
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"EndsAtInvalid"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.createEvent),
+		},
+		{
+			Name:                "success",
+			Url:                 "/api/v1/events/create",
+			Method:              http.MethodPost,
+			Body:                `{"name":"test_events_create","address":"test_events_create","coordinates":{"x":-170,"y":-85}}`,
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
 			Handler:             testHandler.jwtAuth(testHandler.createEvent),
 		},
 		{
@@ -72,7 +127,7 @@ func TestCreateEvent(t *testing.T) {
 	}
 }
 
-func TestGetEvent(t *testing.T) {
+func testGetEvent(t *testing.T) {
 	tests := []testData{
 		{
 			Name:                "empty body",
@@ -107,7 +162,7 @@ func TestGetEvent(t *testing.T) {
 			ResponseContentType: contentTypeJSON,
 			ExpectedStatusCode:  http.StatusOK,
 			CheckBody:           true,
-			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":{"id":"%s","ownerId":"owner_id","name":"integration_tests","address":"integration_tests","coordinates":{"x":99999,"y":99999},"usersCount":1,"media":[{"id":"media_id","contentType":"image/jpeg"}],"chatMessages":[{"userId":"test_id_1","userName":"test_events_1","userImageId":"","message":"test message"},{"userId":"test_id_2","userName":"test_events_2","userImageId":"","message":"another message"}]}}`, testEventId),
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":{"id":"%s","ownerId":"owner_id","name":"integration_tests","address":"integration_tests","state":0,"visibility":0,"coordinates":{"x":-170,"y":-85},"usersCount":1,"media":[{"id":"media_id","contentType":"image/jpeg"}],"roles":[],"chatMessages":[{"id":"%s","userId":"test_id_1","userName":"test_events_1","userImageId":"","message":"test message","createdAt":"2022-06-01T12:00:00Z"},{"id":"%s","userId":"test_id_2","userName":"test_events_2","userImageId":"","message":"another message","createdAt":"2022-06-01T12:01:00Z"}]}}`, testEventId, testChatMessageId1, testChatMessageId2),
 			Handler:             testHandler.getEventByID,
 		},
 	}
@@ -119,7 +174,7 @@ func TestGetEvent(t *testing.T) {
 	}
 }
 
-func TestRangeEvents(t *testing.T) {
+func testRangeEvents(t *testing.T) {
 	tests := []testData{
 		{
 			Name:                "empty result",
@@ -142,7 +197,7 @@ func TestRangeEvents(t *testing.T) {
 			ResponseContentType: contentTypeJSON,
 			ExpectedStatusCode:  http.StatusOK,
 			CheckBody:           true,
-			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","usersCount":0,"coordinates":{"x":10,"y":10}}]}`, testEventId10),
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","state":0,"usersCount":0,"coordinates":{"x":10,"y":10}}]}`, testEventId10),
 			Handler:             testHandler.getEventsByRange,
 		},
 		{
@@ -154,7 +209,7 @@ func TestRangeEvents(t *testing.T) {
 			ResponseContentType: contentTypeJSON,
 			ExpectedStatusCode:  http.StatusOK,
 			CheckBody:           true,
-			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","usersCount":0,"coordinates":{"x":25,"y":25}}]}`, testEventId10, testEventId25),
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","state":0,"usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","state":0,"usersCount":0,"coordinates":{"x":25,"y":25}}]}`, testEventId10, testEventId25),
 			Handler:             testHandler.getEventsByRange,
 		},
 		{
@@ -166,7 +221,7 @@ func TestRangeEvents(t *testing.T) {
 			ResponseContentType: contentTypeJSON,
 			ExpectedStatusCode:  http.StatusOK,
 			CheckBody:           true,
-			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","usersCount":0,"coordinates":{"x":25,"y":25}},{"id":"%s","name":"integration_tests_range_3","usersCount":0,"coordinates":{"x":50,"y":50}}]}`, testEventId10, testEventId25, testEventId50),
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","state":0,"usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","state":0,"usersCount":0,"coordinates":{"x":25,"y":25}},{"id":"%s","name":"integration_tests_range_3","state":0,"usersCount":0,"coordinates":{"x":50,"y":50}}]}`, testEventId10, testEventId25, testEventId50),
 			Handler:             testHandler.getEventsByRange,
 		},
 		{
@@ -178,7 +233,7 @@ func TestRangeEvents(t *testing.T) {
 			ResponseContentType: contentTypeJSON,
 			ExpectedStatusCode:  http.StatusOK,
 			CheckBody:           true,
-			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","usersCount":0,"coordinates":{"x":25,"y":25}},{"id":"%s","name":"integration_tests_range_3","usersCount":0,"coordinates":{"x":50,"y":50}},{"id":"%s","name":"integration_tests_range_4","usersCount":0,"coordinates":{"x":75,"y":75}}]}`, testEventId10, testEventId25, testEventId50, testEventId75),
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","state":0,"usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","state":0,"usersCount":0,"coordinates":{"x":25,"y":25}},{"id":"%s","name":"integration_tests_range_3","state":0,"usersCount":0,"coordinates":{"x":50,"y":50}},{"id":"%s","name":"integration_tests_range_4","state":0,"usersCount":0,"coordinates":{"x":75,"y":75}}]}`, testEventId10, testEventId25, testEventId50, testEventId75),
 			Handler:             testHandler.getEventsByRange,
 		},
 		{
@@ -190,7 +245,7 @@ func TestRangeEvents(t *testing.T) {
 			ResponseContentType: contentTypeJSON,
 			ExpectedStatusCode:  http.StatusOK,
 			CheckBody:           true,
-			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","usersCount":0,"coordinates":{"x":25,"y":25}},{"id":"%s","name":"integration_tests_range_3","usersCount":0,"coordinates":{"x":50,"y":50}},{"id":"%s","name":"integration_tests_range_4","usersCount":0,"coordinates":{"x":75,"y":75}},{"id":"%s","name":"integration_tests_range_5","usersCount":0,"coordinates":{"x":100,"y":100}}]}`, testEventId10, testEventId25, testEventId50, testEventId75, testEventId100),
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","state":0,"usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","state":0,"usersCount":0,"coordinates":{"x":25,"y":25}},{"id":"%s","name":"integration_tests_range_3","state":0,"usersCount":0,"coordinates":{"x":50,"y":50}},{"id":"%s","name":"integration_tests_range_4","state":0,"usersCount":0,"coordinates":{"x":75,"y":75}},{"id":"%s","name":"integration_tests_range_5","state":0,"usersCount":0,"coordinates":{"x":100,"y":80}}]}`, testEventId10, testEventId25, testEventId50, testEventId75, testEventId100),
 			Handler:             testHandler.getEventsByRange,
 		},
 		{
@@ -202,7 +257,7 @@ func TestRangeEvents(t *testing.T) {
 			ResponseContentType: contentTypeJSON,
 			ExpectedStatusCode:  http.StatusOK,
 			CheckBody:           true,
-			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","usersCount":0,"coordinates":{"x":25,"y":25}}]}`, testEventId10, testEventId25),
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","state":0,"usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","state":0,"usersCount":0,"coordinates":{"x":25,"y":25}}]}`, testEventId10, testEventId25),
 			Handler:             testHandler.getEventsByRange,
 		},
 		{
@@ -214,7 +269,7 @@ func TestRangeEvents(t *testing.T) {
 			ResponseContentType: contentTypeJSON,
 			ExpectedStatusCode:  http.StatusOK,
 			CheckBody:           true,
-			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_3","usersCount":0,"coordinates":{"x":50,"y":50}}]}`, testEventId50),
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_3","state":0,"usersCount":0,"coordinates":{"x":50,"y":50}}]}`, testEventId50),
 			Handler:             testHandler.getEventsByRange,
 		},
 		{
@@ -226,7 +281,7 @@ func TestRangeEvents(t *testing.T) {
 			ResponseContentType: contentTypeJSON,
 			ExpectedStatusCode:  http.StatusOK,
 			CheckBody:           true,
-			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_2","usersCount":0,"coordinates":{"x":25,"y":25}},{"id":"%s","name":"integration_tests_range_3","usersCount":0,"coordinates":{"x":50,"y":50}},{"id":"%s","name":"integration_tests_range_4","usersCount":0,"coordinates":{"x":75,"y":75}}]}`, testEventId25, testEventId50, testEventId75),
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_2","state":0,"usersCount":0,"coordinates":{"x":25,"y":25}},{"id":"%s","name":"integration_tests_range_3","state":0,"usersCount":0,"coordinates":{"x":50,"y":50}},{"id":"%s","name":"integration_tests_range_4","state":0,"usersCount":0,"coordinates":{"x":75,"y":75}}]}`, testEventId25, testEventId50, testEventId75),
 			Handler:             testHandler.getEventsByRange,
 		},
 	}
@@ -237,3 +292,881 @@ func TestRangeEvents(t *testing.T) {
 		})
 	}
 }
+
+func testNearbyEvents(t *testing.T) {
+	tests := []testData{
+		{
+			Name:                "empty body",
+			Url:                 "/api/v1/events/nearby",
+			Method:              http.MethodPost,
+			Body:                `{}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"CoordinatesIsEmpty"},{"errorCode":"DistanceIsEmpty"}],"result":null}`,
+			Handler:             testHandler.getEventsNearby,
+		},
+		{
+			Name:                "invalid coordinates and distance",
+			Url:                 "/api/v1/events/nearby",
+			Method:              http.MethodPost,
+			Body:                `{"coordinates": {"x": 200,"y": 0},"distance": 0}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"CoordinatesInvalid"},{"errorCode":"DistanceInvalid"}],"result":null}`,
+			Handler:             testHandler.getEventsNearby,
+		},
+		{
+			Name:                "empty result",
+			Url:                 "/api/v1/events/nearby",
+			Method:              http.MethodPost,
+			Body:                `{"coordinates": {"x": -10,"y": -10},"distance": 1000}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":[]}`,
+			Handler:             testHandler.getEventsNearby,
+		},
+		{
+			Name:                "success",
+			Url:                 "/api/v1/events/nearby",
+			Method:              http.MethodPost,
+			Body:                `{"coordinates": {"x": 10,"y": 10},"distance": 1000}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":[{"id":"%s","name":"integration_tests_range_1","state":0,"usersCount":0,"coordinates":{"x":10,"y":10},"distance":0}]}`, testEventId10),
+			Handler:             testHandler.getEventsNearby,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			testHandlerMethod(test, t)
+		})
+	}
+}
+
+func testEventClusters(t *testing.T) {
+	tests := []testData{
+		{
+			Name:                "empty zoom",
+			Url:                 "/api/v1/events/clusters",
+			Method:              http.MethodPost,
+			Body:                `{"coordinates": {"x": 0,"y": 0},"horizontalRange": 202,"verticalRange": 202}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"ZoomIsEmpty"}],"result":null}`,
+			Handler:             testHandler.getEventClusters,
+		},
+		{
+			Name:                "invalid zoom",
+			Url:                 "/api/v1/events/clusters",
+			Method:              http.MethodPost,
+			Body:                `{"coordinates": {"x": 0,"y": 0},"horizontalRange": 202,"verticalRange": 202,"zoom": 30}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"ZoomInvalid"}],"result":null}`,
+			Handler:             testHandler.getEventClusters,
+		},
+		{
+			Name:                "zoom 0",
+			Url:                 "/api/v1/events/clusters",
+			Method:              http.MethodPost,
+			Body:                `{"coordinates": {"x": 0,"y": 0},"horizontalRange": 202,"verticalRange": 202,"zoom": 0}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":{"clusters":[{"coordinates":{"x":40,"y":40},"eventsCount":4,"usersCount":0}],"events":[{"id":"%s","name":"integration_tests_range_5","state":0,"usersCount":0,"coordinates":{"x":100,"y":80}}]}}`, testEventId100),
+			Handler:             testHandler.getEventClusters,
+		},
+		{
+			Name:                "zoom 15",
+			Url:                 "/api/v1/events/clusters",
+			Method:              http.MethodPost,
+			Body:                `{"coordinates": {"x": 0,"y": 0},"horizontalRange": 52,"verticalRange": 52,"zoom": 15}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":{"clusters":[],"events":[{"id":"%s","name":"integration_tests_range_1","state":0,"usersCount":0,"coordinates":{"x":10,"y":10}},{"id":"%s","name":"integration_tests_range_2","state":0,"usersCount":0,"coordinates":{"x":25,"y":25}}]}}`, testEventId10, testEventId25),
+			Handler:             testHandler.getEventClusters,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			testHandlerMethod(test, t)
+		})
+	}
+}
+
+func testChatHistory(t *testing.T) {
+	tests := []testData{
+		{
+			Name:                "empty body",
+			Url:                 "/api/v1/events/chat/history",
+			Method:              http.MethodPost,
+			Body:                `{}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"IdIsEmpty"}],"result":null}`,
+			Handler:             testHandler.getChatHistory,
+		},
+		{
+			Name:                "invalid cursor",
+			Url:                 "/api/v1/events/chat/history",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","beforeId":"123","afterId":"%s","limit":0}`, testEventId, testChatMessageId1),
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"InvalidIdFormat"},{"errorCode":"ChatHistoryCursorConflict"},{"errorCode":"ChatHistoryLimitInvalid"}],"result":null}`,
+			Handler:             testHandler.getChatHistory,
+		},
+		{
+			Name:                "chat message not found",
+			Url:                 "/api/v1/events/chat/history",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","beforeId":"%s"}`, testEventId, testEventId),
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"ChatMessageNotFound"}],"result":null}`,
+			Handler:             testHandler.getChatHistory,
+		},
+		{
+			Name:                "latest",
+			Url:                 "/api/v1/events/chat/history",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","limit":1}`, testEventId),
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":{"chatMessages":[{"id":"%s","userId":"test_id_2","userName":"test_events_2","userImageId":"","message":"another message","createdAt":"2022-06-01T12:01:00Z"}],"hasMore":true}}`, testChatMessageId2),
+			Handler:             testHandler.getChatHistory,
+		},
+		{
+			Name:                "before",
+			Url:                 "/api/v1/events/chat/history",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","beforeId":"%s","limit":1}`, testEventId, testChatMessageId2),
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":{"chatMessages":[{"id":"%s","userId":"test_id_1","userName":"test_events_1","userImageId":"","message":"test message","createdAt":"2022-06-01T12:00:00Z"}],"hasMore":false}}`, testChatMessageId1),
+			Handler:             testHandler.getChatHistory,
+		},
+		{
+			Name:                "after",
+			Url:                 "/api/v1/events/chat/history",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","afterId":"%s"}`, testEventId, testChatMessageId2),
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":{"chatMessages":[],"hasMore":false}}`,
+			Handler:             testHandler.getChatHistory,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			testHandlerMethod(test, t)
+		})
+	}
+}
+
+func testEventVisibility(t *testing.T) {
+	ownerTokens, err := testHandler.services.Sessions.Create(context.Background(), service.CreateSessionInput{UserID: testOwnerId, UserName: "integration_tests_events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inviteToken, err := testHandler.tokenManager.GetInviteToken(testLinkEventId, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherInviteToken, err := testHandler.tokenManager.GetInviteToken(testEventId, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []testData{
+		{
+			Name:                "invalid visibility",
+			Url:                 "/api/v1/events/create",
+			Method:              http.MethodPost,
+			Body:                `{"name":"test_events_create","address":"test_events_create","coordinates":{"x":0,"y":0},"visibility":3}`,
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"VisibilityInvalid"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.createEvent),
+		},
+		{
+			Name:                "link anonymous",
+			Url:                 "/api/v1/events/get",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testLinkEventId),
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"EventAccessDenied"}],"result":null}`,
+			Handler:             testHandler.optionalJwtAuth(testHandler.getEventByID),
+		},
+		{
+			Name:                "link invite of another event",
+			Url:                 "/api/v1/events/get",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","inviteToken":"%s"}`, testLinkEventId, otherInviteToken),
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"InvalidInviteToken"}],"result":null}`,
+			Handler:             testHandler.optionalJwtAuth(testHandler.getEventByID),
+		},
+		{
+			Name:                "link chat history without invite",
+			Url:                 "/api/v1/events/chat/history",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testLinkEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"EventAccessDenied"}],"result":null}`,
+			Handler:             testHandler.optionalJwtAuth(testHandler.getChatHistory),
+		},
+		{
+			Name:                "link with invite",
+			Url:                 "/api/v1/events/get",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","inviteToken":"%s"}`, testLinkEventId, inviteToken),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":{"id":"%s","ownerId":"%s","name":"integration_tests_link","address":"integration_tests_link","state":0,"visibility":1,"coordinates":{"x":-170,"y":-85},"usersCount":0,"media":[],"roles":[],"chatMessages":[]}}`, testLinkEventId, testOwnerId),
+			Handler:             testHandler.optionalJwtAuth(testHandler.getEventByID),
+		},
+		{
+			Name:                "link invited member",
+			Url:                 "/api/v1/events/chat/history",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testLinkEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":{"chatMessages":[],"hasMore":false}}`,
+			Handler:             testHandler.optionalJwtAuth(testHandler.getChatHistory),
+		},
+		{
+			Name:                "invite not owner",
+			Url:                 "/api/v1/events/invite",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testLinkEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"UserIsNotOwner"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.createEventInvite),
+		},
+		{
+			Name:                "invite not allowed",
+			Url:                 "/api/v1/events/invite",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"InviteNotAllowed"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.createEventInvite),
+		},
+		{
+			Name:                "approval not requested",
+			Url:                 "/api/v1/events/get",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"EventAccessDenied"}],"result":null}`,
+			Handler:             testHandler.optionalJwtAuth(testHandler.getEventByID),
+		},
+		{
+			Name:                "join request not allowed",
+			Url:                 "/api/v1/events/join/request",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testLinkEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"JoinRequestNotAllowed"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.requestJoinEvent),
+		},
+		{
+			Name:                "join request",
+			Url:                 "/api/v1/events/join/request",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.requestJoinEvent),
+		},
+		{
+			Name:                "join request again",
+			Url:                 "/api/v1/events/join/request",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"JoinRequestAlreadyExist"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.requestJoinEvent),
+		},
+		{
+			Name:                "join requests not owner",
+			Url:                 "/api/v1/events/join/requests",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"UserIsNotOwner"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.getJoinRequests),
+		},
+		{
+			Name:                "approve not owner",
+			Url:                 "/api/v1/events/join/approve",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s"}`, testApprovalEventId, testUserId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"UserIsNotOwner"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.approveJoinRequest),
+		},
+		{
+			Name:                "approve",
+			Url:                 "/api/v1/events/join/approve",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s"}`, testApprovalEventId, testUserId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.approveJoinRequest),
+		},
+		{
+			Name:                "reject missing",
+			Url:                 "/api/v1/events/join/reject",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s"}`, testApprovalEventId, testUserId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"JoinRequestNotFound"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.rejectJoinRequest),
+		},
+		{
+			Name:                "approved member",
+			Url:                 "/api/v1/events/get",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        fmt.Sprintf(`{"isSuccess":true,"errors":null,"result":{"id":"%s","ownerId":"%s","name":"integration_tests_approval","address":"integration_tests_approval","state":0,"visibility":2,"coordinates":{"x":-170,"y":-85},"usersCount":0,"media":[],"roles":[],"chatMessages":[]}}`, testApprovalEventId, testOwnerId),
+			Handler:             testHandler.optionalJwtAuth(testHandler.getEventByID),
+		},
+		{
+			Name:                "join request member",
+			Url:                 "/api/v1/events/join/request",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"UserAlreadyMember"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.requestJoinEvent),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			testHandlerMethod(test, t)
+		})
+	}
+}
+
+func testEventRoles(t *testing.T) {
+	ownerTokens, err := testHandler.services.Sessions.Create(context.Background(), service.CreateSessionInput{UserID: testOwnerId, UserName: "integration_tests_events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []testData{
+		{
+			Name:                "invalid role",
+			Url:                 "/api/v1/events/roles/grant",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s","role":3}`, testApprovalEventId, testUserId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"RoleInvalid"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.grantEventRole),
+		},
+		{
+			Name:                "grant not owner",
+			Url:                 "/api/v1/events/roles/grant",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s","role":2}`, testApprovalEventId, testUserId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"PermissionDenied"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.grantEventRole),
+		},
+		{
+			Name:                "grant to owner",
+			Url:                 "/api/v1/events/roles/grant",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s","role":2}`, testApprovalEventId, testOwnerId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"PermissionDenied"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.grantEventRole),
+		},
+		{
+			Name:                "grant co-host",
+			Url:                 "/api/v1/events/roles/grant",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s","role":2}`, testApprovalEventId, testUserId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.grantEventRole),
+		},
+		{
+			Name:                "co-host join requests",
+			Url:                 "/api/v1/events/join/requests",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":[]}`,
+			Handler:             testHandler.jwtAuth(testHandler.getJoinRequests),
+		},
+		{
+			Name:                "co-host grant",
+			Url:                 "/api/v1/events/roles/grant",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s","role":2}`, testApprovalEventId, testUserId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"PermissionDenied"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.grantEventRole),
+		},
+		{
+			Name:                "revoke",
+			Url:                 "/api/v1/events/roles/revoke",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s"}`, testApprovalEventId, testUserId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.revokeEventRole),
+		},
+		{
+			Name:                "revoke not found",
+			Url:                 "/api/v1/events/roles/revoke",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s"}`, testApprovalEventId, testUserId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"RoleNotFound"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.revokeEventRole),
+		},
+		{
+			Name:                "revoked join requests",
+			Url:                 "/api/v1/events/join/requests",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"UserIsNotOwner"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.getJoinRequests),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			testHandlerMethod(test, t)
+		})
+	}
+}
+
+func testEventModeration(t *testing.T) {
+	ownerTokens, err := testHandler.services.Sessions.Create(context.Background(), service.CreateSessionInput{UserID: testOwnerId, UserName: "integration_tests_events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []testData{
+		{
+			Name:                "delete message not found",
+			Url:                 "/api/v1/events/chat/delete",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","messageId":"%s"}`, testApprovalEventId, uuid.New().String()),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"ChatMessageNotFound"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.deleteChatMessage),
+		},
+		{
+			Name:                "delete message without message id",
+			Url:                 "/api/v1/events/chat/delete",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","messageId":""}`, testApprovalEventId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"IdIsEmpty"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.deleteChatMessage),
+		},
+		{
+			Name:                "delete message not moderator",
+			Url:                 "/api/v1/events/chat/delete",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","messageId":"%s"}`, testApprovalEventId, uuid.New().String()),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"PermissionDenied"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.deleteChatMessage),
+		},
+		{
+			Name:                "invalid mute minutes",
+			Url:                 "/api/v1/events/users/mute",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s","minutes":0}`, testApprovalEventId, testUserId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"MinutesInvalid"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.muteEventUser),
+		},
+		{
+			Name:                "mute owner",
+			Url:                 "/api/v1/events/users/mute",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s","minutes":10}`, testApprovalEventId, testOwnerId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"PermissionDenied"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.muteEventUser),
+		},
+		{
+			Name:                "mute",
+			Url:                 "/api/v1/events/users/mute",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s","minutes":10}`, testApprovalEventId, testUserId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.muteEventUser),
+		},
+		{
+			Name:                "kick owner",
+			Url:                 "/api/v1/events/users/kick",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s"}`, testApprovalEventId, testOwnerId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"PermissionDenied"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.kickEventUser),
+		},
+		{
+			Name:                "kick",
+			Url:                 "/api/v1/events/users/kick",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s"}`, testApprovalEventId, testUserId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.kickEventUser),
+		},
+		{
+			Name:                "ban",
+			Url:                 "/api/v1/events/users/ban",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","userId":"%s"}`, testApprovalEventId, testUserId),
+			AuthHeader:          ownerTokens.AccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.banEventUser),
+		},
+		{
+			Name:                "banned get",
+			Url:                 "/api/v1/events/get",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"UserBanned"}],"result":null}`,
+			Handler:             testHandler.optionalJwtAuth(testHandler.getEventByID),
+		},
+		{
+			Name:                "banned join request",
+			Url:                 "/api/v1/events/join/request",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"UserBanned"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.requestJoinEvent),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			testHandlerMethod(test, t)
+		})
+	}
+}
+
+func testEventStream(t *testing.T) {
+	tests := []testData{
+		{
+			Name:                "send empty message",
+			Url:                 "/api/v1/events/chat/send",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","replyToId":"123"}`, testEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"InvalidIdFormat"},{"errorCode":"MessageIsEmpty"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.sendChatMessage),
+		},
+		{
+			Name:                "send as banned user",
+			Url:                 "/api/v1/events/chat/send",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","message":"lol"}`, testApprovalEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"UserBanned"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.sendChatMessage),
+		},
+		{
+			Name:                "stream invalid event id",
+			Url:                 "/api/v1/events/stream?eventId=123",
+			Method:              http.MethodGet,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"InvalidIdFormat"}],"result":null}`,
+			Handler:             testHandler.optionalJwtAuth(testHandler.streamEvent),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			testHandlerMethod(test, t)
+		})
+	}
+
+	t.Run("send and receive", func(t *testing.T) {
+		server := httptest.NewServer(testHandler.queryAccessToken(testHandler.optionalJwtAuth(testHandler.GET(testHandler.streamEvent))))
+		defer server.Close()
+		response, err := http.Get(fmt.Sprintf("%s?eventId=%s&accessToken=%s", server.URL, testEventId, testUserAccessToken))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer response.Body.Close()
+		if response.Header.Get("Content-Type") != contentTypeEventStream {
+			t.Fatalf("Incorrect Content-Type: %s", response.Header.Get("Content-Type"))
+		}
+
+		testHandlerMethod(testData{
+			Url:                 "/api/v1/events/chat/send",
+			Method:              http.MethodPost,
+			Body:                fmt.Sprintf(`{"eventId":"%s","message":"streamed"}`, testEventId),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.sendChatMessage),
+		}, t)
+
+		reader := bufio.NewReader(response.Body)
+		lines := make([]string, 3)
+
+		for i := range lines {
+			if lines[i], err = reader.ReadString('\n'); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if !strings.HasPrefix(lines[0], "id: ") || lines[1] != "event: chatMessage\n" || !strings.Contains(lines[2], `"message":"streamed"`) {
+			t.Fatalf("Incorrect stream event: %q", lines)
+		}
+	})
+
+	t.Run("disconnect while publishing", func(t *testing.T) {
+		server := httptest.NewServer(testHandler.optionalJwtAuth(testHandler.GET(testHandler.streamEvent)))
+		defer server.Close()
+		goroutines := runtime.NumGoroutine()
+		message := []byte(`chatMessage/{"message":"` + strings.Repeat("a", 1<<16) + `"}`)
+
+		for i := 0; i < 10; i++ {
+			ctx, cancel := context.WithCancel(context.Background())
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s?eventId=%s", server.URL, testEventId), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the unread messages fill the connection, so the handler stops reading them while the publisher still sends
+			for j := 0; j < 100; j++ {
+				testHandler.services.Publisher.Publish(testEventId, message)
+			}
+
+			cancel()
+			_ = response.Body.Close()
+		}
+
+		server.CloseClientConnections()
+		http.DefaultClient.CloseIdleConnections()
+		deadline := time.Now().Add(time.Second * 5)
+
+		for runtime.NumGoroutine() > goroutines {
+			if time.Now().After(deadline) {
+				t.Fatalf("Goroutines left after disconnect\nExpected: %d\nActual: %d", goroutines, runtime.NumGoroutine())
+			}
+
+			time.Sleep(time.Millisecond * 10)
+		}
+	})
+}
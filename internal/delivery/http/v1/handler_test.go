@@ -16,8 +16,10 @@ import (
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/broker"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/hash"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/sms"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/storage"
 )
 
@@ -35,18 +37,29 @@ type testData struct {
 	Handler             http.HandlerFunc
 }
 
+const testSMSFilePath = "sms/messages.log"
+
 var testHandler *Handler
+var testUserId string
 var testUserAccessToken string
+var testOwnerId string
+var testLogoutAccessToken string
+var testLogoutRefreshToken string
 var testEventId string
+var testChatMessageId1 string
+var testChatMessageId2 string
 var testEventId10 string
 var testEventId25 string
 var testEventId50 string
 var testEventId75 string
 var testEventId100 string
+var testLinkEventId string
+var testApprovalEventId string
 
 func TestHandler(t *testing.T) {
 	defer os.RemoveAll("logs")
 	defer os.RemoveAll("media")
+	defer os.RemoveAll("sms")
 	appLogger, logFile, err := logger.NewZeroLogger()
 	if err != nil {
 		log.Fatal(err)
@@ -61,24 +74,29 @@ func TestHandler(t *testing.T) {
 
 	configuration.ServerPort = 9090
 	configuration.DbName = "test"
+	if os.Getenv("DB_DRIVER") == "" {
+		configuration.DbDriver = repository.DriverMemory
+	}
 	tokenManager := auth.NewJwtManager(configuration.JWTKey, configuration.JWTIssuer, configuration.JWTAudience, time.Minute*5)
-	repositories, cleaner, err := repository.NewRepositories(configuration.DbConnection, configuration.DbName)
+	repositories, cleaner, err := repository.NewRepositories(configuration.DbDriver, configuration.DbConnection, configuration.DbName)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	defer cleaner.Clean()
-	hashManager, err := hash.NewPasswordHashManager(configuration.HashKey)
+	legacyHashManager, err := hash.NewHMACPasswordHashManager(configuration.HashKey)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	hashManager := hash.NewArgon2idPasswordHashManager(hash.DefaultArgon2idParams(), legacyHashManager)
+
 	password, err := hashManager.HashPassword("string")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	userId, err := repositories.Users.CreateUser(context.Background(), domain.User{
+	testUserId, err = repositories.Users.CreateUser(context.Background(), domain.User{
 		Name:     "integration_tests",
 		Phone:    "1111111111",
 		Password: password,
@@ -87,12 +105,7 @@ func TestHandler(t *testing.T) {
 		log.Fatal(err)
 	}
 
-	testUserAccessToken, err = tokenManager.GetAccessToken(auth.TokenData{ID: userId, Name: "integration_tests"})
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	_, err = repositories.Users.CreateUser(context.Background(), domain.User{
+	testOwnerId, err = repositories.Users.CreateUser(context.Background(), domain.User{
 		Name:     "integration_tests_events",
 		Phone:    "9090909090",
 		Password: password,
@@ -107,8 +120,8 @@ func TestHandler(t *testing.T) {
 		Address: "integration_tests",
 		State:   domain.EventStateOpened,
 		Coordinates: domain.Coordinates{
-			X: 99999,
-			Y: 99999,
+			X: -170,
+			Y: -85,
 		},
 		Users: []domain.UserInfo{
 			{ID: "owner_id"},
@@ -119,18 +132,26 @@ func TestHandler(t *testing.T) {
 				ContentType: "image/jpeg",
 			},
 		},
-		ChatMessages: []domain.ChatMessage{
-			{
-				UserID:   "test_id_1",
-				UserName: "test_events_1",
-				Message:  "test message",
-			},
-			{
-				UserID:   "test_id_2",
-				UserName: "test_events_2",
-				Message:  "another message",
-			},
-		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	testChatMessageId1, err = repositories.Events.AddChatMessage(context.Background(), testEventId, domain.ChatMessage{
+		UserID:    "test_id_1",
+		UserName:  "test_events_1",
+		Message:   "test message",
+		CreatedAt: time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	testChatMessageId2, err = repositories.Events.AddChatMessage(context.Background(), testEventId, domain.ChatMessage{
+		UserID:    "test_id_2",
+		UserName:  "test_events_2",
+		Message:   "another message",
+		CreatedAt: time.Date(2022, 6, 1, 12, 1, 0, 0, time.UTC),
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -141,19 +162,43 @@ func TestHandler(t *testing.T) {
 		log.Fatal(err)
 	}
 
+	err = initEventsForVisibilityTest(repositories.Events)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	fileStorage, err := storage.NewLocalFileStorage("media")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	services, err := service.NewServices(appLogger, repositories, hashManager, tokenManager, fileStorage)
+	smsSender, err := sms.NewFileSender(testSMSFilePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	services, err := service.NewServices(appLogger, repositories, hashManager, tokenManager, fileStorage, smsSender, time.Hour, broker.NewMemoryBroker(), service.PublisherOptions{}, service.MediaOptions{}, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tokens, err := services.Sessions.Create(context.Background(), service.CreateSessionInput{UserID: testUserId, UserName: "integration_tests"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	testUserAccessToken = tokens.AccessToken
+	tokens, err = services.Sessions.Create(context.Background(), service.CreateSessionInput{UserID: testUserId, UserName: "integration_tests"})
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	testLogoutAccessToken = tokens.AccessToken
+	testLogoutRefreshToken = tokens.RefreshToken
 	testHandler = NewHandler(appLogger, services, tokenManager)
-	t.Run("users", TestUsers)
-	t.Run("events", TestEvents)
+	t.Run("users", testUsers)
+	t.Run("events", testEvents)
+	t.Run("media", testMedia)
 }
 
 func testHandlerMethod(testData testData, t *testing.T) {
@@ -198,9 +243,8 @@ func initEventsForRangeTest(events repository.Events) error {
 			X: 10,
 			Y: 10,
 		},
-		Users:        []domain.UserInfo{},
-		Media:        []domain.MediaInfo{},
-		ChatMessages: []domain.ChatMessage{},
+		Users: []domain.UserInfo{},
+		Media: []domain.MediaInfo{},
 	})
 	if err != nil {
 		return err
@@ -216,9 +260,8 @@ func initEventsForRangeTest(events repository.Events) error {
 			X: 25,
 			Y: 25,
 		},
-		Users:        []domain.UserInfo{},
-		Media:        []domain.MediaInfo{},
-		ChatMessages: []domain.ChatMessage{},
+		Users: []domain.UserInfo{},
+		Media: []domain.MediaInfo{},
 	})
 	if err != nil {
 		return err
@@ -233,9 +276,8 @@ func initEventsForRangeTest(events repository.Events) error {
 			X: 50,
 			Y: 50,
 		},
-		Users:        []domain.UserInfo{},
-		Media:        []domain.MediaInfo{},
-		ChatMessages: []domain.ChatMessage{},
+		Users: []domain.UserInfo{},
+		Media: []domain.MediaInfo{},
 	})
 	if err != nil {
 		return err
@@ -250,9 +292,8 @@ func initEventsForRangeTest(events repository.Events) error {
 			X: 75,
 			Y: 75,
 		},
-		Users:        []domain.UserInfo{},
-		Media:        []domain.MediaInfo{},
-		ChatMessages: []domain.ChatMessage{},
+		Users: []domain.UserInfo{},
+		Media: []domain.MediaInfo{},
 	})
 	if err != nil {
 		return err
@@ -265,11 +306,10 @@ func initEventsForRangeTest(events repository.Events) error {
 		State:   domain.EventStateOpened,
 		Coordinates: domain.Coordinates{
 			X: 100,
-			Y: 100,
+			Y: 80,
 		},
-		Users:        []domain.UserInfo{},
-		Media:        []domain.MediaInfo{},
-		ChatMessages: []domain.ChatMessage{},
+		Users: []domain.UserInfo{},
+		Media: []domain.MediaInfo{},
 	})
 	if err != nil {
 		return err
@@ -277,3 +317,38 @@ func initEventsForRangeTest(events repository.Events) error {
 
 	return nil
 }
+
+func initEventsForVisibilityTest(events repository.Events) error {
+	var err error
+	testLinkEventId, err = events.CreateEvent(context.Background(), domain.Event{
+		OwnerID:    testOwnerId,
+		Name:       "integration_tests_link",
+		Address:    "integration_tests_link",
+		State:      domain.EventStateOpened,
+		Visibility: domain.EventVisibilityLink,
+		Coordinates: domain.Coordinates{
+			X: -170,
+			Y: -85,
+		},
+		Users: []domain.UserInfo{},
+		Media: []domain.MediaInfo{},
+	})
+	if err != nil {
+		return err
+	}
+
+	testApprovalEventId, err = events.CreateEvent(context.Background(), domain.Event{
+		OwnerID:    testOwnerId,
+		Name:       "integration_tests_approval",
+		Address:    "integration_tests_approval",
+		State:      domain.EventStateOpened,
+		Visibility: domain.EventVisibilityApproval,
+		Coordinates: domain.Coordinates{
+			X: -170,
+			Y: -85,
+		},
+		Users: []domain.UserInfo{},
+		Media: []domain.MediaInfo{},
+	})
+	return err
+}
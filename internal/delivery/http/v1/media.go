@@ -1,9 +1,9 @@
 package v1
 
 import (
-	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
@@ -11,7 +11,7 @@ import (
 )
 
 func (h *Handler) initMediaAPI(mux *http.ServeMux) {
-	mux.HandleFunc("/api/v1/media", h.POST(h.uploadMedia))
+	mux.HandleFunc("/api/v1/media", h.POST(h.optionalJwtAuth(h.uploadMedia)))
 	mux.HandleFunc("/api/v1/media/metadata/", h.GET(h.getMetadata))
 	mux.HandleFunc("/api/v1/media/", func(writer http.ResponseWriter, request *http.Request) {
 		switch request.Method {
@@ -30,6 +30,12 @@ func (h *Handler) initMediaAPI(mux *http.ServeMux) {
 
 // UploadMedia godoc
 // @Summary      Загрузить медиафайл
+// @Description  Картинка (jpeg, png, gif, webp, heic) или видео (mp4, mov, webm), тип определяется по содержимому файла.
+// @Description  Ошибки: UnsupportedMediaType - другой тип, FileTooLarge - больше MEDIA_MAX_IMAGE_SIZE_MB для картинок
+// @Description  или MEDIA_MAX_VIDEO_SIZE_MB для видео, FileIsEmpty - пустой файл, FileIsMissing - нет файла в форме
+// @Description  Из картинок удаляются метаданные (EXIF с геолокацией и устройством), кроме ориентации, если не MEDIA_STRIP_METADATA=false
+// @Description  Вложением в чат можно отправить только файл, загруженный с авторизацией этим же пользователем, или медиа эвента
+// @Security     UserAuth
 // @Tags         media
 // @Accept       multipart/form-data
 // @Produce      json
@@ -38,17 +44,20 @@ func (h *Handler) initMediaAPI(mux *http.ServeMux) {
 // @Success      200 {object} apiResponse{result=string}
 // @Router       /v1/media [post]
 func (h *Handler) uploadMedia(writer http.ResponseWriter, request *http.Request) {
-	data, header, isOk := h.parseFormFile(writer, request, "file")
+	file, header, isOk := h.parseFormFile(writer, request, "file", h.services.Media.MaxSize(service.MediaContentTypes))
 
 	if !isOk {
 		return
 	}
 
+	defer file.Close()
 	mediaId, err := h.services.Media.Create(request.Context(), service.CreateMediaInput{
-		Name:        header.Filename,
-		ContentType: header.Header.Get("Content-Type"),
-		Size:        header.Size,
-		Data:        data,
+		Name:         header.Filename,
+		ContentType:  header.Header.Get("Content-Type"),
+		OwnerID:      getUserID(request),
+		Size:         header.Size,
+		Data:         file,
+		AllowedTypes: service.MediaContentTypes,
 	})
 
 	if err != nil {
@@ -61,16 +70,27 @@ func (h *Handler) uploadMedia(writer http.ResponseWriter, request *http.Request)
 
 // GetMedia godoc
 // @Summary      Получить медиафайл
+// @Description  Поддерживает Range (перемотка видео) и If-Modified-Since.
+// @Description  Если включены presigned ссылки хранилища S3, отвечает редиректом 302 на файл в хранилище,
+// @Description  кроме старых файлов не медиа-типов, которые отдаются через API только для скачивания.
+// @Description  Для картинок size отдает уменьшенную копию в jpeg (64, 256 или 1024 px по большей стороне):
+// @Description  наименьшую не меньше size, если такой нет - оригинал. Доступные копии - в variants метаинформации
 // @Tags         media
 // @Accept       */*
 // @Content-Type */*
-// @param        id   path      string  true  "media ID"
+// @param        id    path      string  true   "media ID"
+// @param        size  query     int     false  "размер копии картинки в px"
+// @param        Range header    string  false  "диапазон байт, например bytes=0-1023"
 // @Success      200
+// @Success      206
+// @Success      304
+// @Success      302
 // @Failure      400
 // @Failure      404
+// @Failure      416
 // @Router       /v1/media/{id} [get]
 func (h *Handler) getMedia(writer http.ResponseWriter, request *http.Request) {
-	mediaId := strings.TrimPrefix(request.RequestURI, "/api/v1/media/")
+	mediaId := strings.TrimPrefix(request.URL.Path, "/api/v1/media/")
 
 	if mediaId == "" {
 		writer.WriteHeader(http.StatusBadRequest)
@@ -90,36 +110,64 @@ func (h *Handler) getMedia(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	mediaData, err := h.services.Media.GetFile(request.Context(), mediaId)
+	size := 0
 
-	if err != nil {
-		if err == domain.ErrMediaNotFound {
-			writer.WriteHeader(http.StatusNotFound)
+	if value := request.URL.Query().Get("size"); value != "" {
+		if size, err = strconv.Atoi(value); err != nil || size <= 0 {
+			writer.WriteHeader(http.StatusBadRequest)
 			return
 		}
+	}
 
-		h.logger.LogError(err)
-		writer.WriteHeader(http.StatusInternalServerError)
+	fileURL, err := h.services.Media.GetFileURL(request.Context(), mediaId, size)
+
+	if err != nil {
+		h.writeMediaError(writer, err)
+		return
+	}
+
+	if fileURL != "" {
+		http.Redirect(writer, request, fileURL, http.StatusFound)
 		return
 	}
 
-	writer.Header().Set("Content-Type", mediaData.ContentType)
-	writer.Header().Set("Content-Length", fmt.Sprintf("%d", mediaData.Size))
-	writer.WriteHeader(http.StatusOK)
-	_, err = writer.Write(mediaData.Data)
+	mediaData, err := h.services.Media.GetFile(request.Context(), mediaId, size)
 
 	if err != nil {
-		h.logger.LogError(err)
-		writer.WriteHeader(http.StatusInternalServerError)
+		h.writeMediaError(writer, err)
 		return
 	}
+
+	defer mediaData.Data.Close()
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// files uploaded before the type checks may be anything, like html, so they are only downloaded
+	if service.IsMediaContentType(mediaData.ContentType) {
+		writer.Header().Set("Content-Type", mediaData.ContentType)
+	} else {
+		writer.Header().Set("Content-Type", "application/octet-stream")
+		writer.Header().Set("Content-Disposition", "attachment")
+	}
+
+	http.ServeContent(writer, request, "", mediaData.LastModifiedDate, mediaData.Data)
+}
+
+func (h *Handler) writeMediaError(writer http.ResponseWriter, err error) {
+	if err == domain.ErrMediaNotFound {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	h.logger.LogError(err)
+	writer.WriteHeader(http.StatusInternalServerError)
 }
 
 type fileMetadataResponse struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Size        int64  `json:"size"`
-	ContentType string `json:"contentType"`
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	Size        int64                 `json:"size"`
+	ContentType string                `json:"contentType"`
+	Variants    []domain.MediaVariant `json:"variants"`
 }
 
 // GetMetadata godoc
@@ -158,6 +206,7 @@ func (h *Handler) getMetadata(writer http.ResponseWriter, request *http.Request)
 		Name:        metadata.Name,
 		Size:        metadata.Size,
 		ContentType: metadata.ContentType,
+		Variants:    metadata.Variants,
 	}))
 }
 
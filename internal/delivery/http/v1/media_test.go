@@ -0,0 +1,176 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
+)
+
+// testMP4Header is the ftyp box the mp4 files start with.
+const testMP4Header = "\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"
+
+func testMedia(t *testing.T) {
+	data := append([]byte(testMP4Header), bytes.Repeat([]byte("0123456789"), 100)...)
+	body, contentType := newTestMultipartFile(t, "file", "video.mp4", "text/html", data)
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/media", body)
+	request.Header.Set("Content-Type", contentType)
+	recorder := httptest.NewRecorder()
+	testHandler.POST(testHandler.uploadMedia).ServeHTTP(recorder, request)
+	response := apiResponse{}
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	mediaId, ok := response.Result.(string)
+
+	if !response.IsSuccess || !ok {
+		t.Fatalf("Upload failed: %s", recorder.Body.String())
+	}
+
+	t.Run("get", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/media/"+mediaId, nil)
+		recorder := httptest.NewRecorder()
+		testHandler.getMedia(recorder, request)
+		result := recorder.Result()
+
+		if result.StatusCode != http.StatusOK || result.Header.Get("Content-Type") != "video/mp4" || result.Header.Get("X-Content-Type-Options") != "nosniff" ||
+			result.Header.Get("Accept-Ranges") != "bytes" || !bytes.Equal(recorder.Body.Bytes(), data) {
+			t.Fatalf("Incorrect response: %d %v", result.StatusCode, result.Header)
+		}
+
+		request = httptest.NewRequest(http.MethodGet, "/api/v1/media/"+mediaId, nil)
+		request.Header.Set("If-Modified-Since", result.Header.Get("Last-Modified"))
+		recorder = httptest.NewRecorder()
+		testHandler.getMedia(recorder, request)
+
+		if recorder.Code != http.StatusNotModified {
+			t.Fatalf("Expected status code: %d\nActual status code: %d", http.StatusNotModified, recorder.Code)
+		}
+	})
+
+	t.Run("get size", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/media/"+mediaId+"?size=64", nil)
+		recorder := httptest.NewRecorder()
+		testHandler.getMedia(recorder, request)
+
+		if recorder.Code != http.StatusOK || !bytes.Equal(recorder.Body.Bytes(), data) {
+			t.Fatalf("Expected the original for a video, got status code %d", recorder.Code)
+		}
+
+		request = httptest.NewRequest(http.MethodGet, "/api/v1/media/"+mediaId+"?size=big", nil)
+		recorder = httptest.NewRecorder()
+		testHandler.getMedia(recorder, request)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status code: %d\nActual status code: %d", http.StatusBadRequest, recorder.Code)
+		}
+	})
+
+	t.Run("get range", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/media/"+mediaId, nil)
+		request.Header.Set("Range", "bytes=24-33")
+		recorder := httptest.NewRecorder()
+		testHandler.getMedia(recorder, request)
+		result := recorder.Result()
+
+		if result.StatusCode != http.StatusPartialContent || result.Header.Get("Content-Range") != "bytes 24-33/1024" ||
+			recorder.Body.String() != "0123456789" {
+			t.Fatalf("Incorrect response: %d %v %s", result.StatusCode, result.Header, recorder.Body.String())
+		}
+	})
+
+	t.Run("upload too large", func(t *testing.T) {
+		body, contentType := newTestMultipartFile(t, "image", "photo.jpg", "image/jpeg", make([]byte, testHandler.services.Media.MaxSize(service.ImageContentTypes)+1))
+		testHandlerMethod(testData{
+			Url:                 "/api/v1/users/media/set",
+			Method:              http.MethodPost,
+			Body:                body.String(),
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentType,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"FileTooLarge"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.setUserImage),
+		}, t)
+	})
+
+	t.Run("upload html", func(t *testing.T) {
+		body, contentType := newTestMultipartFile(t, "file", "photo.jpg", "image/jpeg", []byte("<html><script>alert(1)</script></html>"))
+		testHandlerMethod(testData{
+			Url:                 "/api/v1/media",
+			Method:              http.MethodPost,
+			Body:                body.String(),
+			RequestContentType:  contentType,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"UnsupportedMediaType"}],"result":null}`,
+			Handler:             testHandler.uploadMedia,
+		}, t)
+	})
+
+	t.Run("upload without file", func(t *testing.T) {
+		body, contentType := newTestMultipartFile(t, "image", "photo.jpg", "image/jpeg", []byte(testMP4Header))
+		testHandlerMethod(testData{
+			Url:                 "/api/v1/media",
+			Method:              http.MethodPost,
+			Body:                body.String(),
+			RequestContentType:  contentType,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"FileIsMissing"}],"result":null}`,
+			Handler:             testHandler.uploadMedia,
+		}, t)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		testHandlerMethod(testData{
+			Url:                 "/api/v1/media/" + mediaId,
+			Method:              http.MethodDelete,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.deleteMedia,
+		}, t)
+
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/media/"+mediaId, nil)
+		recorder := httptest.NewRecorder()
+		testHandler.getMedia(recorder, request)
+
+		if recorder.Code != http.StatusNotFound {
+			t.Fatalf("Expected status code: %d\nActual status code: %d", http.StatusNotFound, recorder.Code)
+		}
+	})
+}
+
+func newTestMultipartFile(t *testing.T, field string, filename string, contentType string, data []byte) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="`+field+`"; filename="`+filename+`"`)
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = part.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return body, writer.FormDataContentType()
+}
@@ -6,17 +6,25 @@ import (
 	"errors"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
 
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
 )
 
 const invalidMethod = "invalid method"
 
 type userID string
+type sessionID string
+type userName string
+type userImageID string
 
 var userIdKey = userID("UserID")
+var sessionIdKey = sessionID("SessionID")
+var userNameKey = userName("UserName")
+var userImageIdKey = userImageID("UserImageID")
 var unauthorizedResponse = newErrorResponse(auth.ErrInvalidToken.Error())
 
 func (h *Handler) GET(next http.HandlerFunc) http.HandlerFunc {
@@ -80,27 +88,29 @@ func (h *Handler) Recover(next http.Handler) http.Handler {
 
 func (h *Handler) Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-		if strings.Contains(request.RequestURI, "/api/") && !strings.Contains(request.RequestURI, "/websockets/") {
-			var buf bytes.Buffer
-			tee := io.TeeReader(request.Body, &buf)
-			body, err := ioutil.ReadAll(tee)
-			if err != nil {
-				h.logger.LogError(err)
-				h.writeJSONResponse(writer, newErrorResponse(internalError))
-				return
-			}
-
+		if strings.Contains(request.RequestURI, "/api/") && !strings.Contains(request.RequestURI, "/websockets/") &&
+			!strings.Contains(request.RequestURI, "/events/stream") {
 			requestContentType := request.Header.Get("Content-Type")
 			switch requestContentType {
 			case contentTypeJSON:
+				var buf bytes.Buffer
+				tee := io.TeeReader(request.Body, &buf)
+				body, err := ioutil.ReadAll(tee)
+				if err != nil {
+					h.logger.LogError(err)
+					h.writeJSONResponse(writer, newErrorResponse(internalError))
+					return
+				}
+
 				h.logger.LogHttpRequest(request.RequestURI, request.Method, string(body), requestContentType)
+				request.Body = io.NopCloser(&buf)
 				break
 			default:
+				// other bodies, like uploaded files, are streamed to the handler as is
 				h.logger.LogHttpRequest(request.RequestURI, request.Method, "(hidden)", requestContentType)
 				break
 			}
 
-			request.Body = io.NopCloser(&buf)
 			writerWithState := newLoggingWriter(writer)
 			next.ServeHTTP(writerWithState, request)
 			responseContentType := writer.Header().Get("Content-Type")
@@ -119,10 +129,24 @@ func (h *Handler) Logging(next http.Handler) http.Handler {
 }
 
 func (h *Handler) jwtAuth(next http.HandlerFunc) http.HandlerFunc {
+	return h.authenticate(next, true)
+}
+
+// optionalJwtAuth passes anonymous requests through and authenticates the ones with the Authorization header.
+func (h *Handler) optionalJwtAuth(next http.HandlerFunc) http.HandlerFunc {
+	return h.authenticate(next, false)
+}
+
+func (h *Handler) authenticate(next http.HandlerFunc, isRequired bool) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
 		headerValue := request.Header.Get("Authorization")
 
 		if headerValue == "" {
+			if !isRequired {
+				next.ServeHTTP(writer, request)
+				return
+			}
+
 			h.writeJSONResponse(writer, unauthorizedResponse)
 			return
 		}
@@ -160,7 +184,22 @@ func (h *Handler) jwtAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		next.ServeHTTP(writer, request.WithContext(context.WithValue(request.Context(), userIdKey, userID(token.ID))))
+		if err = h.services.Sessions.Check(request.Context(), token.ID, token.SessionID); err != nil {
+			if errors.Is(err, domain.ErrSessionNotFound) {
+				h.writeJSONResponse(writer, unauthorizedResponse)
+				return
+			}
+
+			h.logger.LogError(err)
+			h.writeJSONResponse(writer, newErrorResponse(internalError))
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), userIdKey, userID(token.ID))
+		ctx = context.WithValue(ctx, sessionIdKey, sessionID(token.SessionID))
+		ctx = context.WithValue(ctx, userNameKey, userName(token.Name))
+		ctx = context.WithValue(ctx, userImageIdKey, userImageID(token.ImageID))
+		next.ServeHTTP(writer, request.WithContext(ctx))
 	}
 }
 
@@ -173,3 +212,51 @@ func getUserID(request *http.Request) string {
 
 	return string(value)
 }
+
+func getSessionID(request *http.Request) string {
+	value, ok := request.Context().Value(sessionIdKey).(sessionID)
+
+	if !ok {
+		return ""
+	}
+
+	return string(value)
+}
+
+func getUserName(request *http.Request) string {
+	value, ok := request.Context().Value(userNameKey).(userName)
+
+	if !ok {
+		return ""
+	}
+
+	return string(value)
+}
+
+func getUserImageID(request *http.Request) string {
+	value, ok := request.Context().Value(userImageIdKey).(userImageID)
+
+	if !ok {
+		return ""
+	}
+
+	return string(value)
+}
+
+func getDeviceInfo(request *http.Request, deviceName string) domain.DeviceInfo {
+	ipAddress := request.Header.Get("X-Real-IP")
+
+	if ipAddress == "" {
+		if host, _, err := net.SplitHostPort(request.RemoteAddr); err == nil {
+			ipAddress = host
+		} else {
+			ipAddress = request.RemoteAddr
+		}
+	}
+
+	return domain.DeviceInfo{
+		Name:      deviceName,
+		UserAgent: request.UserAgent(),
+		IPAddress: ipAddress,
+	}
+}
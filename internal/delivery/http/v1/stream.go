@@ -0,0 +1,204 @@
+package v1
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/protocol"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
+)
+
+const (
+	contentTypeEventStream = "text/event-stream"
+	streamKeepAlivePeriod  = time.Second * 30
+)
+
+// streamedTypes are the published messages sent to the event stream.
+var streamedTypes = map[string]bool{
+	protocol.TypeEventUpdated: true,
+	protocol.TypeChatMessage:  true,
+	protocol.TypeMediaAdded:   true,
+	protocol.TypeMediaRemoved: true,
+	protocol.TypeCloseEvent:   true,
+	protocol.TypeResync:       true,
+}
+
+type streamMessage struct {
+	seq     uint64
+	message protocol.Message
+}
+
+type streamSubscriber struct {
+	userId string
+	ch     chan streamMessage
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newStreamSubscriber(userId string) *streamSubscriber {
+	return &streamSubscriber{
+		userId: userId,
+		ch:     make(chan streamMessage),
+		closed: make(chan struct{}),
+	}
+}
+
+func (s *streamSubscriber) OnReceive(seq uint64, data []byte) {
+	message, err := protocol.DecodeV1(data)
+
+	if err != nil {
+		return
+	}
+
+	if streamedTypes[message.Type] {
+		select {
+		case s.ch <- streamMessage{seq: seq, message: message}:
+		case <-s.closed:
+			return
+		}
+	}
+
+	// a kicked or banned user gets no more updates
+	if protocol.RemovesUser(message, s.userId) {
+		s.OnClose()
+	}
+}
+
+func (s *streamSubscriber) OnClose() {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+}
+
+// queryAccessToken lets clients that can't set headers, like the browser EventSource, pass the token in the query.
+func (h *Handler) queryAccessToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if token := request.URL.Query().Get("accessToken"); token != "" && request.Header.Get("Authorization") == "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(writer, request)
+	}
+}
+
+// StreamEvent godoc
+// @Summary      Обновления эвента через Server-Sent Events
+// @Description  Замена вебсокету эвента для клиентов, у которых он недоступен. События eventUpdated, chatMessage, mediaAdded,
+// @Description  mediaRemoved и closeEvent, id события - номер сообщения эвента, data - json как в payload вебсокета v2.
+// @Description  При переподключении с заголовком Last-Event-ID досылаются пропущенные события, если их уже нет - приходит resync.
+// @Description  Авторизация необязательна, токен можно передать в accessToken, доступ к закрытым эвентам как в /v1/events/get.
+// @Description  Сообщения в чат отправляются через /v1/events/chat/send
+// @Security     UserAuth
+// @Tags         events
+// @Produce      text/event-stream
+// @param        eventId     query string true  "id эвента"
+// @param        inviteToken query string false "токен приглашения"
+// @param        accessToken query string false "jwt токен, если нельзя передать заголовок"
+// @Success      200
+// @Router       /v1/events/stream [get]
+func (h *Handler) streamEvent(writer http.ResponseWriter, request *http.Request) {
+	eventId := request.URL.Query().Get("eventId")
+	validationErrs, err := validateId(eventId)
+
+	if err != nil {
+		h.logger.LogError(err)
+		h.writeJSONResponse(writer, newErrorResponse(internalError))
+		return
+	}
+
+	if len(validationErrs) > 0 {
+		h.writeJSONResponse(writer, newValidationErrsResponse(validationErrs))
+		return
+	}
+
+	flusher, ok := writer.(http.Flusher)
+
+	if !ok {
+		h.writeJSONResponse(writer, newErrorResponse(internalError))
+		return
+	}
+
+	userId := getUserID(request)
+	err = h.services.Events.CheckAccess(request.Context(), service.EventAccessInput{
+		EventID:     eventId,
+		UserID:      userId,
+		InviteToken: request.URL.Query().Get("inviteToken"),
+	})
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	sub := newStreamSubscriber(userId)
+
+	if lastEventId := request.Header.Get("Last-Event-ID"); lastEventId != "" {
+		lastSeq, err := strconv.ParseUint(lastEventId, 10, 64)
+
+		if err != nil {
+			h.writeJSONResponse(writer, newErrorResponse(invalidLastEventIdError))
+			return
+		}
+
+		h.services.Publisher.Resume(eventId, lastSeq, sub)
+	} else {
+		h.services.Publisher.Subscribe(eventId, sub)
+	}
+
+	// closing the subscriber releases the publisher blocked on a message nobody reads anymore
+	defer sub.OnClose()
+	defer h.services.Publisher.Unsubscribe(eventId, sub)
+	writer.Header().Set("Content-Type", contentTypeEventStream)
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("X-Accel-Buffering", "no")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	ticker := time.NewTicker(streamKeepAlivePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-sub.closed:
+			return
+		case <-ticker.C:
+			if _, err = writer.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+		case message := <-sub.ch:
+			data, err := encodeStreamEvent(message)
+
+			if err != nil {
+				h.logger.LogError(err)
+				continue
+			}
+
+			if _, err = writer.Write(data); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+func encodeStreamEvent(message streamMessage) ([]byte, error) {
+	payload, err := protocol.EncodePayload(message.message)
+
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bytes.Buffer{}
+	buffer.WriteString("id: ")
+	buffer.WriteString(strconv.FormatUint(message.seq, 10))
+	buffer.WriteString("\nevent: ")
+	buffer.WriteString(message.message.Type)
+	buffer.WriteString("\ndata: ")
+	buffer.Write(payload)
+	buffer.WriteString("\n\n")
+	return buffer.Bytes(), nil
+}
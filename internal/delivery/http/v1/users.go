@@ -3,6 +3,7 @@ package v1
 import (
 	"net/http"
 	"regexp"
+	"time"
 
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
 )
@@ -19,23 +20,32 @@ const (
 	invalidPhoneFormatError     = "PhoneRegexInvalid"
 	invalidPasswordLengthError  = "PasswordLengthInvalid"
 	invalidConfirmPasswordError = "ConfirmPasswordInvalid"
+	emptyRefreshTokenError      = "RefreshTokenIsEmpty"
+	emptyCodeError              = "CodeIsEmpty"
 )
 
 func (h *Handler) initUsersAPI(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/users/create", h.POST(h.createUser))
 	mux.HandleFunc("/api/v1/users/login", h.POST(h.loginUser))
 	mux.HandleFunc("/api/v1/users/password/change", h.POST(h.jwtAuth(h.changePassword)))
+	mux.HandleFunc("/api/v1/users/password/reset/request", h.POST(h.requestPasswordReset))
+	mux.HandleFunc("/api/v1/users/password/reset/verify", h.POST(h.resetPassword))
 	mux.HandleFunc("/api/v1/users/update", h.POST(h.jwtAuth(h.updateUser)))
 	mux.HandleFunc("/api/v1/users/media/set", h.POST(h.jwtAuth(h.setUserImage)))
+	mux.HandleFunc("/api/v1/users/token/refresh", h.POST(h.refreshToken))
+	mux.HandleFunc("/api/v1/users/logout", h.POST(h.jwtAuth(h.logoutUser)))
+	mux.HandleFunc("/api/v1/users/sessions", h.GET(h.jwtAuth(h.getSessions)))
+	mux.HandleFunc("/api/v1/users/sessions/revoke", h.POST(h.jwtAuth(h.revokeSession)))
 }
 
 type loginResponse struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Phone       string  `json:"phone"`
-	ImageID     *string `json:"imageId"`
-	EventID     *string `json:"eventId"`
-	AccessToken string  `json:"accessToken"`
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Phone        string  `json:"phone"`
+	ImageID      *string `json:"imageId"`
+	EventID      *string `json:"eventId"`
+	AccessToken  string  `json:"accessToken"`
+	RefreshToken string  `json:"refreshToken"`
 }
 
 type createUserRequest struct {
@@ -43,6 +53,7 @@ type createUserRequest struct {
 	Phone           string `json:"phone"`
 	Password        string `json:"password"`
 	ConfirmPassword string `json:"confirmPassword"`
+	DeviceName      string `json:"deviceName"`
 }
 
 func (r createUserRequest) Validate() ([]string, error) {
@@ -93,6 +104,7 @@ func (h *Handler) createUser(writer http.ResponseWriter, request *http.Request)
 		Name:     reqBody.Name,
 		Phone:    reqBody.Phone,
 		Password: reqBody.Password,
+		Device:   getDeviceInfo(request, reqBody.DeviceName),
 	})
 
 	if err != nil {
@@ -104,8 +116,9 @@ func (h *Handler) createUser(writer http.ResponseWriter, request *http.Request)
 }
 
 type loginUserRequest struct {
-	Phone    string `json:"phone"`
-	Password string `json:"password"`
+	Phone      string `json:"phone"`
+	Password   string `json:"password"`
+	DeviceName string `json:"deviceName"`
 }
 
 func (r loginUserRequest) Validate() ([]string, error) {
@@ -147,6 +160,7 @@ func (h *Handler) loginUser(writer http.ResponseWriter, request *http.Request) {
 	response, err := h.services.Users.Login(request.Context(), service.LoginUserInput{
 		Phone:    reqBody.Phone,
 		Password: reqBody.Password,
+		Device:   getDeviceInfo(request, reqBody.DeviceName),
 	})
 
 	if err != nil {
@@ -200,8 +214,9 @@ func (h *Handler) changePassword(writer http.ResponseWriter, request *http.Reque
 	}
 
 	result, err := h.services.Users.ChangePassword(request.Context(), service.ChangePasswordInput{
-		ID:       getUserID(request),
-		Password: reqBody.Password,
+		ID:        getUserID(request),
+		SessionID: getSessionID(request),
+		Password:  reqBody.Password,
 	})
 
 	if err != nil {
@@ -214,6 +229,111 @@ func (h *Handler) changePassword(writer http.ResponseWriter, request *http.Reque
 	}))
 }
 
+type passwordResetRequest struct {
+	Phone string `json:"phone"`
+}
+
+func (r passwordResetRequest) Validate() ([]string, error) {
+	var validationErrors []string
+
+	if r.Phone == "" {
+		validationErrors = append(validationErrors, emptyPhoneError)
+	} else if matched, err := regexp.MatchString(phoneRegexp, r.Phone); err != nil {
+		return nil, err
+	} else if !matched {
+		validationErrors = append(validationErrors, invalidPhoneFormatError)
+	}
+
+	return validationErrors, nil
+}
+
+// RequestPasswordReset godoc
+// @Summary      Запросить код для восстановления пароля по смс
+// @Description  Ответ не зависит от того, зарегистрирован ли номер. На номер - не чаще раза в минуту и не больше 5 раз в час,
+// @Description  иначе OtpRequestsLimitExceeded. На код дается 5 попыток, дальше OtpAttemptsLimitExceeded
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body passwordResetRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/users/password/reset/request [post]
+func (h *Handler) requestPasswordReset(writer http.ResponseWriter, request *http.Request) {
+	reqBody := passwordResetRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Users.RequestPasswordReset(request.Context(), reqBody.Phone); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
+type resetPasswordRequest struct {
+	Phone           string `json:"phone"`
+	Code            string `json:"code"`
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirmPassword"`
+}
+
+func (r resetPasswordRequest) Validate() ([]string, error) {
+	validationErrors, err := passwordResetRequest{Phone: r.Phone}.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Code == "" {
+		validationErrors = append(validationErrors, emptyCodeError)
+	}
+
+	if r.Password == "" {
+		validationErrors = append(validationErrors, emptyPasswordError)
+	} else if len(r.Password) < requiredPasswordLength {
+		validationErrors = append(validationErrors, invalidPasswordLengthError)
+	}
+
+	if r.Password != r.ConfirmPassword {
+		validationErrors = append(validationErrors, invalidConfirmPasswordError)
+	}
+
+	return validationErrors, nil
+}
+
+// ResetPassword godoc
+// @Summary      Установить новый пароль по коду из смс (все сессии пользователя отзываются)
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body resetPasswordRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/users/password/reset/verify [post]
+func (h *Handler) resetPassword(writer http.ResponseWriter, request *http.Request) {
+	reqBody := resetPasswordRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	err := h.services.Users.ResetPassword(request.Context(), service.ResetPasswordInput{
+		Phone:    reqBody.Phone,
+		Code:     reqBody.Code,
+		Password: reqBody.Password,
+	})
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
 type updateUserRequest struct {
 	Name  string `json:"name"`
 	Phone string `json:"phone"`
@@ -251,9 +371,10 @@ func (h *Handler) updateUser(writer http.ResponseWriter, request *http.Request)
 	}
 
 	result, err := h.services.Users.Update(request.Context(), service.UpdateUserInput{
-		ID:    getUserID(request),
-		Name:  reqBody.Name,
-		Phone: reqBody.Phone,
+		ID:        getUserID(request),
+		SessionID: getSessionID(request),
+		Name:      reqBody.Name,
+		Phone:     reqBody.Phone,
 	})
 
 	if err != nil {
@@ -273,6 +394,10 @@ type setImageResponse struct {
 
 // SetUserImage godoc
 // @Summary      Установить пользователю картинку
+// @Description  Только картинка (jpeg, png, gif, webp, heic), тип определяется по содержимому файла.
+// @Description  Ошибки: UnsupportedMediaType - другой тип, FileTooLarge - больше MEDIA_MAX_IMAGE_SIZE_MB,
+// @Description  FileIsEmpty - пустой файл, FileIsMissing - нет файла в форме
+// @Description  Из картинок удаляются метаданные (EXIF с геолокацией и устройством), кроме ориентации, если не MEDIA_STRIP_METADATA=false
 // @Security     UserAuth
 // @Tags         users
 // @Accept       multipart/form-data
@@ -282,18 +407,20 @@ type setImageResponse struct {
 // @Success      200 {object} apiResponse{result=setImageResponse}
 // @Router       /v1/users/media/set [post]
 func (h *Handler) setUserImage(writer http.ResponseWriter, request *http.Request) {
-	data, header, isOk := h.parseFormFile(writer, request, "image")
+	file, header, isOk := h.parseFormFile(writer, request, "image", h.services.Media.MaxSize(service.ImageContentTypes))
 
 	if !isOk {
 		return
 	}
 
+	defer file.Close()
 	imageId, accessToken, err := h.services.Users.SetUserImage(request.Context(), service.SetUserImageInput{
 		UserID:      getUserID(request),
+		SessionID:   getSessionID(request),
 		FileName:    header.Filename,
 		ContentType: header.Header.Get("Content-Type"),
 		Size:        header.Size,
-		FileData:    data,
+		FileData:    file,
 	})
 
 	if err != nil {
@@ -306,3 +433,130 @@ func (h *Handler) setUserImage(writer http.ResponseWriter, request *http.Request
 		AccessToken: accessToken,
 	}))
 }
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (r refreshTokenRequest) Validate() ([]string, error) {
+	var validationErrors []string
+
+	if r.RefreshToken == "" {
+		validationErrors = append(validationErrors, emptyRefreshTokenError)
+	}
+
+	return validationErrors, nil
+}
+
+type tokenPairResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RefreshToken godoc
+// @Summary      Обновить access токен по refresh токену
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body refreshTokenRequest true "body"
+// @Success      200 {object} apiResponse{result=tokenPairResponse}
+// @Router       /v1/users/token/refresh [post]
+func (h *Handler) refreshToken(writer http.ResponseWriter, request *http.Request) {
+	reqBody := refreshTokenRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	result, err := h.services.Sessions.Refresh(request.Context(), reqBody.RefreshToken)
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(result))
+}
+
+// LogoutUser godoc
+// @Summary      Выйти из системы (отозвать текущую сессию)
+// @Security     UserAuth
+// @Tags         users
+// @Produce      json
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/users/logout [post]
+func (h *Handler) logoutUser(writer http.ResponseWriter, request *http.Request) {
+	if err := h.services.Sessions.Revoke(request.Context(), getUserID(request), getSessionID(request)); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
+
+type deviceResponse struct {
+	Name      string `json:"name"`
+	UserAgent string `json:"userAgent"`
+	IPAddress string `json:"ipAddress"`
+}
+
+type sessionResponse struct {
+	ID         string         `json:"id"`
+	Device     deviceResponse `json:"device"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	LastUsedAt time.Time      `json:"lastUsedAt"`
+	ExpiresAt  time.Time      `json:"expiresAt"`
+	IsCurrent  bool           `json:"isCurrent"`
+}
+
+// GetSessions godoc
+// @Summary      Получить активные сессии пользователя
+// @Security     UserAuth
+// @Tags         users
+// @Produce      json
+// @Success      200 {object} apiResponse{result=[]sessionResponse}
+// @Router       /v1/users/sessions [get]
+func (h *Handler) getSessions(writer http.ResponseWriter, request *http.Request) {
+	result, err := h.services.Sessions.GetUserSessions(request.Context(), getUserID(request), getSessionID(request))
+
+	if err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(result))
+}
+
+type sessionIDRequest struct {
+	SessionID string `json:"sessionId"`
+}
+
+func (r sessionIDRequest) Validate() ([]string, error) {
+	return validateId(r.SessionID)
+}
+
+// RevokeSession godoc
+// @Summary      Отозвать сессию пользователя
+// @Security     UserAuth
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Content-Type application/json
+// @param        request body sessionIDRequest true "body"
+// @Success      200 {object} apiResponse{result=string}
+// @Router       /v1/users/sessions/revoke [post]
+func (h *Handler) revokeSession(writer http.ResponseWriter, request *http.Request) {
+	reqBody := sessionIDRequest{}
+
+	if isOk := h.bindValidatedRequestJSON(writer, request, &reqBody); !isOk {
+		return
+	}
+
+	if err := h.services.Sessions.Revoke(request.Context(), getUserID(request), reqBody.SessionID); err != nil {
+		h.writeError(writer, err)
+		return
+	}
+
+	h.writeJSONResponse(writer, newSuccessResponse(nil))
+}
@@ -1,17 +1,21 @@
 package v1
 
 import (
+	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 )
 
-func TestUsers(t *testing.T) {
-	t.Run("create", TestCreateUser)
-	t.Run("login", TestLoginUser)
-	t.Run("update", TestUpdateUser)
+func testUsers(t *testing.T) {
+	t.Run("create", testCreateUser)
+	t.Run("login", testLoginUser)
+	t.Run("update", testUpdateUser)
+	t.Run("sessions", testSessions)
+	t.Run("password reset", testPasswordReset)
 }
 
-func TestCreateUser(t *testing.T) {
+func testCreateUser(t *testing.T) {
 	tests := []testData{
 		{
 			Name:                "empty phone",
@@ -152,7 +156,7 @@ func TestCreateUser(t *testing.T) {
 	}
 }
 
-func TestLoginUser(t *testing.T) {
+func testLoginUser(t *testing.T) {
 	tests := []testData{
 		{
 			Name:                "empty phone",
@@ -257,7 +261,7 @@ func TestLoginUser(t *testing.T) {
 	}
 }
 
-func TestUpdateUser(t *testing.T) {
+func testUpdateUser(t *testing.T) {
 	tests := []testData{
 		{
 			Name:                "unauthorized",
@@ -388,3 +392,297 @@ func TestUpdateUser(t *testing.T) {
 		})
 	}
 }
+
+func testSessions(t *testing.T) {
+	tests := []testData{
+		{
+			Name:                "refresh empty token",
+			Url:                 "/api/v1/users/token/refresh",
+			Method:              http.MethodPost,
+			Body:                `{}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"RefreshTokenIsEmpty"}],"result":null}`,
+			Handler:             testHandler.refreshToken,
+		},
+		{
+			Name:                "refresh invalid token",
+			Url:                 "/api/v1/users/token/refresh",
+			Method:              http.MethodPost,
+			Body:                `{"refreshToken":"invalid"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"InvalidRefreshToken"}],"result":null}`,
+			Handler:             testHandler.refreshToken,
+		},
+		{
+			Name:                "refresh success",
+			Url:                 "/api/v1/users/token/refresh",
+			Method:              http.MethodPost,
+			Body:                `{"refreshToken":"` + testLogoutRefreshToken + `"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			Handler:             testHandler.refreshToken,
+		},
+		{
+			Name:                "refresh rotated token",
+			Url:                 "/api/v1/users/token/refresh",
+			Method:              http.MethodPost,
+			Body:                `{"refreshToken":"` + testLogoutRefreshToken + `"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"InvalidRefreshToken"}],"result":null}`,
+			Handler:             testHandler.refreshToken,
+		},
+		{
+			Name:                "sessions unauthorized",
+			Url:                 "/api/v1/users/sessions",
+			Method:              http.MethodGet,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"unauthorized"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.getSessions),
+		},
+		{
+			Name:                "sessions success",
+			Url:                 "/api/v1/users/sessions",
+			Method:              http.MethodGet,
+			AuthHeader:          testUserAccessToken,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			Handler:             testHandler.jwtAuth(testHandler.getSessions),
+		},
+		{
+			Name:                "revoke invalid id",
+			Url:                 "/api/v1/users/sessions/revoke",
+			Method:              http.MethodPost,
+			Body:                `{"sessionId":"123"}`,
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"InvalidIdFormat"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.revokeSession),
+		},
+		{
+			Name:                "revoke not found",
+			Url:                 "/api/v1/users/sessions/revoke",
+			Method:              http.MethodPost,
+			Body:                `{"sessionId":"7a2f2c5e-3c1b-4b8e-9d6f-0e1a2b3c4d5e"}`,
+			AuthHeader:          testUserAccessToken,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"SessionNotFound"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.revokeSession),
+		},
+		{
+			Name:                "logout success",
+			Url:                 "/api/v1/users/logout",
+			Method:              http.MethodPost,
+			AuthHeader:          testLogoutAccessToken,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.logoutUser),
+		},
+		{
+			Name:                "revoked session unauthorized",
+			Url:                 "/api/v1/users/sessions",
+			Method:              http.MethodGet,
+			AuthHeader:          testLogoutAccessToken,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"unauthorized"}],"result":null}`,
+			Handler:             testHandler.jwtAuth(testHandler.getSessions),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			testHandlerMethod(test, t)
+		})
+	}
+}
+
+func testPasswordReset(t *testing.T) {
+	requestTests := []testData{
+		{
+			Name:                "request invalid phone format",
+			Url:                 "/api/v1/users/password/reset/request",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"123"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"PhoneRegexInvalid"}],"result":null}`,
+			Handler:             testHandler.requestPasswordReset,
+		},
+		{
+			Name:                "request unknown phone",
+			Url:                 "/api/v1/users/password/reset/request",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"9000000000"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.requestPasswordReset,
+		},
+		{
+			Name:                "request success",
+			Url:                 "/api/v1/users/password/reset/request",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"9090909090"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.requestPasswordReset,
+		},
+		{
+			Name:                "request too often",
+			Url:                 "/api/v1/users/password/reset/request",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"9090909090"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"OtpRequestsLimitExceeded"}],"result":null}`,
+			Handler:             testHandler.requestPasswordReset,
+		},
+		{
+			Name:                "request unknown phone too often",
+			Url:                 "/api/v1/users/password/reset/request",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"9000000000"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"OtpRequestsLimitExceeded"}],"result":null}`,
+			Handler:             testHandler.requestPasswordReset,
+		},
+	}
+
+	for _, test := range requestTests {
+		t.Run(test.Name, func(t *testing.T) {
+			testHandlerMethod(test, t)
+		})
+	}
+
+	code := readLastSMSCode(t)
+	verifyTests := []testData{
+		{
+			Name:                "verify empty code",
+			Url:                 "/api/v1/users/password/reset/verify",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"9090909090","password":"654321","confirmPassword":"654321"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"CodeIsEmpty"}],"result":null}`,
+			Handler:             testHandler.resetPassword,
+		},
+		{
+			Name:                "verify invalid code",
+			Url:                 "/api/v1/users/password/reset/verify",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"9090909090","code":"` + invertCode(code) + `","password":"654321","confirmPassword":"654321"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"OtpCodeInvalid"}],"result":null}`,
+			Handler:             testHandler.resetPassword,
+		},
+		{
+			Name:                "verify unknown phone",
+			Url:                 "/api/v1/users/password/reset/verify",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"9000000000","code":"` + code + `","password":"654321","confirmPassword":"654321"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"OtpCodeInvalid"}],"result":null}`,
+			Handler:             testHandler.resetPassword,
+		},
+		{
+			Name:                "verify success",
+			Url:                 "/api/v1/users/password/reset/verify",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"9090909090","code":"` + code + `","password":"654321","confirmPassword":"654321"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":true,"errors":null,"result":null}`,
+			Handler:             testHandler.resetPassword,
+		},
+		{
+			Name:                "verify used code",
+			Url:                 "/api/v1/users/password/reset/verify",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"9090909090","code":"` + code + `","password":"654321","confirmPassword":"654321"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			CheckBody:           true,
+			ExpectedBody:        `{"isSuccess":false,"errors":[{"errorCode":"OtpCodeInvalid"}],"result":null}`,
+			Handler:             testHandler.resetPassword,
+		},
+		{
+			Name:                "login with new password",
+			Url:                 "/api/v1/users/login",
+			Method:              http.MethodPost,
+			Body:                `{"phone":"9090909090","password":"654321"}`,
+			RequestContentType:  contentTypeJSON,
+			ResponseContentType: contentTypeJSON,
+			ExpectedStatusCode:  http.StatusOK,
+			Handler:             testHandler.loginUser,
+		},
+	}
+
+	for _, test := range verifyTests {
+		t.Run(test.Name, func(t *testing.T) {
+			testHandlerMethod(test, t)
+		})
+	}
+}
+
+func readLastSMSCode(t *testing.T) string {
+	data, err := ioutil.ReadFile(testSMSFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	message := lines[len(lines)-1]
+	return message[strings.LastIndex(message, " ")+1:]
+}
+
+func invertCode(code string) string {
+	inverted := []byte(code)
+	for i, c := range inverted {
+		inverted[i] = '9' - c + '0'
+	}
+	return string(inverted)
+}
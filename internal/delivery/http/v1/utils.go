@@ -2,6 +2,7 @@ package v1
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/storage"
 )
 
 const (
@@ -16,9 +18,13 @@ const (
 	contentTypeFORM = "multipart/form-data"
 	idRegexp        = `^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$`
 
+	// files over maxFormMemory are kept in temporary files while the form is parsed
+	maxFormMemory = 1 << 20
+
 	internalError        = "InternalError"
 	emptyIDError         = "IdIsEmpty"
 	invalidIdFormatError = "InvalidIdFormat"
+	missingFileError     = "FileIsMissing"
 )
 
 type validatedRequest interface {
@@ -97,47 +103,46 @@ func (h *Handler) writeError(writer http.ResponseWriter, err error) {
 	h.writeJSONResponse(writer, newErrorResponse(err.Error()))
 }
 
-func (h *Handler) parseFormFile(writer http.ResponseWriter, request *http.Request, filename string) ([]byte, *multipart.FileHeader, bool) {
+// parseFormFile reads the form up to maxSize of the file plus maxFormMemory for the other fields, the caller closes the file.
+func (h *Handler) parseFormFile(writer http.ResponseWriter, request *http.Request, filename string, maxSize int64) (multipart.File, *multipart.FileHeader, bool) {
 	if !strings.Contains(request.Header.Get("Content-Type"), contentTypeFORM) {
 		h.writeJSONResponse(writer, newErrorResponse("invalid Content-Type"))
 		return nil, nil, false
 	}
 
-	if err := request.ParseForm(); err != nil {
-		h.logger.LogError(err)
-		h.writeJSONResponse(writer, newErrorResponse(internalError))
-		return nil, nil, false
-	}
+	request.Body = ioutil.NopCloser(storage.LimitReader(request.Body, maxSize+maxFormMemory))
 
-	file, header, err := request.FormFile(filename)
+	if err := request.ParseMultipartForm(maxFormMemory); err != nil {
+		if errors.Is(err, domain.ErrFileTooLarge) {
+			h.writeError(writer, domain.ErrFileTooLarge)
+			return nil, nil, false
+		}
 
-	if err != nil {
 		h.logger.LogError(err)
 		h.writeJSONResponse(writer, newErrorResponse(internalError))
 		return nil, nil, false
 	}
 
-	data := make([]byte, header.Size)
+	file, header, err := request.FormFile(filename)
 
-	if _, err = file.Read(data); err != nil {
-		h.logger.LogError(err)
-		h.writeJSONResponse(writer, newErrorResponse(internalError))
+	if err == http.ErrMissingFile {
+		h.writeJSONResponse(writer, newErrorResponse(missingFileError))
 		return nil, nil, false
 	}
 
-	if err = file.Close(); err != nil {
+	if err != nil {
 		h.logger.LogError(err)
 		h.writeJSONResponse(writer, newErrorResponse(internalError))
 		return nil, nil, false
 	}
 
-	if err = request.Body.Close(); err != nil {
-		h.logger.LogError(err)
-		h.writeJSONResponse(writer, newErrorResponse(internalError))
+	if header.Size > maxSize {
+		_ = file.Close()
+		h.writeError(writer, domain.ErrFileTooLarge)
 		return nil, nil, false
 	}
 
-	return data, header, true
+	return file, header, true
 }
 
 func validateId(id string) ([]string, error) {
@@ -154,6 +159,18 @@ func validateId(id string) ([]string, error) {
 	return validationErrors, nil
 }
 
+func validateCoordinates(value *coordinates) []string {
+	if value == nil || value.X == nil || value.Y == nil {
+		return []string{emptyCoordinatesError}
+	}
+
+	if !(domain.Coordinates{X: *value.X, Y: *value.Y}).IsValid() {
+		return []string{invalidCoordinatesError}
+	}
+
+	return nil
+}
+
 func containsError(err errorResponse, errs []errorResponse) bool {
 	for _, item := range errs {
 		if item.ErrorCode == err.ErrorCode {
@@ -0,0 +1,281 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+)
+
+const idRegexp = `^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$`
+
+type UserData struct {
+	EventID     string
+	UserID      string
+	UserName    string
+	UserImageID string
+}
+
+// Reply is an answer to a client message, Close disconnects the client once it's written.
+type Reply struct {
+	Data  []byte
+	Close bool
+}
+
+var websocketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+type Upgrader struct {
+	logger       logger.Logger
+	tokenManager auth.TokenManager
+	sessions     service.Sessions
+}
+
+func NewUpgrader(logger logger.Logger, tokenManager auth.TokenManager, sessions service.Sessions) *Upgrader {
+	return &Upgrader{
+		logger:       logger,
+		tokenManager: tokenManager,
+		sessions:     sessions,
+	}
+}
+
+// Upgrade authenticates the user and lets authorize reject the connection before the upgrade.
+func (u *Upgrader) Upgrade(writer http.ResponseWriter, request *http.Request,
+	authorize func(ctx context.Context, userId string) error) (UserData, *websocket.Conn, error) {
+	paramToken := request.URL.Query().Get("accessToken")
+
+	if paramToken == "" {
+		writer.WriteHeader(http.StatusUnauthorized)
+		return UserData{}, nil, errors.New("empty accessToken")
+	}
+
+	token, err := u.tokenManager.ParseToken(paramToken)
+
+	if err != nil {
+		if err == auth.ErrInvalidToken {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return UserData{}, nil, err
+		}
+
+		u.logger.LogError(err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return UserData{}, nil, err
+	}
+
+	isValid, err := ValidateId(token.ID)
+
+	if err != nil {
+		u.logger.LogError(err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return UserData{}, nil, err
+	}
+
+	if !isValid {
+		writer.WriteHeader(http.StatusBadRequest)
+		return UserData{}, nil, errors.New("invalid id")
+	}
+
+	if err = u.sessions.Check(request.Context(), token.ID, token.SessionID); err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return UserData{}, nil, err
+		}
+
+		u.logger.LogError(err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return UserData{}, nil, err
+	}
+
+	if err = authorize(request.Context(), token.ID); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrEventAccessDenied), errors.Is(err, domain.ErrInvalidInviteToken), errors.Is(err, domain.ErrUserBanned):
+			writer.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, domain.ErrEventNotFound):
+			writer.WriteHeader(http.StatusNotFound)
+		case domain.IsInternalError(err):
+			u.logger.LogError(err)
+			writer.WriteHeader(http.StatusInternalServerError)
+		default:
+			writer.WriteHeader(http.StatusBadRequest)
+		}
+		return UserData{}, nil, err
+	}
+
+	conn, err := websocketUpgrader.Upgrade(writer, request, nil)
+
+	if err != nil {
+		u.logger.LogError(err)
+		return UserData{}, nil, err
+	}
+
+	return UserData{
+		UserID:      token.ID,
+		UserName:    token.Name,
+		UserImageID: token.ImageID,
+	}, conn, nil
+}
+
+type ReadContext struct {
+	Context    context.Context
+	Conn       *websocket.Conn
+	Subscriber service.Subscriber
+	UserData   UserData
+	Logger     logger.Logger
+	Replies    chan<- Reply
+	Done       <-chan struct{}
+}
+
+// Reply passes the answer to the writing goroutine, false means the connection is already closed.
+func (c *ReadContext) Reply(data []byte, close bool) bool {
+	select {
+	case c.Replies <- Reply{Data: data, Close: close}:
+		return true
+	case <-c.Done:
+		return false
+	}
+}
+
+// ReadMessages passes the text messages to messageHandler and its answers to the client,
+// closeHandler is called once the connection is closed.
+func ReadMessages(context *ReadContext,
+	messageHandler func(ctx context.Context, userData UserData, body []byte) []byte,
+	closeHandler func(ctx context.Context, userData UserData, subscriber service.Subscriber)) {
+	defer func() {
+		if r := recover(); r != nil {
+			handlePanic(r, context.Logger, context.Conn)
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				handlePanic(r, context.Logger, context.Conn)
+			}
+		}()
+		closeHandler(context.Context, context.UserData, context.Subscriber)
+	}()
+	for {
+		messageType, data, err := context.Conn.ReadMessage()
+
+		if err != nil {
+			return
+		}
+
+		switch messageType {
+		case websocket.TextMessage:
+			if response := messageHandler(context.Context, context.UserData, data); response != nil {
+				if !context.Reply(response, false) {
+					return
+				}
+			}
+		default:
+			_ = context.Conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseUnsupportedData, "closing connection"), time.Now().Add(time.Second))
+			return
+		}
+	}
+}
+
+// WriteMessages owns the writes to the connection: published messages, replies and pings.
+// It closes the connection and done when the subscriber is closed or a write fails.
+func WriteMessages(pingPeriod time.Duration, conn *websocket.Conn, ch <-chan []byte, closed <-chan struct{}, replies <-chan Reply, done chan<- struct{}) {
+	ticker := time.NewTicker(pingPeriod * 9 / 10)
+	defer func() {
+		ticker.Stop()
+		close(done)
+		conn.Close()
+	}()
+
+	err := conn.SetReadDeadline(time.Now().Add(pingPeriod))
+
+	if err != nil {
+		return
+	}
+
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pingPeriod))
+	})
+
+	for {
+		select {
+		case <-ticker.C:
+			if err = conn.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case message := <-ch:
+			if err = conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case response := <-replies:
+			if err = conn.WriteMessage(websocket.TextMessage, response.Data); err != nil {
+				return
+			}
+			if response.Close {
+				return
+			}
+		}
+	}
+}
+
+func handlePanic(r any, logger logger.Logger, conn *websocket.Conn) {
+	var err error
+
+	switch t := r.(type) {
+	case error:
+		err = t
+		break
+	case string:
+		err = errors.New(t)
+		break
+	default:
+		err = errors.New("unknown error")
+		break
+	}
+
+	if err != nil {
+		logger.LogError(err)
+	}
+
+	conn.Close()
+}
+
+// ParseLastSeq reads the optional lastSeq query parameter of a reconnecting client.
+func ParseLastSeq(request *http.Request) (uint64, bool, error) {
+	if !request.URL.Query().Has("lastSeq") {
+		return 0, false, nil
+	}
+
+	lastSeq, err := strconv.ParseUint(request.URL.Query().Get("lastSeq"), 10, 64)
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return lastSeq, true, nil
+}
+
+func ValidateId(id string) (bool, error) {
+	if id == "" {
+		return false, nil
+	}
+
+	if matched, err := regexp.MatchString(idRegexp, id); err != nil {
+		return false, err
+	} else if !matched {
+		return false, nil
+	}
+
+	return true, nil
+}
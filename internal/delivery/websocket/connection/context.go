@@ -1,4 +1,4 @@
-package v1
+package connection
 
 import "context"
 
@@ -7,7 +7,8 @@ type socketContext struct {
 	ch <-chan struct{}
 }
 
-func newSocketContext(ctx context.Context) context.Context {
+// NewContext keeps the values of the request context but is never canceled, the connection outlives the request.
+func NewContext(ctx context.Context) context.Context {
 	return socketContext{
 		Context: ctx,
 	}
@@ -5,12 +5,15 @@ import (
 	"time"
 
 	v1 "github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/v1"
+	v2 "github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/v2"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
 )
 
-func InitWebsocketsRoutes(mux *http.ServeMux, logger logger.Logger, tokenManager auth.TokenManager, events service.Events, publisher service.Publisher) {
-	v1Handler := v1.NewHandler(time.Second*120, logger, tokenManager, events, publisher)
+func InitWebsocketsRoutes(mux *http.ServeMux, logger logger.Logger, tokenManager auth.TokenManager, events service.Events, presence service.Presence, sessions service.Sessions, publisher service.Publisher) {
+	v1Handler := v1.NewHandler(time.Second*120, logger, tokenManager, events, presence, sessions, publisher)
 	v1Handler.InitRoutes(mux)
+	v2Handler := v2.NewHandler(time.Second*120, logger, tokenManager, events, presence, sessions, publisher)
+	v2Handler.InitRoutes(mux)
 }
@@ -0,0 +1,330 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+const (
+	TypeEventUpdated = "eventUpdated"
+	TypeChatMessage  = "chatMessage"
+	TypeMediaAdded   = "mediaAdded"
+	TypeMediaRemoved = "mediaRemoved"
+	TypeCloseEvent   = "closeEvent"
+	TypeResync       = "resync"
+	TypeRoleChanged  = "roleChanged"
+	TypeAck          = "ack"
+	TypeError        = "error"
+
+	TypeChatMessageDeleted = "chatMessageDeleted"
+	TypeUserMuted          = "userMuted"
+	TypeUserKicked         = "userKicked"
+	TypeUserBanned         = "userBanned"
+
+	TypeChatMessageUpdated = "chatMessageUpdated"
+	TypeUserJoined         = "userJoined"
+	TypeUserLeft           = "userLeft"
+	TypeUserTyping         = "userTyping"
+
+	TypePresence = "presence"
+	TypeTyping   = "typing"
+
+	TypeSubscribe           = "subscribe"
+	TypeUnsubscribe         = "unsubscribe"
+	TypeJoinRequestApproved = "joinRequestApproved"
+	TypeJoinRequestRejected = "joinRequestRejected"
+	TypeChatReply           = "chatReply"
+
+	TypeSendChatMessage   = "sendChatMessage"
+	TypeEditChatMessage   = "editChatMessage"
+	TypeReactChatMessage  = "reactChatMessage"
+	TypeDeleteChatMessage = "deleteChatMessage"
+	TypeMuteUser          = "muteUser"
+	TypeKickUser          = "kickUser"
+	TypeBanUser           = "banUser"
+)
+
+const (
+	ErrorCodeInternal             = "InternalError"
+	ErrorCodeInvalidMessageFormat = "InvalidMessageFormat"
+	ErrorCodeUnknownMessageType   = "UnknownMessageType"
+	ErrorCodeEmptyMessage         = "MessageIsEmpty"
+	ErrorCodeInvalidID            = "InvalidIdFormat"
+	ErrorCodeInvalidMuteMinutes   = "MinutesInvalid"
+	ErrorCodeTooManyAttachments   = "AttachmentsLimitExceeded"
+	ErrorCodeInvalidEmoji         = "EmojiInvalid"
+	ErrorCodeTooManySubscriptions = "SubscriptionsLimitExceeded"
+)
+
+const (
+	MaxMuteMinutes     = 7 * 24 * 60
+	MaxChatAttachments = 10
+	MaxSubscriptions   = 50
+	maxEmojiLength     = 32
+)
+
+var ErrInvalidMessageFormat = errors.New(ErrorCodeInvalidMessageFormat)
+
+const (
+	v1Separator    = '/'
+	v1SeqSeparator = ':'
+)
+
+// Message is a protocol independent representation of a websocket message.
+// Publisher transports it in the v1 wire format, each endpoint re-encodes it for its own clients.
+// Seq is the per-event sequence number of a published message, EventID is set for the user websocket.
+type Message struct {
+	EventID string
+	Seq     uint64
+	Type    string
+	Payload []byte
+}
+
+func DecodeV1(data []byte) (Message, error) {
+	index := bytes.IndexByte(data, v1Separator)
+
+	if index < 0 {
+		return Message{}, ErrInvalidMessageFormat
+	}
+
+	return Message{
+		Type:    string(data[:index]),
+		Payload: data[index+1:],
+	}, nil
+}
+
+func EncodeV1(message Message) []byte {
+	result := make([]byte, 0, len(message.Type)+len(message.Payload)+1)
+	result = append(result, message.Type...)
+	result = append(result, v1Separator)
+	return append(result, message.Payload...)
+}
+
+// EncodeSequencedV1 prefixes a published v1 message with its sequence number, like 42:chatMessage/{...}.
+func EncodeSequencedV1(seq uint64, data []byte) []byte {
+	result := strconv.AppendUint(make([]byte, 0, len(data)+21), seq, 10)
+	result = append(result, v1SeqSeparator)
+	return append(result, data...)
+}
+
+type Envelope struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id"`
+	EventID string          `json:"eventId,omitempty"`
+	Seq     uint64          `json:"seq,omitempty"`
+	Ts      int64           `json:"ts"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type AckPayload struct {
+	MessageID string `json:"messageId"`
+}
+
+type Error struct {
+	ErrorCode string `json:"errorCode"`
+}
+
+type ErrorPayload struct {
+	MessageID string  `json:"messageId"`
+	Errors    []Error `json:"errors"`
+}
+
+type ChatMessagePayload struct {
+	Message     string   `json:"message"`
+	ReplyToID   string   `json:"replyToId"`
+	Attachments []string `json:"attachments"`
+}
+
+// Validate returns the error code of an invalid payload or an empty string.
+func (p ChatMessagePayload) Validate() string {
+	if len(p.Attachments) > MaxChatAttachments {
+		return ErrorCodeTooManyAttachments
+	}
+
+	if p.Message == "" && len(p.Attachments) == 0 {
+		return ErrorCodeEmptyMessage
+	}
+
+	return ""
+}
+
+type EditChatMessagePayload struct {
+	MessageID string `json:"messageId"`
+	Message   string `json:"message"`
+}
+
+func (p EditChatMessagePayload) Validate() string {
+	if p.MessageID == "" {
+		return ErrorCodeInvalidID
+	}
+
+	if p.Message == "" {
+		return ErrorCodeEmptyMessage
+	}
+
+	return ""
+}
+
+type ChatReactionPayload struct {
+	MessageID string `json:"messageId"`
+	Emoji     string `json:"emoji"`
+}
+
+func (p ChatReactionPayload) Validate() string {
+	if p.MessageID == "" {
+		return ErrorCodeInvalidID
+	}
+
+	if p.Emoji == "" || len(p.Emoji) > maxEmojiLength {
+		return ErrorCodeInvalidEmoji
+	}
+
+	return ""
+}
+
+type DeleteChatMessagePayload struct {
+	MessageID string `json:"messageId"`
+}
+
+type MuteUserPayload struct {
+	UserID  string `json:"userId"`
+	Minutes int    `json:"minutes"`
+}
+
+// SubscribePayload adds an event to the user websocket, with Join the user also joins the event like on the event websocket.
+type SubscribePayload struct {
+	EventID     string  `json:"eventId"`
+	InviteToken string  `json:"inviteToken"`
+	Join        bool    `json:"join"`
+	LastSeq     *uint64 `json:"lastSeq"`
+}
+
+type UnsubscribePayload struct {
+	EventID string `json:"eventId"`
+}
+
+type UserPayload struct {
+	UserID string `json:"userId"`
+}
+
+// RemovesUser reports whether the published message disconnects the user from the event.
+func RemovesUser(message Message, userId string) bool {
+	if message.Type != TypeUserKicked && message.Type != TypeUserBanned {
+		return false
+	}
+
+	payload := UserPayload{}
+
+	if err := json.Unmarshal(message.Payload, &payload); err != nil {
+		return false
+	}
+
+	return payload.UserID == userId
+}
+
+type PresencePayload struct {
+	MessageID string                `json:"messageId"`
+	Users     []domain.PresenceUser `json:"users"`
+}
+
+func NewPresenceV1(users []domain.PresenceUser) ([]byte, error) {
+	payload, err := json.Marshal(users)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return EncodeV1(Message{Type: TypePresence, Payload: payload}), nil
+}
+
+func NewPresence(messageId string, users []domain.PresenceUser) ([]byte, error) {
+	payload, err := json.Marshal(PresencePayload{MessageID: messageId, Users: users})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(newEnvelope(TypePresence, payload))
+}
+
+func DecodeV2(data []byte) (Envelope, error) {
+	envelope := Envelope{}
+
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return Envelope{}, ErrInvalidMessageFormat
+	}
+
+	if envelope.Type == "" {
+		return Envelope{}, ErrInvalidMessageFormat
+	}
+
+	return envelope, nil
+}
+
+// EncodePayload converts a message payload to JSON. Payloads that are not valid JSON,
+// like the media id of mediaRemoved, are encoded as JSON strings, empty payloads as null.
+func EncodePayload(message Message) (json.RawMessage, error) {
+	switch {
+	case len(message.Payload) == 0:
+		return json.RawMessage("null"), nil
+	case json.Valid(message.Payload):
+		return message.Payload, nil
+	default:
+		return json.Marshal(string(message.Payload))
+	}
+}
+
+// EncodeV2 wraps a message into an envelope with the payload converted by EncodePayload.
+func EncodeV2(message Message) ([]byte, error) {
+	payload, err := EncodePayload(message)
+
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := newEnvelope(message.Type, payload)
+	envelope.EventID = message.EventID
+	envelope.Seq = message.Seq
+	return json.Marshal(envelope)
+}
+
+func NewAck(messageId string) ([]byte, error) {
+	payload, err := json.Marshal(AckPayload{MessageID: messageId})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(newEnvelope(TypeAck, payload))
+}
+
+func NewError(messageId string, errorCodes ...string) ([]byte, error) {
+	errs := make([]Error, len(errorCodes))
+
+	for i, code := range errorCodes {
+		errs[i] = Error{ErrorCode: code}
+	}
+
+	payload, err := json.Marshal(ErrorPayload{MessageID: messageId, Errors: errs})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(newEnvelope(TypeError, payload))
+}
+
+func newEnvelope(messageType string, payload json.RawMessage) Envelope {
+	return Envelope{
+		Type:    messageType,
+		ID:      uuid.New().String(),
+		Ts:      time.Now().UnixMilli(),
+		Payload: payload,
+	}
+}
@@ -0,0 +1,139 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeV1(t *testing.T) {
+	message, err := DecodeV1([]byte(`chatMessage/hello/world`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message.Type != TypeChatMessage || string(message.Payload) != "hello/world" {
+		t.Fatalf("Incorrect message: %s %s", message.Type, message.Payload)
+	}
+
+	if string(EncodeV1(message)) != "chatMessage/hello/world" {
+		t.Fatalf("Incorrect encoded message: %s", EncodeV1(message))
+	}
+
+	if _, err = DecodeV1([]byte("closeEvent")); err != ErrInvalidMessageFormat {
+		t.Fatalf("Expected error: %v\nActual error: %v", ErrInvalidMessageFormat, err)
+	}
+}
+
+func TestEncodeV2(t *testing.T) {
+	tests := []struct {
+		Name            string
+		Message         Message
+		ExpectedPayload string
+	}{
+		{Name: "json payload", Message: Message{Type: TypeMediaAdded, Payload: []byte(`{"id":"1","contentType":"image/png"}`)}, ExpectedPayload: `{"id":"1","contentType":"image/png"}`},
+		{Name: "string payload", Message: Message{Type: TypeMediaRemoved, Payload: []byte(`media-id`)}, ExpectedPayload: `"media-id"`},
+		{Name: "empty payload", Message: Message{Type: TypeCloseEvent}, ExpectedPayload: `null`},
+		{Name: "sequenced", Message: Message{Seq: 42, Type: TypeResync}, ExpectedPayload: `null`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			data, err := EncodeV2(test.Message)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			envelope, err := DecodeV2(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if envelope.Type != test.Message.Type || envelope.ID == "" || envelope.Ts == 0 || envelope.Seq != test.Message.Seq || string(envelope.Payload) != test.ExpectedPayload {
+				t.Fatalf("Incorrect envelope: %s", data)
+			}
+		})
+	}
+}
+
+func TestEncodeSequencedV1(t *testing.T) {
+	data := EncodeSequencedV1(42, []byte(`chatMessage/{"message":"lol"}`))
+
+	if string(data) != `42:chatMessage/{"message":"lol"}` {
+		t.Fatalf("Incorrect message: %s", data)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	data, err := NewError("client-id", ErrorCodeEmptyMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envelope, err := DecodeV2(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := ErrorPayload{}
+	if err = json.Unmarshal(envelope.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if envelope.Type != TypeError || payload.MessageID != "client-id" || len(payload.Errors) != 1 || payload.Errors[0].ErrorCode != ErrorCodeEmptyMessage {
+		t.Fatalf("Incorrect error envelope: %s", data)
+	}
+
+	if _, err = DecodeV2([]byte(`{"id":"1"}`)); err != ErrInvalidMessageFormat {
+		t.Fatalf("Expected error: %v\nActual error: %v", ErrInvalidMessageFormat, err)
+	}
+}
+
+func TestRemovesUser(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Message  Message
+		Expected bool
+	}{
+		{Name: "kicked", Message: Message{Type: TypeUserKicked, Payload: []byte(`{"userId":"user"}`)}, Expected: true},
+		{Name: "banned", Message: Message{Type: TypeUserBanned, Payload: []byte(`{"userId":"user"}`)}, Expected: true},
+		{Name: "other user", Message: Message{Type: TypeUserBanned, Payload: []byte(`{"userId":"other"}`)}, Expected: false},
+		{Name: "muted", Message: Message{Type: TypeUserMuted, Payload: []byte(`{"userId":"user"}`)}, Expected: false},
+		{Name: "invalid payload", Message: Message{Type: TypeUserKicked, Payload: []byte(`user`)}, Expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if actual := RemovesUser(test.Message, "user"); actual != test.Expected {
+				t.Fatalf("Incorrect result\nExpected: %v\nActual: %v", test.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateChatPayloads(t *testing.T) {
+	attachments := make([]string, MaxChatAttachments+1)
+	tests := []struct {
+		Name     string
+		Payload  interface{ Validate() string }
+		Expected string
+	}{
+		{Name: "message", Payload: ChatMessagePayload{Message: "hello"}, Expected: ""},
+		{Name: "attachments only", Payload: ChatMessagePayload{Attachments: []string{"media-id"}}, Expected: ""},
+		{Name: "empty message", Payload: ChatMessagePayload{ReplyToID: "message-id"}, Expected: ErrorCodeEmptyMessage},
+		{Name: "too many attachments", Payload: ChatMessagePayload{Message: "hello", Attachments: attachments}, Expected: ErrorCodeTooManyAttachments},
+		{Name: "edit", Payload: EditChatMessagePayload{MessageID: "message-id", Message: "hello"}, Expected: ""},
+		{Name: "empty edit", Payload: EditChatMessagePayload{MessageID: "message-id"}, Expected: ErrorCodeEmptyMessage},
+		{Name: "edit without id", Payload: EditChatMessagePayload{Message: "hello"}, Expected: ErrorCodeInvalidID},
+		{Name: "reaction", Payload: ChatReactionPayload{MessageID: "message-id", Emoji: "🔥"}, Expected: ""},
+		{Name: "empty emoji", Payload: ChatReactionPayload{MessageID: "message-id"}, Expected: ErrorCodeInvalidEmoji},
+		{Name: "long emoji", Payload: ChatReactionPayload{MessageID: "message-id", Emoji: string(make([]byte, maxEmojiLength+1))}, Expected: ErrorCodeInvalidEmoji},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if actual := test.Payload.Validate(); actual != test.Expected {
+				t.Fatalf("Incorrect error code\nExpected: %s\nActual: %s", test.Expected, actual)
+			}
+		})
+	}
+}
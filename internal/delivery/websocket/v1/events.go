@@ -2,16 +2,19 @@ package v1
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
-	"strings"
+	"time"
 
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/connection"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/protocol"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
 )
 
 func (h *Handler) upgradeEventConnection(writer http.ResponseWriter, request *http.Request) {
 	eventId := request.URL.Query().Get("eventId")
-	isValid, err := validateId(eventId)
+	isValid, err := connection.ValidateId(eventId)
 
 	if err != nil {
 		h.logger.LogError(err)
@@ -24,7 +27,21 @@ func (h *Handler) upgradeEventConnection(writer http.ResponseWriter, request *ht
 		return
 	}
 
-	userInfo, conn, err := h.upgradeConnection(writer, request)
+	lastSeq, sequenced, err := connection.ParseLastSeq(request)
+
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	inviteToken := request.URL.Query().Get("inviteToken")
+	userInfo, conn, err := h.upgrader.Upgrade(writer, request, func(ctx context.Context, userId string) error {
+		return h.events.CheckAccess(ctx, service.EventAccessInput{
+			EventID:     eventId,
+			UserID:      userId,
+			InviteToken: inviteToken,
+		})
+	})
 
 	if err != nil {
 		return
@@ -32,19 +49,36 @@ func (h *Handler) upgradeEventConnection(writer http.ResponseWriter, request *ht
 
 	userInfo.EventID = eventId
 	ch := make(chan []byte)
-	sub := newSubscriber(ch)
-	h.publisher.Subscribe(eventId, sub)
-	websocketContext := &readContext{context: newSocketContext(request.Context()), conn: conn, subscriber: sub, userData: userInfo, logger: h.logger}
+	done := make(chan struct{})
+	sub := newSubscriber(userInfo.UserID, sequenced, ch, done)
+
+	if sequenced {
+		h.publisher.Resume(eventId, lastSeq, sub)
+	} else {
+		h.publisher.Subscribe(eventId, sub)
+	}
+
+	replies := make(chan connection.Reply)
+	websocketContext := &connection.ReadContext{
+		Context:    connection.NewContext(request.Context()),
+		Conn:       conn,
+		Subscriber: sub,
+		UserData:   userInfo,
+		Logger:     h.logger,
+		Replies:    replies,
+		Done:       done,
+	}
 
-	go readMessages(websocketContext, h.eventHandler, h.eventCloseHandler)
-	go writeMessages(h.pingPeriod, conn, ch)
+	go connection.WriteMessages(h.pingPeriod, conn, ch, sub.closed, replies, done)
 
-	err = h.events.AddUserInfo(request.Context(), service.AddUserInfoInput{
-		EventID: eventId,
-		UserID:  userInfo.UserID,
+	err = h.presence.Join(request.Context(), eventId, domain.PresenceUser{
+		UserID:      userInfo.UserID,
+		UserName:    userInfo.UserName,
+		UserImageID: userInfo.UserImageID,
 	})
 
 	if err != nil {
+		h.publisher.Unsubscribe(eventId, sub)
 		closeErr := conn.Close()
 		if closeErr != nil {
 			h.logger.LogError(closeErr)
@@ -57,42 +91,129 @@ func (h *Handler) upgradeEventConnection(writer http.ResponseWriter, request *ht
 		writer.WriteHeader(http.StatusBadRequest)
 		return
 	}
+
+	go connection.ReadMessages(websocketContext, h.eventHandler, h.eventCloseHandler)
 }
 
-func (h *Handler) eventHandler(ctx context.Context, userData userData, body []byte) {
-	prefix, message, isFound := strings.Cut(string(body), "/")
-	if !isFound {
-		return
+func (h *Handler) eventHandler(ctx context.Context, userData connection.UserData, body []byte) []byte {
+	message, err := protocol.DecodeV1(body)
+	if err != nil {
+		return nil
 	}
-	switch prefix {
-	case "chatMessage":
-		{
-			err := h.events.SendChatMessage(ctx, service.ChatMessageInput{
-				EventID:     userData.EventID,
-				UserID:      userData.UserID,
-				UserName:    userData.UserName,
-				UserImageID: userData.UserImageID,
-				Message:     message,
-			})
-
-			if err != nil {
-				if domain.IsInternalError(err) {
-					h.logger.LogError(err)
-				}
-			}
+	switch message.Type {
+	case protocol.TypeChatMessage:
+		h.logError(h.events.SendChatMessage(ctx, service.ChatMessageInput{
+			EventID:     userData.EventID,
+			UserID:      userData.UserID,
+			UserName:    userData.UserName,
+			UserImageID: userData.UserImageID,
+			Message:     string(message.Payload),
+		}))
+	case protocol.TypeSendChatMessage:
+		payload := protocol.ChatMessagePayload{}
+
+		if err = json.Unmarshal(message.Payload, &payload); err != nil || payload.Validate() != "" {
+			return nil
+		}
+
+		h.logError(h.events.SendChatMessage(ctx, service.ChatMessageInput{
+			EventID:       userData.EventID,
+			UserID:        userData.UserID,
+			UserName:      userData.UserName,
+			UserImageID:   userData.UserImageID,
+			Message:       payload.Message,
+			ReplyToID:     payload.ReplyToID,
+			AttachmentIDs: payload.Attachments,
+		}))
+	case protocol.TypeEditChatMessage:
+		payload := protocol.EditChatMessagePayload{}
+
+		if err = json.Unmarshal(message.Payload, &payload); err != nil || payload.Validate() != "" {
+			return nil
+		}
+
+		h.logError(h.events.EditChatMessage(ctx, service.EditChatMessageInput{
+			EventID:   userData.EventID,
+			UserID:    userData.UserID,
+			MessageID: payload.MessageID,
+			Message:   payload.Message,
+		}))
+	case protocol.TypeReactChatMessage:
+		payload := protocol.ChatReactionPayload{}
+
+		if err = json.Unmarshal(message.Payload, &payload); err != nil || payload.Validate() != "" {
+			return nil
+		}
+
+		h.logError(h.events.ToggleChatReaction(ctx, service.ChatReactionInput{
+			EventID:   userData.EventID,
+			UserID:    userData.UserID,
+			MessageID: payload.MessageID,
+			Emoji:     payload.Emoji,
+		}))
+	case protocol.TypeDeleteChatMessage:
+		h.logError(h.events.DeleteChatMessage(ctx, service.DeleteChatMessageInput{
+			EventID:   userData.EventID,
+			UserID:    userData.UserID,
+			MessageID: string(message.Payload),
+		}))
+	case protocol.TypeMuteUser:
+		payload := protocol.MuteUserPayload{}
+
+		if err = json.Unmarshal(message.Payload, &payload); err != nil || payload.Minutes <= 0 || payload.Minutes > protocol.MaxMuteMinutes {
+			return nil
+		}
+
+		h.logError(h.events.MuteUser(ctx, service.MuteUserInput{
+			EventID:      userData.EventID,
+			UserID:       userData.UserID,
+			TargetUserID: payload.UserID,
+			Duration:     time.Duration(payload.Minutes) * time.Minute,
+		}))
+	case protocol.TypeKickUser:
+		h.logError(h.events.KickUser(ctx, service.ModerateUserInput{
+			EventID:      userData.EventID,
+			UserID:       userData.UserID,
+			TargetUserID: string(message.Payload),
+		}))
+	case protocol.TypeBanUser:
+		h.logError(h.events.BanUser(ctx, service.ModerateUserInput{
+			EventID:      userData.EventID,
+			UserID:       userData.UserID,
+			TargetUserID: string(message.Payload),
+		}))
+	case protocol.TypePresence:
+		users, err := h.presence.GetUsers(userData.EventID)
+
+		if err != nil {
+			h.logger.LogError(err)
+			return nil
 		}
+
+		data, err := protocol.NewPresenceV1(users)
+
+		if err != nil {
+			h.logger.LogError(err)
+			return nil
+		}
+
+		return data
+	case protocol.TypeTyping:
+		h.logError(h.presence.Typing(userData.EventID, userData.UserID))
 	default:
 		h.logger.LogWarning("unknown route")
 	}
-}
 
-func (h *Handler) eventCloseHandler(ctx context.Context, userData userData, subscriber service.Subscriber) {
-	h.publisher.Unsubscribe(userData.EventID, subscriber)
-	err := h.events.RemoveUserInfo(ctx, userData.EventID, userData.UserID)
+	return nil
+}
 
-	if err != nil {
-		if domain.IsInternalError(err) {
-			h.logger.LogError(err)
-		}
+func (h *Handler) logError(err error) {
+	if err != nil && domain.IsInternalError(err) {
+		h.logger.LogError(err)
 	}
 }
+
+func (h *Handler) eventCloseHandler(ctx context.Context, userData connection.UserData, subscriber service.Subscriber) {
+	h.publisher.Unsubscribe(userData.EventID, subscriber)
+	h.logError(h.presence.Leave(userData.EventID, userData.UserID))
+}
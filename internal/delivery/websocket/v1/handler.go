@@ -4,30 +4,35 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/connection"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
 )
 
 type Handler struct {
-	pingPeriod   time.Duration
-	logger       logger.Logger
-	tokenManager auth.TokenManager
-	events       service.Events
-	publisher    service.Publisher
+	pingPeriod time.Duration
+	logger     logger.Logger
+	upgrader   *connection.Upgrader
+	events     service.Events
+	presence   service.Presence
+	publisher  service.Publisher
 }
 
 func NewHandler(pingPeriod time.Duration,
 	logger logger.Logger,
 	tokenManager auth.TokenManager,
 	events service.Events,
+	presence service.Presence,
+	sessions service.Sessions,
 	publisher service.Publisher) *Handler {
 	return &Handler{
-		pingPeriod:   pingPeriod,
-		logger:       logger,
-		tokenManager: tokenManager,
-		events:       events,
-		publisher:    publisher,
+		pingPeriod: pingPeriod,
+		logger:     logger,
+		upgrader:   connection.NewUpgrader(logger, tokenManager, sessions),
+		events:     events,
+		presence:   presence,
+		publisher:  publisher,
 	}
 }
 
@@ -1,23 +1,54 @@
 package v1
 
 import (
-	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
+	"sync"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/protocol"
 )
 
 type subscriber struct {
-	ch chan<- []byte
+	userId    string
+	sequenced bool
+	ch        chan<- []byte
+	done      <-chan struct{}
+	closed    chan struct{}
+	once      sync.Once
 }
 
-func newSubscriber(ch chan<- []byte) service.Subscriber {
+func newSubscriber(userId string, sequenced bool, ch chan<- []byte, done <-chan struct{}) *subscriber {
 	return &subscriber{
-		ch: ch,
+		userId:    userId,
+		sequenced: sequenced,
+		ch:        ch,
+		done:      done,
+		closed:    make(chan struct{}),
 	}
 }
 
-func (s *subscriber) OnReceive(message []byte) {
-	s.ch <- message
+func (s *subscriber) OnReceive(seq uint64, message []byte) {
+	data := message
+
+	// clients that reconnect with lastSeq get the sequence number of every message
+	if s.sequenced {
+		data = protocol.EncodeSequencedV1(seq, message)
+	}
+
+	select {
+	case s.ch <- data:
+	case <-s.closed:
+		return
+	case <-s.done:
+		return
+	}
+
+	// a kicked or banned user gets the notification and then is disconnected
+	if decoded, err := protocol.DecodeV1(message); err == nil && protocol.RemovesUser(decoded, s.userId) {
+		s.OnClose()
+	}
 }
 
 func (s *subscriber) OnClose() {
-	close(s.ch)
+	s.once.Do(func() {
+		close(s.closed)
+	})
 }
@@ -0,0 +1,302 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/connection"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/protocol"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
+)
+
+func (h *Handler) upgradeEventConnection(writer http.ResponseWriter, request *http.Request) {
+	eventId := request.URL.Query().Get("eventId")
+	isValid, err := connection.ValidateId(eventId)
+
+	if err != nil {
+		h.logger.LogError(err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !isValid {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	lastSeq, resume, err := connection.ParseLastSeq(request)
+
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	inviteToken := request.URL.Query().Get("inviteToken")
+	userInfo, conn, err := h.upgrader.Upgrade(writer, request, func(ctx context.Context, userId string) error {
+		return h.events.CheckAccess(ctx, service.EventAccessInput{
+			EventID:     eventId,
+			UserID:      userId,
+			InviteToken: inviteToken,
+		})
+	})
+
+	if err != nil {
+		return
+	}
+
+	userInfo.EventID = eventId
+	ch := make(chan []byte)
+	replies := make(chan connection.Reply)
+	done := make(chan struct{})
+	sub := newSubscriber(userInfo.UserID, "", h.logger, ch, done)
+
+	if resume {
+		h.publisher.Resume(eventId, lastSeq, sub)
+	} else {
+		h.publisher.Subscribe(eventId, sub)
+	}
+
+	websocketContext := &connection.ReadContext{
+		Context:    connection.NewContext(request.Context()),
+		Conn:       conn,
+		Subscriber: sub,
+		UserData:   userInfo,
+		Logger:     h.logger,
+		Replies:    replies,
+		Done:       done,
+	}
+
+	go connection.WriteMessages(h.pingPeriod, conn, ch, sub.closed, replies, done)
+
+	err = h.presence.Join(request.Context(), eventId, domain.PresenceUser{
+		UserID:      userInfo.UserID,
+		UserName:    userInfo.UserName,
+		UserImageID: userInfo.UserImageID,
+	})
+
+	if err != nil {
+		h.publisher.Unsubscribe(eventId, sub)
+		websocketContext.Reply(h.errorReply("", err), true)
+		return
+	}
+
+	go connection.ReadMessages(websocketContext, h.eventHandler, h.eventCloseHandler)
+}
+
+func (h *Handler) eventHandler(ctx context.Context, userData connection.UserData, body []byte) []byte {
+	envelope, err := protocol.DecodeV2(body)
+
+	if err != nil {
+		return h.errorReply("", err)
+	}
+
+	switch envelope.Type {
+	case protocol.TypeChatMessage:
+		payload := protocol.ChatMessagePayload{}
+
+		if err = json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return h.errorReply(envelope.ID, protocol.ErrInvalidMessageFormat)
+		}
+
+		if errorCode := payload.Validate(); errorCode != "" {
+			return h.newError(envelope.ID, errorCode)
+		}
+
+		err = h.events.SendChatMessage(ctx, service.ChatMessageInput{
+			EventID:       userData.EventID,
+			UserID:        userData.UserID,
+			UserName:      userData.UserName,
+			UserImageID:   userData.UserImageID,
+			Message:       payload.Message,
+			ReplyToID:     payload.ReplyToID,
+			AttachmentIDs: payload.Attachments,
+		})
+
+		if err != nil {
+			return h.errorReply(envelope.ID, err)
+		}
+
+		return h.newAck(envelope.ID)
+	case protocol.TypeEditChatMessage:
+		payload := protocol.EditChatMessagePayload{}
+
+		if err = json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return h.errorReply(envelope.ID, protocol.ErrInvalidMessageFormat)
+		}
+
+		if errorCode := payload.Validate(); errorCode != "" {
+			return h.newError(envelope.ID, errorCode)
+		}
+
+		err = h.events.EditChatMessage(ctx, service.EditChatMessageInput{
+			EventID:   userData.EventID,
+			UserID:    userData.UserID,
+			MessageID: payload.MessageID,
+			Message:   payload.Message,
+		})
+
+		return h.resultReply(envelope.ID, err)
+	case protocol.TypeReactChatMessage:
+		payload := protocol.ChatReactionPayload{}
+
+		if err = json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return h.errorReply(envelope.ID, protocol.ErrInvalidMessageFormat)
+		}
+
+		if errorCode := payload.Validate(); errorCode != "" {
+			return h.newError(envelope.ID, errorCode)
+		}
+
+		err = h.events.ToggleChatReaction(ctx, service.ChatReactionInput{
+			EventID:   userData.EventID,
+			UserID:    userData.UserID,
+			MessageID: payload.MessageID,
+			Emoji:     payload.Emoji,
+		})
+
+		return h.resultReply(envelope.ID, err)
+	case protocol.TypeDeleteChatMessage:
+		payload := protocol.DeleteChatMessagePayload{}
+
+		if err = json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return h.errorReply(envelope.ID, protocol.ErrInvalidMessageFormat)
+		}
+
+		if isValid, _ := connection.ValidateId(payload.MessageID); !isValid {
+			return h.newError(envelope.ID, protocol.ErrorCodeInvalidID)
+		}
+
+		err = h.events.DeleteChatMessage(ctx, service.DeleteChatMessageInput{
+			EventID:   userData.EventID,
+			UserID:    userData.UserID,
+			MessageID: payload.MessageID,
+		})
+
+		return h.resultReply(envelope.ID, err)
+	case protocol.TypeMuteUser:
+		payload := protocol.MuteUserPayload{}
+
+		if err = json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return h.errorReply(envelope.ID, protocol.ErrInvalidMessageFormat)
+		}
+
+		if isValid, _ := connection.ValidateId(payload.UserID); !isValid {
+			return h.newError(envelope.ID, protocol.ErrorCodeInvalidID)
+		}
+
+		if payload.Minutes <= 0 || payload.Minutes > protocol.MaxMuteMinutes {
+			return h.newError(envelope.ID, protocol.ErrorCodeInvalidMuteMinutes)
+		}
+
+		err = h.events.MuteUser(ctx, service.MuteUserInput{
+			EventID:      userData.EventID,
+			UserID:       userData.UserID,
+			TargetUserID: payload.UserID,
+			Duration:     time.Duration(payload.Minutes) * time.Minute,
+		})
+
+		return h.resultReply(envelope.ID, err)
+	case protocol.TypeKickUser, protocol.TypeBanUser:
+		payload := protocol.UserPayload{}
+
+		if err = json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return h.errorReply(envelope.ID, protocol.ErrInvalidMessageFormat)
+		}
+
+		if isValid, _ := connection.ValidateId(payload.UserID); !isValid {
+			return h.newError(envelope.ID, protocol.ErrorCodeInvalidID)
+		}
+
+		input := service.ModerateUserInput{
+			EventID:      userData.EventID,
+			UserID:       userData.UserID,
+			TargetUserID: payload.UserID,
+		}
+
+		if envelope.Type == protocol.TypeKickUser {
+			err = h.events.KickUser(ctx, input)
+		} else {
+			err = h.events.BanUser(ctx, input)
+		}
+
+		return h.resultReply(envelope.ID, err)
+	case protocol.TypePresence:
+		users, err := h.presence.GetUsers(userData.EventID)
+
+		if err != nil {
+			h.logger.LogError(err)
+			return h.newError(envelope.ID, protocol.ErrorCodeInternal)
+		}
+
+		data, err := protocol.NewPresence(envelope.ID, users)
+
+		if err != nil {
+			h.logger.LogError(err)
+			return h.newError(envelope.ID, protocol.ErrorCodeInternal)
+		}
+
+		return data
+	case protocol.TypeTyping:
+		return h.resultReply(envelope.ID, h.presence.Typing(userData.EventID, userData.UserID))
+	default:
+		return h.newError(envelope.ID, protocol.ErrorCodeUnknownMessageType)
+	}
+}
+
+func (h *Handler) eventCloseHandler(ctx context.Context, userData connection.UserData, subscriber service.Subscriber) {
+	h.publisher.Unsubscribe(userData.EventID, subscriber)
+
+	err := h.presence.Leave(userData.EventID, userData.UserID)
+
+	if err != nil {
+		if domain.IsInternalError(err) {
+			h.logger.LogError(err)
+		}
+	}
+}
+
+func (h *Handler) resultReply(messageId string, err error) []byte {
+	if err != nil {
+		return h.errorReply(messageId, err)
+	}
+
+	return h.newAck(messageId)
+}
+
+func (h *Handler) errorReply(messageId string, err error) []byte {
+	if err == protocol.ErrInvalidMessageFormat {
+		return h.newError(messageId, protocol.ErrorCodeInvalidMessageFormat)
+	}
+
+	if domain.IsInternalError(err) {
+		h.logger.LogError(err)
+		return h.newError(messageId, protocol.ErrorCodeInternal)
+	}
+
+	return h.newError(messageId, err.Error())
+}
+
+func (h *Handler) newError(messageId string, errorCodes ...string) []byte {
+	data, err := protocol.NewError(messageId, errorCodes...)
+
+	if err != nil {
+		h.logger.LogError(err)
+		return nil
+	}
+
+	return data
+}
+
+func (h *Handler) newAck(messageId string) []byte {
+	data, err := protocol.NewAck(messageId)
+
+	if err != nil {
+		h.logger.LogError(err)
+		return nil
+	}
+
+	return data
+}
@@ -0,0 +1,68 @@
+package v2
+
+import (
+	"sync"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/protocol"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+)
+
+// subscriber sends the publisher messages as v2 envelopes with their per-event sequence number,
+// eventId is only set for the events of the user websocket.
+type subscriber struct {
+	userId  string
+	eventId string
+	logger  logger.Logger
+	ch      chan<- []byte
+	done    <-chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newSubscriber(userId string, eventId string, logger logger.Logger, ch chan<- []byte, done <-chan struct{}) *subscriber {
+	return &subscriber{
+		userId:  userId,
+		eventId: eventId,
+		logger:  logger,
+		ch:      ch,
+		done:    done,
+		closed:  make(chan struct{}),
+	}
+}
+
+func (s *subscriber) OnReceive(seq uint64, message []byte) {
+	decoded, err := protocol.DecodeV1(message)
+
+	if err != nil {
+		s.logger.LogError(err)
+		return
+	}
+
+	decoded.EventID = s.eventId
+	decoded.Seq = seq
+	data, err := protocol.EncodeV2(decoded)
+
+	if err != nil {
+		s.logger.LogError(err)
+		return
+	}
+
+	select {
+	case s.ch <- data:
+	case <-s.closed:
+		return
+	case <-s.done:
+		return
+	}
+
+	// a kicked or banned user gets the notification and then is disconnected
+	if protocol.RemovesUser(decoded, s.userId) {
+		s.OnClose()
+	}
+}
+
+func (s *subscriber) OnClose() {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+}
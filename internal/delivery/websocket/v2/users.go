@@ -0,0 +1,217 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/connection"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/delivery/websocket/protocol"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/service"
+)
+
+type eventSubscription struct {
+	subscriber *subscriber
+	joined     bool
+}
+
+// userConnection multiplexes the user notifications and the events the user follows over one websocket.
+type userConnection struct {
+	handler *Handler
+	user    connection.UserData
+	ch      chan<- []byte
+	done    <-chan struct{}
+	mutex   sync.Mutex
+	events  map[string]*eventSubscription
+}
+
+func (h *Handler) upgradeUserConnection(writer http.ResponseWriter, request *http.Request) {
+	userInfo, conn, err := h.upgrader.Upgrade(writer, request, func(context.Context, string) error {
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	ch := make(chan []byte)
+	replies := make(chan connection.Reply)
+	done := make(chan struct{})
+	sub := newSubscriber(userInfo.UserID, "", h.logger, ch, done)
+	h.publisher.Subscribe(service.UserTopic(userInfo.UserID), sub)
+	userConn := &userConnection{
+		handler: h,
+		user:    userInfo,
+		ch:      ch,
+		done:    done,
+		events:  make(map[string]*eventSubscription),
+	}
+	websocketContext := &connection.ReadContext{
+		Context:    connection.NewContext(request.Context()),
+		Conn:       conn,
+		Subscriber: sub,
+		UserData:   userInfo,
+		Logger:     h.logger,
+		Replies:    replies,
+		Done:       done,
+	}
+
+	go connection.WriteMessages(h.pingPeriod, conn, ch, sub.closed, replies, done)
+	go connection.ReadMessages(websocketContext, userConn.handle, userConn.close)
+}
+
+func (c *userConnection) handle(ctx context.Context, _ connection.UserData, body []byte) []byte {
+	h := c.handler
+	envelope, err := protocol.DecodeV2(body)
+
+	if err != nil {
+		return h.errorReply("", err)
+	}
+
+	switch envelope.Type {
+	case protocol.TypeSubscribe:
+		payload := protocol.SubscribePayload{}
+
+		if err = json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return h.errorReply(envelope.ID, protocol.ErrInvalidMessageFormat)
+		}
+
+		if isValid, _ := connection.ValidateId(payload.EventID); !isValid {
+			return h.newError(envelope.ID, protocol.ErrorCodeInvalidID)
+		}
+
+		if c.subscribed(payload.EventID) {
+			return h.newAck(envelope.ID)
+		}
+
+		if c.count() >= protocol.MaxSubscriptions {
+			return h.newError(envelope.ID, protocol.ErrorCodeTooManySubscriptions)
+		}
+
+		return h.resultReply(envelope.ID, c.subscribe(ctx, payload))
+	case protocol.TypeUnsubscribe:
+		payload := protocol.UnsubscribePayload{}
+
+		if err = json.Unmarshal(envelope.Payload, &payload); err != nil {
+			return h.errorReply(envelope.ID, protocol.ErrInvalidMessageFormat)
+		}
+
+		c.unsubscribe(payload.EventID)
+		return h.newAck(envelope.ID)
+	default:
+		return h.newError(envelope.ID, protocol.ErrorCodeUnknownMessageType)
+	}
+}
+
+func (c *userConnection) subscribe(ctx context.Context, payload protocol.SubscribePayload) error {
+	h := c.handler
+	err := h.events.CheckAccess(ctx, service.EventAccessInput{
+		EventID:     payload.EventID,
+		UserID:      c.user.UserID,
+		InviteToken: payload.InviteToken,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	sub := newSubscriber(c.user.UserID, payload.EventID, h.logger, c.ch, c.done)
+
+	if payload.LastSeq != nil {
+		h.publisher.Resume(payload.EventID, *payload.LastSeq, sub)
+	} else {
+		h.publisher.Subscribe(payload.EventID, sub)
+	}
+
+	if payload.Join {
+		err = h.presence.Join(ctx, payload.EventID, domain.PresenceUser{
+			UserID:      c.user.UserID,
+			UserName:    c.user.UserName,
+			UserImageID: c.user.UserImageID,
+		})
+
+		if err != nil {
+			h.publisher.Unsubscribe(payload.EventID, sub)
+			return err
+		}
+	}
+
+	c.mutex.Lock()
+	c.events[payload.EventID] = &eventSubscription{subscriber: sub, joined: payload.Join}
+	c.mutex.Unlock()
+	go c.watch(payload.EventID, sub)
+	return nil
+}
+
+// watch drops the subscription when the event is closed or the user is kicked from it.
+func (c *userConnection) watch(eventId string, sub *subscriber) {
+	select {
+	case <-sub.closed:
+		c.remove(eventId, sub)
+	case <-c.done:
+	}
+}
+
+func (c *userConnection) unsubscribe(eventId string) {
+	c.mutex.Lock()
+	subscription, ok := c.events[eventId]
+	c.mutex.Unlock()
+
+	if ok {
+		c.remove(eventId, subscription.subscriber)
+		subscription.subscriber.OnClose()
+	}
+}
+
+func (c *userConnection) remove(eventId string, sub *subscriber) {
+	c.mutex.Lock()
+	subscription, ok := c.events[eventId]
+
+	if !ok || subscription.subscriber != sub {
+		c.mutex.Unlock()
+		return
+	}
+
+	delete(c.events, eventId)
+	c.mutex.Unlock()
+	c.leave(eventId, subscription)
+}
+
+func (c *userConnection) leave(eventId string, subscription *eventSubscription) {
+	c.handler.publisher.Unsubscribe(eventId, subscription.subscriber)
+
+	if !subscription.joined {
+		return
+	}
+
+	if err := c.handler.presence.Leave(eventId, c.user.UserID); err != nil && domain.IsInternalError(err) {
+		c.handler.logger.LogError(err)
+	}
+}
+
+func (c *userConnection) subscribed(eventId string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, ok := c.events[eventId]
+	return ok
+}
+
+func (c *userConnection) count() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.events)
+}
+
+func (c *userConnection) close(_ context.Context, userData connection.UserData, subscriber service.Subscriber) {
+	c.handler.publisher.Unsubscribe(service.UserTopic(userData.UserID), subscriber)
+	c.mutex.Lock()
+	events := c.events
+	c.events = make(map[string]*eventSubscription)
+	c.mutex.Unlock()
+
+	for eventId, subscription := range events {
+		c.leave(eventId, subscription)
+	}
+}
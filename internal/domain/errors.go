@@ -9,12 +9,41 @@ var (
 	ErrUserNotFound           = errors.New("UserNotFound")
 	ErrInvalidPassword        = errors.New("InvalidPassword")
 
-	ErrMediaNotFound = errors.New("MediaNotFound")
+	ErrSessionNotFound     = errors.New("SessionNotFound")
+	ErrInvalidRefreshToken = errors.New("InvalidRefreshToken")
+
+	ErrOtpCodeNotFound          = errors.New("OtpCodeNotFound")
+	ErrOtpCodeInvalid           = errors.New("OtpCodeInvalid")
+	ErrOtpCodeExpired           = errors.New("OtpCodeExpired")
+	ErrOtpRequestsLimitExceeded = errors.New("OtpRequestsLimitExceeded")
+	ErrOtpAttemptsLimitExceeded = errors.New("OtpAttemptsLimitExceeded")
+
+	ErrMediaNotFound        = errors.New("MediaNotFound")
+	ErrFileTooLarge         = errors.New("FileTooLarge")
+	ErrFileIsEmpty          = errors.New("FileIsEmpty")
+	ErrUnsupportedMediaType = errors.New("UnsupportedMediaType")
 
 	ErrEventNotFound        = errors.New("EventNotFound")
 	ErrOwnerAlreadyHasEvent = errors.New("OwnerAlreadyHasEvent")
 	ErrUserAlreadyExist     = errors.New("UserAlreadyExist")
 	ErrUserIsNotOwner       = errors.New("UserIsNotOwner")
+	ErrPermissionDenied     = errors.New("PermissionDenied")
+	ErrEventNotStarted      = errors.New("EventNotStarted")
+	ErrChatMessageNotFound  = errors.New("ChatMessageNotFound")
+
+	ErrEventAccessDenied       = errors.New("EventAccessDenied")
+	ErrInvalidInviteToken      = errors.New("InvalidInviteToken")
+	ErrInviteNotAllowed        = errors.New("InviteNotAllowed")
+	ErrJoinRequestNotAllowed   = errors.New("JoinRequestNotAllowed")
+	ErrJoinRequestAlreadyExist = errors.New("JoinRequestAlreadyExist")
+	ErrJoinRequestNotFound     = errors.New("JoinRequestNotFound")
+	ErrUserAlreadyMember       = errors.New("UserAlreadyMember")
+
+	ErrRoleNotFound = errors.New("RoleNotFound")
+	ErrInvalidRole  = errors.New("InvalidRole")
+
+	ErrUserMuted  = errors.New("UserMuted")
+	ErrUserBanned = errors.New("UserBanned")
 )
 
 func IsInternalError(err error) bool {
@@ -25,11 +54,35 @@ func IsInternalError(err error) bool {
 		ErrNameAndPhoneAlreadyUse,
 		ErrUserNotFound,
 		ErrInvalidPassword,
+		ErrSessionNotFound,
+		ErrInvalidRefreshToken,
+		ErrOtpCodeNotFound,
+		ErrOtpCodeInvalid,
+		ErrOtpCodeExpired,
+		ErrOtpRequestsLimitExceeded,
+		ErrOtpAttemptsLimitExceeded,
 		ErrMediaNotFound,
+		ErrFileTooLarge,
+		ErrFileIsEmpty,
+		ErrUnsupportedMediaType,
 		ErrEventNotFound,
 		ErrOwnerAlreadyHasEvent,
 		ErrUserAlreadyExist,
-		ErrUserIsNotOwner:
+		ErrUserIsNotOwner,
+		ErrPermissionDenied,
+		ErrEventNotStarted,
+		ErrChatMessageNotFound,
+		ErrEventAccessDenied,
+		ErrInvalidInviteToken,
+		ErrInviteNotAllowed,
+		ErrJoinRequestNotAllowed,
+		ErrJoinRequestAlreadyExist,
+		ErrJoinRequestNotFound,
+		ErrUserAlreadyMember,
+		ErrRoleNotFound,
+		ErrInvalidRole,
+		ErrUserMuted,
+		ErrUserBanned:
 		return false
 	default:
 		return true
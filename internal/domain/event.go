@@ -4,14 +4,36 @@ import "time"
 
 const EventStateOpened = 0
 const EventStateClosed = 1
+const EventStateScheduled = 2
+
+const EventVisibilityPublic = 0
+const EventVisibilityLink = 1
+const EventVisibilityApproval = 2
+
+const EventRoleGuest = 0
+const EventRoleModerator = 1
+const EventRoleCoHost = 2
+const EventRoleOwner = 3
+
+const EarthRadiusMeters = 6371008.8
 
 type EventState int
 
+type EventVisibility int
+
+type EventRole int
+
 type Coordinates struct {
 	X float64 `bson:"x" json:"x"`
 	Y float64 `bson:"y" json:"y"`
 }
 
+// IsValid reports whether X is a longitude and Y is a latitude, events outside of the bounds
+// never match the geo queries.
+func (c Coordinates) IsValid() bool {
+	return c.X >= -180 && c.X <= 180 && c.Y >= -90 && c.Y <= 90
+}
+
 type UserInfo struct {
 	ID string `bson:"_id" json:"id"`
 }
@@ -21,40 +43,112 @@ type MediaInfo struct {
 	ContentType string `bson:"content_type" json:"contentType"`
 }
 
+type EventRoleInfo struct {
+	UserID string    `bson:"_id"  json:"userId"`
+	Role   EventRole `bson:"role" json:"role"`
+}
+
+type EventMute struct {
+	UserID string    `bson:"_id"   json:"userId"`
+	Until  time.Time `bson:"until" json:"until"`
+}
+
+type JoinRequest struct {
+	UserID      string    `bson:"_id"           json:"userId"`
+	UserName    string    `bson:"user_name"     json:"userName"`
+	UserImageID string    `bson:"user_image_id" json:"userImageId"`
+	CreatedAt   time.Time `bson:"created_at"    json:"createdAt"`
+}
+
+type ChatReaction struct {
+	UserID string `bson:"user_id" json:"userId"`
+	Emoji  string `bson:"emoji"   json:"emoji"`
+}
+
+// ChatMessageEdit keeps the text a message had before it was edited at EditedAt.
+type ChatMessageEdit struct {
+	Message  string    `bson:"message"   json:"message"`
+	EditedAt time.Time `bson:"edited_at" json:"editedAt"`
+}
+
 type ChatMessage struct {
-	UserID      string `bson:"user_id"       json:"userId"`
-	UserName    string `bson:"user_name"     json:"userName"`
-	UserImageID string `bson:"user_image_id" json:"userImageId"`
-	Message     string `bson:"message"       json:"message"`
+	ID          string            `bson:"_id"                   json:"id"`
+	EventID     string            `bson:"event_id"              json:"-"`
+	UserID      string            `bson:"user_id"               json:"userId"`
+	UserName    string            `bson:"user_name"             json:"userName"`
+	UserImageID string            `bson:"user_image_id"         json:"userImageId"`
+	Message     string            `bson:"message"               json:"message"`
+	CreatedAt   time.Time         `bson:"created_at"            json:"createdAt"`
+	ReplyToID   string            `bson:"reply_to_id,omitempty" json:"replyToId,omitempty"`
+	Attachments []MediaInfo       `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	Reactions   []ChatReaction    `bson:"reactions,omitempty"   json:"reactions,omitempty"`
+	Edits       []ChatMessageEdit `bson:"edits,omitempty"       json:"edits,omitempty"`
+}
+
+type PresenceUser struct {
+	UserID      string `json:"userId"`
+	UserName    string `json:"userName"`
+	UserImageID string `json:"userImageId"`
+}
+
+type ChatHistory struct {
+	ChatMessages []ChatMessage `json:"chatMessages"`
+	HasMore      bool          `json:"hasMore"`
 }
 
 type Event struct {
-	ID           string        `bson:"_id"`
-	OwnerID      string        `bson:"owner_id"`
-	Name         string        `bson:"name"`
-	Address      string        `bson:"address"`
-	State        EventState    `bson:"state"`
-	Coordinates  Coordinates   `bson:"coordinates"`
-	CreatedAt    time.Time     `bson:"created_at"`
-	Users        []UserInfo    `bson:"users"`
-	Media        []MediaInfo   `bson:"media"`
-	ChatMessages []ChatMessage `bson:"chat_messages"`
+	ID           string          `bson:"_id"`
+	OwnerID      string          `bson:"owner_id"`
+	Name         string          `bson:"name"`
+	Address      string          `bson:"address"`
+	State        EventState      `bson:"state"`
+	Visibility   EventVisibility `bson:"visibility"`
+	Coordinates  Coordinates     `bson:"coordinates"`
+	CreatedAt    time.Time       `bson:"created_at"`
+	StartsAt     *time.Time      `bson:"starts_at,omitempty"`
+	EndsAt       *time.Time      `bson:"ends_at,omitempty"`
+	Users        []UserInfo      `bson:"users"`
+	Media        []MediaInfo     `bson:"media"`
+	Members      []UserInfo      `bson:"members"`
+	Roles        []EventRoleInfo `bson:"roles"`
+	JoinRequests []JoinRequest   `bson:"join_requests"`
+	Mutes        []EventMute     `bson:"mutes"`
+	BannedUsers  []UserInfo      `bson:"banned_users"`
 }
 
 type EventRangeData struct {
 	ID          string      `bson:"_id"         json:"id"`
 	Name        string      `bson:"name"        json:"name"`
+	State       EventState  `bson:"state"       json:"state"`
+	StartsAt    *time.Time  `bson:"starts_at"   json:"startsAt,omitempty"`
 	UsersCount  int         `bson:"users_count" json:"usersCount"`
 	Coordinates Coordinates `bson:"coordinates" json:"coordinates"`
+	Distance    *float64    `bson:"distance"    json:"distance,omitempty"`
+}
+
+type EventCluster struct {
+	Coordinates Coordinates `json:"coordinates"`
+	EventsCount int         `json:"eventsCount"`
+	UsersCount  int         `json:"usersCount"`
+}
+
+type EventClusters struct {
+	Clusters []EventCluster   `json:"clusters"`
+	Events   []EventRangeData `json:"events"`
 }
 
 type EventInfo struct {
-	ID           string        `bson:"_id"           json:"id"`
-	OwnerID      string        `bson:"owner_id"      json:"ownerId"`
-	Name         string        `bson:"name"          json:"name"`
-	Address      string        `bson:"address"       json:"address"`
-	Coordinates  Coordinates   `bson:"coordinates"   json:"coordinates"`
-	UsersCount   int           `bson:"users_count"   json:"usersCount"`
-	Media        []MediaInfo   `bson:"media"         json:"media"`
-	ChatMessages []ChatMessage `bson:"chat_messages" json:"chatMessages"`
+	ID           string          `bson:"_id"           json:"id"`
+	OwnerID      string          `bson:"owner_id"      json:"ownerId"`
+	Name         string          `bson:"name"          json:"name"`
+	Address      string          `bson:"address"       json:"address"`
+	State        EventState      `bson:"state"         json:"state"`
+	Visibility   EventVisibility `bson:"visibility"    json:"visibility"`
+	StartsAt     *time.Time      `bson:"starts_at"     json:"startsAt,omitempty"`
+	EndsAt       *time.Time      `bson:"ends_at"       json:"endsAt,omitempty"`
+	Coordinates  Coordinates     `bson:"coordinates"   json:"coordinates"`
+	UsersCount   int             `bson:"users_count"   json:"usersCount"`
+	Media        []MediaInfo     `bson:"media"         json:"media"`
+	Roles        []EventRoleInfo `bson:"roles"         json:"roles"`
+	ChatMessages []ChatMessage   `bson:"chat_messages" json:"chatMessages"`
 }
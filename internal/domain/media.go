@@ -1,17 +1,32 @@
 package domain
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 type FileData struct {
-	ContentType string
-	Size        int64
-	Data        []byte
+	ContentType      string
+	Size             int64
+	LastModifiedDate time.Time
+	Data             io.ReadSeekCloser
 }
 
 type Media struct {
-	ID               string    `bson:"_id"`
-	Name             string    `bson:"name"`
-	ContentType      string    `bson:"content_type"`
-	Size             int64     `bson:"size"`
-	LastModifiedDate time.Time `bson:"last_modified_date"`
+	ID               string         `bson:"_id"`
+	Name             string         `bson:"name"`
+	ContentType      string         `bson:"content_type"`
+	OwnerID          string         `bson:"owner_id"`
+	Size             int64          `bson:"size"`
+	LastModifiedDate time.Time      `bson:"last_modified_date"`
+	Variants         []MediaVariant `bson:"variants"`
+}
+
+// MediaVariant is a resized copy of an image, Size is the side of the square the image is fitted into.
+type MediaVariant struct {
+	Size        int    `bson:"size"         json:"size"`
+	Width       int    `bson:"width"        json:"width"`
+	Height      int    `bson:"height"       json:"height"`
+	ContentType string `bson:"content_type" json:"contentType"`
+	FileSize    int64  `bson:"file_size"    json:"fileSize"`
 }
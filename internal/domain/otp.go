@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+type OtpCode struct {
+	ID        string    `bson:"_id"`
+	Phone     string    `bson:"phone"`
+	Code      string    `bson:"code"`
+	Attempts  int       `bson:"attempts"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// OtpRequestsLimit is how often codes can be requested for one phone:
+// not more often than Interval and not more than Count in a Window.
+type OtpRequestsLimit struct {
+	Interval time.Duration
+	Window   time.Duration
+	Count    int
+}
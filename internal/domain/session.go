@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+type DeviceInfo struct {
+	Name      string `bson:"name"       json:"name"`
+	UserAgent string `bson:"user_agent" json:"userAgent"`
+	IPAddress string `bson:"ip_address" json:"ipAddress"`
+}
+
+type Session struct {
+	ID           string     `bson:"_id"`
+	UserID       string     `bson:"user_id"`
+	RefreshToken string     `bson:"refresh_token"`
+	Device       DeviceInfo `bson:"device"`
+	CreatedAt    time.Time  `bson:"created_at"`
+	LastUsedAt   time.Time  `bson:"last_used_at"`
+	ExpiresAt    time.Time  `bson:"expires_at"`
+}
+
+type SessionInfo struct {
+	ID         string     `json:"id"`
+	Device     DeviceInfo `json:"device"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt time.Time  `json:"lastUsedAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	IsCurrent  bool       `json:"isCurrent"`
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
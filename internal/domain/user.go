@@ -10,10 +10,11 @@ type User struct {
 }
 
 type UserLogin struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Phone       string  `json:"phone"`
-	ImageID     *string `json:"imageId"`
-	EventID     *string `json:"eventId"`
-	AccessToken string  `json:"accessToken"`
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Phone        string  `json:"phone"`
+	ImageID      *string `json:"imageId"`
+	EventID      *string `json:"eventId"`
+	AccessToken  string  `json:"accessToken"`
+	RefreshToken string  `json:"refreshToken"`
 }
@@ -0,0 +1,28 @@
+package memory
+
+type TestsCleaner struct {
+	media    *Media
+	users    *Users
+	sessions *Sessions
+	otpCodes *OtpCodes
+	events   *Events
+}
+
+func newTestsCleaner(media *Media, users *Users, sessions *Sessions, otpCodes *OtpCodes, events *Events) *TestsCleaner {
+	return &TestsCleaner{
+		media:    media,
+		users:    users,
+		sessions: sessions,
+		otpCodes: otpCodes,
+		events:   events,
+	}
+}
+
+func (c *TestsCleaner) Clean() error {
+	c.media.clear()
+	c.users.clear()
+	c.sessions.clear()
+	c.otpCodes.clear()
+	c.events.clear()
+	return nil
+}
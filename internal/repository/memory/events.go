@@ -0,0 +1,628 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+type Events struct {
+	mutex        sync.RWMutex
+	items        []domain.Event
+	chatMessages []domain.ChatMessage
+}
+
+func newEvents() *Events {
+	return &Events{
+		mutex:        sync.RWMutex{},
+		items:        []domain.Event{},
+		chatMessages: []domain.ChatMessage{},
+	}
+}
+
+func (r *Events) CreateEvent(ctx context.Context, event domain.Event) (string, error) {
+	event.ID = uuid.New().String()
+	r.mutex.Lock()
+	r.items = append(r.items, copyEvent(event))
+	r.mutex.Unlock()
+	return event.ID, nil
+}
+
+func (r *Events) GetEventById(ctx context.Context, id string) (domain.Event, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, event := range r.items {
+		if event.ID == id && event.State != domain.EventStateClosed {
+			return copyEvent(event), nil
+		}
+	}
+
+	return domain.Event{}, domain.ErrEventNotFound
+}
+
+func (r *Events) GetEventByOwnerId(ctx context.Context, ownerId string) (domain.Event, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, event := range r.items {
+		if event.OwnerID == ownerId && event.State != domain.EventStateClosed {
+			return copyEvent(event), nil
+		}
+	}
+
+	return domain.Event{}, domain.ErrEventNotFound
+}
+
+func (r *Events) GetEventsByRange(ctx context.Context, xLeft float64, xRight float64, yLeft float64, yRight float64) ([]domain.EventRangeData, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	result := make([]domain.EventRangeData, 0)
+
+	for _, event := range r.items {
+		if event.State == domain.EventStateClosed || event.Visibility != domain.EventVisibilityPublic {
+			continue
+		}
+
+		if !event.Coordinates.IsValid() || !inLongitudeRange(event.Coordinates.X, xLeft, xRight) ||
+			event.Coordinates.Y < yLeft || event.Coordinates.Y > yRight {
+			continue
+		}
+
+		result = append(result, domain.EventRangeData{
+			ID:          event.ID,
+			Name:        event.Name,
+			State:       event.State,
+			StartsAt:    event.StartsAt,
+			UsersCount:  len(event.Users),
+			Coordinates: event.Coordinates,
+		})
+	}
+
+	return result, nil
+}
+
+func (r *Events) GetEventsNearby(ctx context.Context, center domain.Coordinates, maxDistance float64) ([]domain.EventRangeData, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	result := make([]domain.EventRangeData, 0)
+
+	for _, event := range r.items {
+		if event.State == domain.EventStateClosed || event.Visibility != domain.EventVisibilityPublic || !event.Coordinates.IsValid() {
+			continue
+		}
+
+		distance := haversineDistance(center, event.Coordinates)
+
+		if distance > maxDistance {
+			continue
+		}
+
+		result = append(result, domain.EventRangeData{
+			ID:          event.ID,
+			Name:        event.Name,
+			State:       event.State,
+			StartsAt:    event.StartsAt,
+			UsersCount:  len(event.Users),
+			Coordinates: event.Coordinates,
+			Distance:    &distance,
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return *result[i].Distance < *result[j].Distance
+	})
+
+	return result, nil
+}
+
+func (r *Events) GetScheduledEventIds(ctx context.Context, startsBefore time.Time) ([]string, error) {
+	return r.findIds(func(event domain.Event) bool {
+		return event.State == domain.EventStateScheduled && event.StartsAt != nil && !event.StartsAt.After(startsBefore)
+	}), nil
+}
+
+func (r *Events) GetEndedEventIds(ctx context.Context, endsBefore time.Time) ([]string, error) {
+	return r.findIds(func(event domain.Event) bool {
+		return event.State != domain.EventStateClosed && event.EndsAt != nil && !event.EndsAt.After(endsBefore)
+	}), nil
+}
+
+func (r *Events) GetIdleEventIds(ctx context.Context, startedBefore time.Time) ([]string, error) {
+	return r.findIds(func(event domain.Event) bool {
+		startedAt := event.CreatedAt
+		if event.StartsAt != nil {
+			startedAt = *event.StartsAt
+		}
+		return event.State == domain.EventStateOpened && len(event.Users) == 0 && !startedAt.After(startedBefore)
+	}), nil
+}
+
+func (r *Events) OpenEvent(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.items {
+		if r.items[i].ID == id && r.items[i].State == domain.EventStateScheduled {
+			r.items[i].State = domain.EventStateOpened
+			return nil
+		}
+	}
+
+	return domain.ErrEventNotFound
+}
+
+func (r *Events) UpdateEvent(ctx context.Context, id string, address string, coordinates domain.Coordinates) error {
+	return r.updateActive(id, func(event *domain.Event) {
+		event.Address = address
+		event.Coordinates = coordinates
+	})
+}
+
+func (r *Events) RemoveEvent(ctx context.Context, id string) error {
+	return r.updateActive(id, func(event *domain.Event) {
+		event.State = domain.EventStateClosed
+	})
+}
+
+func (r *Events) AddMedia(ctx context.Context, id string, mediaInfo domain.MediaInfo) error {
+	return r.updateActive(id, func(event *domain.Event) {
+		event.Media = append(event.Media, mediaInfo)
+	})
+}
+
+func (r *Events) RemoveMedia(ctx context.Context, eventId string, mediaId string) error {
+	return r.updateActive(eventId, func(event *domain.Event) {
+		media := make([]domain.MediaInfo, 0, len(event.Media))
+		for _, item := range event.Media {
+			if item.ID != mediaId {
+				media = append(media, item)
+			}
+		}
+		event.Media = media
+	})
+}
+
+func (r *Events) AddUserInfo(ctx context.Context, eventId string, userInfo domain.UserInfo) error {
+	return r.update(eventId, func(event *domain.Event) {
+		event.Users = append(event.Users, userInfo)
+	})
+}
+
+func (r *Events) RemoveUserInfo(ctx context.Context, eventId string, userId string) error {
+	return r.update(eventId, func(event *domain.Event) {
+		users := make([]domain.UserInfo, 0, len(event.Users))
+		for _, item := range event.Users {
+			if item.ID != userId {
+				users = append(users, item)
+			}
+		}
+		event.Users = users
+	})
+}
+
+func (r *Events) AddMember(ctx context.Context, eventId string, userInfo domain.UserInfo) error {
+	return r.updateActive(eventId, func(event *domain.Event) {
+		for _, item := range event.Members {
+			if item.ID == userInfo.ID {
+				return
+			}
+		}
+		event.Members = append(event.Members, userInfo)
+	})
+}
+
+func (r *Events) AddJoinRequest(ctx context.Context, eventId string, joinRequest domain.JoinRequest) error {
+	exists := false
+	err := r.updateActive(eventId, func(event *domain.Event) {
+		for _, item := range event.JoinRequests {
+			if item.UserID == joinRequest.UserID {
+				exists = true
+				return
+			}
+		}
+		event.JoinRequests = append(event.JoinRequests, joinRequest)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return domain.ErrJoinRequestAlreadyExist
+	}
+
+	return nil
+}
+
+func (r *Events) ApproveJoinRequest(ctx context.Context, eventId string, userId string) error {
+	return r.removeJoinRequest(eventId, userId, true)
+}
+
+func (r *Events) RemoveJoinRequest(ctx context.Context, eventId string, userId string) error {
+	return r.removeJoinRequest(eventId, userId, false)
+}
+
+func (r *Events) SetRole(ctx context.Context, eventId string, roleInfo domain.EventRoleInfo) error {
+	return r.updateActive(eventId, func(event *domain.Event) {
+		for i := range event.Roles {
+			if event.Roles[i].UserID == roleInfo.UserID {
+				event.Roles[i].Role = roleInfo.Role
+				return
+			}
+		}
+		event.Roles = append(event.Roles, roleInfo)
+	})
+}
+
+func (r *Events) RemoveRole(ctx context.Context, eventId string, userId string) error {
+	found := false
+	err := r.updateActive(eventId, func(event *domain.Event) {
+		roles := make([]domain.EventRoleInfo, 0, len(event.Roles))
+		for _, item := range event.Roles {
+			if item.UserID == userId {
+				found = true
+			} else {
+				roles = append(roles, item)
+			}
+		}
+		event.Roles = roles
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return domain.ErrRoleNotFound
+	}
+
+	return nil
+}
+
+func (r *Events) SetMute(ctx context.Context, eventId string, mute domain.EventMute) error {
+	return r.updateActive(eventId, func(event *domain.Event) {
+		for i := range event.Mutes {
+			if event.Mutes[i].UserID == mute.UserID {
+				event.Mutes[i].Until = mute.Until
+				return
+			}
+		}
+		event.Mutes = append(event.Mutes, mute)
+	})
+}
+
+func (r *Events) BanUser(ctx context.Context, eventId string, userId string) error {
+	return r.updateActive(eventId, func(event *domain.Event) {
+		users := make([]domain.UserInfo, 0, len(event.Users))
+		for _, item := range event.Users {
+			if item.ID != userId {
+				users = append(users, item)
+			}
+		}
+		event.Users = users
+
+		members := make([]domain.UserInfo, 0, len(event.Members))
+		for _, item := range event.Members {
+			if item.ID != userId {
+				members = append(members, item)
+			}
+		}
+		event.Members = members
+
+		roles := make([]domain.EventRoleInfo, 0, len(event.Roles))
+		for _, item := range event.Roles {
+			if item.UserID != userId {
+				roles = append(roles, item)
+			}
+		}
+		event.Roles = roles
+
+		joinRequests := make([]domain.JoinRequest, 0, len(event.JoinRequests))
+		for _, item := range event.JoinRequests {
+			if item.UserID != userId {
+				joinRequests = append(joinRequests, item)
+			}
+		}
+		event.JoinRequests = joinRequests
+
+		for _, item := range event.BannedUsers {
+			if item.ID == userId {
+				return
+			}
+		}
+		event.BannedUsers = append(event.BannedUsers, domain.UserInfo{ID: userId})
+	})
+}
+
+func (r *Events) AddChatMessage(ctx context.Context, id string, chatMessage domain.ChatMessage) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.indexOfOpened(id) < 0 {
+		return "", domain.ErrEventNotFound
+	}
+
+	chatMessage.ID = uuid.New().String()
+	chatMessage.EventID = id
+	r.chatMessages = append(r.chatMessages, copyChatMessage(chatMessage))
+	return chatMessage.ID, nil
+}
+
+func (r *Events) GetChatMessages(ctx context.Context, eventId string, beforeId string, afterId string, limit int) ([]domain.ChatMessage, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	messages := make([]domain.ChatMessage, 0)
+
+	for _, chatMessage := range r.chatMessages {
+		if chatMessage.EventID == eventId {
+			messages = append(messages, chatMessage)
+		}
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		if messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].ID < messages[j].ID
+		}
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+
+	start, end := 0, len(messages)
+
+	switch {
+	case beforeId != "":
+		end = indexOfChatMessage(messages, beforeId)
+		if end < 0 {
+			return nil, domain.ErrChatMessageNotFound
+		}
+		start = end - limit
+	case afterId != "":
+		start = indexOfChatMessage(messages, afterId)
+		if start < 0 {
+			return nil, domain.ErrChatMessageNotFound
+		}
+		start++
+		if start+limit < end {
+			end = start + limit
+		}
+	default:
+		start = end - limit
+	}
+
+	if start < 0 {
+		start = 0
+	}
+
+	result := make([]domain.ChatMessage, 0, end-start)
+	for _, chatMessage := range messages[start:end] {
+		result = append(result, copyChatMessage(chatMessage))
+	}
+
+	return result, nil
+}
+
+func (r *Events) GetChatMessage(ctx context.Context, eventId string, messageId string) (domain.ChatMessage, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, chatMessage := range r.chatMessages {
+		if chatMessage.ID == messageId && chatMessage.EventID == eventId {
+			return copyChatMessage(chatMessage), nil
+		}
+	}
+
+	return domain.ChatMessage{}, domain.ErrChatMessageNotFound
+}
+
+func (r *Events) EditChatMessage(ctx context.Context, eventId string, messageId string, message string, edit domain.ChatMessageEdit) (domain.ChatMessage, error) {
+	return r.updateChatMessage(eventId, messageId, func(chatMessage *domain.ChatMessage) {
+		chatMessage.Message = message
+		chatMessage.Edits = append(chatMessage.Edits, edit)
+	})
+}
+
+func (r *Events) ToggleChatReaction(ctx context.Context, eventId string, messageId string, reaction domain.ChatReaction) (domain.ChatMessage, error) {
+	return r.updateChatMessage(eventId, messageId, func(chatMessage *domain.ChatMessage) {
+		for i, item := range chatMessage.Reactions {
+			if item == reaction {
+				chatMessage.Reactions = append(chatMessage.Reactions[:i], chatMessage.Reactions[i+1:]...)
+				return
+			}
+		}
+		chatMessage.Reactions = append(chatMessage.Reactions, reaction)
+	})
+}
+
+func (r *Events) updateChatMessage(eventId string, messageId string, apply func(chatMessage *domain.ChatMessage)) (domain.ChatMessage, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.chatMessages {
+		if r.chatMessages[i].ID == messageId && r.chatMessages[i].EventID == eventId {
+			apply(&r.chatMessages[i])
+			return copyChatMessage(r.chatMessages[i]), nil
+		}
+	}
+
+	return domain.ChatMessage{}, domain.ErrChatMessageNotFound
+}
+
+func (r *Events) RemoveChatMessage(ctx context.Context, eventId string, messageId string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, chatMessage := range r.chatMessages {
+		if chatMessage.ID == messageId && chatMessage.EventID == eventId {
+			r.chatMessages = append(r.chatMessages[:i], r.chatMessages[i+1:]...)
+			return nil
+		}
+	}
+
+	return domain.ErrChatMessageNotFound
+}
+
+func (r *Events) update(id string, apply func(event *domain.Event)) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	index := r.indexOfOpened(id)
+
+	if index < 0 {
+		return domain.ErrEventNotFound
+	}
+
+	apply(&r.items[index])
+	return nil
+}
+
+func (r *Events) updateActive(id string, apply func(event *domain.Event)) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.items {
+		if r.items[i].ID == id && r.items[i].State != domain.EventStateClosed {
+			apply(&r.items[i])
+			return nil
+		}
+	}
+
+	return domain.ErrEventNotFound
+}
+
+func (r *Events) removeJoinRequest(eventId string, userId string, approve bool) error {
+	found := false
+	err := r.updateActive(eventId, func(event *domain.Event) {
+		joinRequests := make([]domain.JoinRequest, 0, len(event.JoinRequests))
+		for _, item := range event.JoinRequests {
+			if item.UserID == userId {
+				found = true
+			} else {
+				joinRequests = append(joinRequests, item)
+			}
+		}
+		event.JoinRequests = joinRequests
+		if found && approve {
+			event.Members = append(event.Members, domain.UserInfo{ID: userId})
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return domain.ErrJoinRequestNotFound
+	}
+
+	return nil
+}
+
+func (r *Events) findIds(match func(event domain.Event) bool) []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	result := make([]string, 0)
+
+	for _, event := range r.items {
+		if match(event) {
+			result = append(result, event.ID)
+		}
+	}
+
+	return result
+}
+
+func (r *Events) indexOfOpened(id string) int {
+	for i := range r.items {
+		if r.items[i].ID == id && r.items[i].State == domain.EventStateOpened {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (r *Events) clear() {
+	r.mutex.Lock()
+	r.items = []domain.Event{}
+	r.chatMessages = []domain.ChatMessage{}
+	r.mutex.Unlock()
+}
+
+func inLongitudeRange(x float64, xLeft float64, xRight float64) bool {
+	if xLeft > xRight {
+		return x >= xLeft || x <= xRight
+	}
+
+	return x >= xLeft && x <= xRight
+}
+
+func haversineDistance(from domain.Coordinates, to domain.Coordinates) float64 {
+	lat1 := from.Y * math.Pi / 180
+	lat2 := to.Y * math.Pi / 180
+	deltaLat := lat2 - lat1
+	deltaLon := (to.X - from.X) * math.Pi / 180
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
+	return 2 * domain.EarthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func indexOfChatMessage(messages []domain.ChatMessage, id string) int {
+	for i := range messages {
+		if messages[i].ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func copyEvent(event domain.Event) domain.Event {
+	if event.Users != nil {
+		event.Users = append([]domain.UserInfo{}, event.Users...)
+	}
+
+	if event.Media != nil {
+		event.Media = append([]domain.MediaInfo{}, event.Media...)
+	}
+
+	if event.Members != nil {
+		event.Members = append([]domain.UserInfo{}, event.Members...)
+	}
+
+	if event.JoinRequests != nil {
+		event.JoinRequests = append([]domain.JoinRequest{}, event.JoinRequests...)
+	}
+
+	if event.Roles != nil {
+		event.Roles = append([]domain.EventRoleInfo{}, event.Roles...)
+	}
+
+	if event.Mutes != nil {
+		event.Mutes = append([]domain.EventMute{}, event.Mutes...)
+	}
+
+	if event.BannedUsers != nil {
+		event.BannedUsers = append([]domain.UserInfo{}, event.BannedUsers...)
+	}
+
+	return event
+}
+
+func copyChatMessage(chatMessage domain.ChatMessage) domain.ChatMessage {
+	if chatMessage.Attachments != nil {
+		chatMessage.Attachments = append([]domain.MediaInfo{}, chatMessage.Attachments...)
+	}
+
+	if chatMessage.Reactions != nil {
+		chatMessage.Reactions = append([]domain.ChatReaction{}, chatMessage.Reactions...)
+	}
+
+	if chatMessage.Edits != nil {
+		chatMessage.Edits = append([]domain.ChatMessageEdit{}, chatMessage.Edits...)
+	}
+
+	return chatMessage
+}
@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+type Media struct {
+	mutex sync.RWMutex
+	items map[string]domain.Media
+}
+
+func newMedia() *Media {
+	return &Media{
+		mutex: sync.RWMutex{},
+		items: make(map[string]domain.Media),
+	}
+}
+
+func (r *Media) GetMedia(ctx context.Context, id string) (domain.Media, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	media, ok := r.items[id]
+
+	if !ok {
+		return domain.Media{}, domain.ErrMediaNotFound
+	}
+
+	return media, nil
+}
+
+func (r *Media) CreateMedia(ctx context.Context, media domain.Media) (string, error) {
+	media.ID = uuid.New().String()
+	r.mutex.Lock()
+	r.items[media.ID] = media
+	r.mutex.Unlock()
+	return media.ID, nil
+}
+
+func (r *Media) UpdateMedia(ctx context.Context, media domain.Media) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	old, ok := r.items[media.ID]
+
+	if !ok {
+		return domain.ErrMediaNotFound
+	}
+
+	media.OwnerID = old.OwnerID
+	r.items[media.ID] = media
+	return nil
+}
+
+func (r *Media) DeleteMedia(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.items[id]; !ok {
+		return domain.ErrMediaNotFound
+	}
+
+	delete(r.items, id)
+	return nil
+}
+
+func (r *Media) clear() {
+	r.mutex.Lock()
+	r.items = make(map[string]domain.Media)
+	r.mutex.Unlock()
+}
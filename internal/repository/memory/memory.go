@@ -0,0 +1,10 @@
+package memory
+
+func NewRepositories() (*Media, *Users, *Sessions, *OtpCodes, *Events, *TestsCleaner) {
+	media := newMedia()
+	users := newUsers()
+	sessions := newSessions()
+	otpCodes := newOtpCodes()
+	events := newEvents()
+	return media, users, sessions, otpCodes, events, newTestsCleaner(media, users, sessions, otpCodes, events)
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+type otpRequests struct {
+	windowStart   time.Time
+	count         int
+	lastRequestAt time.Time
+}
+
+type OtpCodes struct {
+	mutex    sync.RWMutex
+	items    []domain.OtpCode
+	requests map[string]otpRequests
+}
+
+func newOtpCodes() *OtpCodes {
+	return &OtpCodes{
+		mutex:    sync.RWMutex{},
+		items:    []domain.OtpCode{},
+		requests: make(map[string]otpRequests),
+	}
+}
+
+func (r *OtpCodes) CreateOtpCode(ctx context.Context, code domain.OtpCode) (string, error) {
+	code.ID = uuid.New().String()
+	r.mutex.Lock()
+	r.items = append(r.items, code)
+	r.mutex.Unlock()
+	return code.ID, nil
+}
+
+func (r *OtpCodes) GetLastOtpCode(ctx context.Context, phone string) (domain.OtpCode, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	found := false
+	result := domain.OtpCode{}
+
+	for _, code := range r.items {
+		if code.Phone == phone && (!found || !code.CreatedAt.Before(result.CreatedAt)) {
+			result = code
+			found = true
+		}
+	}
+
+	if !found {
+		return domain.OtpCode{}, domain.ErrOtpCodeNotFound
+	}
+
+	return result, nil
+}
+
+func (r *OtpCodes) AddOtpRequest(ctx context.Context, phone string, now time.Time, limit domain.OtpRequestsLimit) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	requests, ok := r.requests[phone]
+
+	if !ok || !requests.windowStart.After(now.Add(-limit.Window)) {
+		r.requests[phone] = otpRequests{windowStart: now, count: 1, lastRequestAt: now}
+		return nil
+	}
+
+	if requests.count >= limit.Count || requests.lastRequestAt.After(now.Add(-limit.Interval)) {
+		return domain.ErrOtpRequestsLimitExceeded
+	}
+
+	requests.count++
+	requests.lastRequestAt = now
+	r.requests[phone] = requests
+	return nil
+}
+
+func (r *OtpCodes) IncrementOtpAttempts(ctx context.Context, id string, maxAttempts int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.items {
+		if r.items[i].ID == id {
+			if r.items[i].Attempts >= maxAttempts {
+				return domain.ErrOtpAttemptsLimitExceeded
+			}
+
+			r.items[i].Attempts++
+			return nil
+		}
+	}
+
+	return domain.ErrOtpCodeNotFound
+}
+
+func (r *OtpCodes) RemoveOtpCode(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.items {
+		if r.items[i].ID == id {
+			r.items = append(r.items[:i], r.items[i+1:]...)
+			return nil
+		}
+	}
+
+	return domain.ErrOtpCodeNotFound
+}
+
+func (r *OtpCodes) clear() {
+	r.mutex.Lock()
+	r.items = []domain.OtpCode{}
+	r.requests = make(map[string]otpRequests)
+	r.mutex.Unlock()
+}
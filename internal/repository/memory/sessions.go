@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+type Sessions struct {
+	mutex sync.RWMutex
+	items []domain.Session
+}
+
+func newSessions() *Sessions {
+	return &Sessions{
+		mutex: sync.RWMutex{},
+		items: []domain.Session{},
+	}
+}
+
+func (r *Sessions) CreateSession(ctx context.Context, session domain.Session) (string, error) {
+	session.ID = uuid.New().String()
+	r.mutex.Lock()
+	r.items = append(r.items, session)
+	r.mutex.Unlock()
+	return session.ID, nil
+}
+
+func (r *Sessions) GetSessionByID(ctx context.Context, id string) (domain.Session, error) {
+	return r.find(func(session domain.Session) bool {
+		return session.ID == id
+	})
+}
+
+func (r *Sessions) GetSessionByRefreshToken(ctx context.Context, refreshToken string) (domain.Session, error) {
+	return r.find(func(session domain.Session) bool {
+		return session.RefreshToken == refreshToken
+	})
+}
+
+func (r *Sessions) GetUserSessions(ctx context.Context, userId string) ([]domain.Session, error) {
+	r.mutex.RLock()
+	result := []domain.Session{}
+
+	for _, session := range r.items {
+		if session.UserID == userId {
+			result = append(result, session)
+		}
+	}
+
+	r.mutex.RUnlock()
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (r *Sessions) UpdateRefreshToken(ctx context.Context, id string, oldRefreshToken string, newRefreshToken string, lastUsedAt time.Time, expiresAt time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.items {
+		if r.items[i].ID == id && r.items[i].RefreshToken == oldRefreshToken {
+			r.items[i].RefreshToken = newRefreshToken
+			r.items[i].LastUsedAt = lastUsedAt
+			r.items[i].ExpiresAt = expiresAt
+			return nil
+		}
+	}
+
+	return domain.ErrSessionNotFound
+}
+
+func (r *Sessions) RemoveSession(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.items {
+		if r.items[i].ID == id {
+			r.items = append(r.items[:i], r.items[i+1:]...)
+			return nil
+		}
+	}
+
+	return domain.ErrSessionNotFound
+}
+
+func (r *Sessions) RemoveUserSessions(ctx context.Context, userId string) error {
+	r.mutex.Lock()
+	items := make([]domain.Session, 0, len(r.items))
+
+	for _, session := range r.items {
+		if session.UserID != userId {
+			items = append(items, session)
+		}
+	}
+
+	r.items = items
+	r.mutex.Unlock()
+	return nil
+}
+
+func (r *Sessions) find(match func(session domain.Session) bool) (domain.Session, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, session := range r.items {
+		if match(session) {
+			return session, nil
+		}
+	}
+
+	return domain.Session{}, domain.ErrSessionNotFound
+}
+
+func (r *Sessions) clear() {
+	r.mutex.Lock()
+	r.items = []domain.Session{}
+	r.mutex.Unlock()
+}
@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+type Users struct {
+	mutex sync.RWMutex
+	items []domain.User
+}
+
+func newUsers() *Users {
+	return &Users{
+		mutex: sync.RWMutex{},
+		items: []domain.User{},
+	}
+}
+
+func (r *Users) GetNamesCount(ctx context.Context, name string) (int64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var count int64
+
+	for _, user := range r.items {
+		if user.Name == name {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *Users) GetPhonesCount(ctx context.Context, phone string) (int64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var count int64
+
+	for _, user := range r.items {
+		if user.Phone == phone {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *Users) CreateUser(ctx context.Context, user domain.User) (string, error) {
+	user.ID = uuid.New().String()
+	r.mutex.Lock()
+	r.items = append(r.items, user)
+	r.mutex.Unlock()
+	return user.ID, nil
+}
+
+func (r *Users) GetUserByID(ctx context.Context, id string) (domain.User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, user := range r.items {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+
+	return domain.User{}, domain.ErrUserNotFound
+}
+
+func (r *Users) GetUserByPhone(ctx context.Context, phone string) (domain.User, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, user := range r.items {
+		if user.Phone == phone {
+			return user, nil
+		}
+	}
+
+	return domain.User{}, domain.ErrUserNotFound
+}
+
+func (r *Users) ChangePassword(ctx context.Context, id string, password string) error {
+	return r.update(id, func(user *domain.User) {
+		user.Password = password
+	})
+}
+
+func (r *Users) SetImageId(ctx context.Context, userId string, imageId string) error {
+	return r.update(userId, func(user *domain.User) {
+		user.ImageID = imageId
+	})
+}
+
+func (r *Users) UpdateName(ctx context.Context, userId string, name string) error {
+	return r.update(userId, func(user *domain.User) {
+		user.Name = name
+	})
+}
+
+func (r *Users) UpdatePhone(ctx context.Context, userId string, phone string) error {
+	return r.update(userId, func(user *domain.User) {
+		user.Phone = phone
+	})
+}
+
+func (r *Users) UpdateNameAndPhone(ctx context.Context, userId string, name string, phone string) error {
+	return r.update(userId, func(user *domain.User) {
+		user.Name = name
+		user.Phone = phone
+	})
+}
+
+func (r *Users) update(id string, apply func(user *domain.User)) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.items {
+		if r.items[i].ID == id {
+			apply(&r.items[i])
+			return nil
+		}
+	}
+
+	return domain.ErrUserNotFound
+}
+
+func (r *Users) clear() {
+	r.mutex.Lock()
+	r.items = []domain.User{}
+	r.mutex.Unlock()
+}
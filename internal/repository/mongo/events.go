@@ -3,6 +3,7 @@ package mongo
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
@@ -11,19 +12,34 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var rangeDataProjection = bson.D{
+	{Key: "_id", Value: 1},
+	{Key: "name", Value: 1},
+	{Key: "state", Value: 1},
+	{Key: "starts_at", Value: 1},
+	{Key: "coordinates", Value: 1},
+	{Key: "users_count", Value: bson.D{{Key: "$size", Value: "$users"}}},
+}
+
+var activeStateFilter = bson.E{Key: "state", Value: bson.D{{Key: "$ne", Value: domain.EventStateClosed}}}
+
+var publicVisibilityFilter = bson.E{Key: "visibility", Value: domain.EventVisibilityPublic}
+
 type Events struct {
-	db *mongo.Collection
+	db           *mongo.Collection
+	chatMessages *mongo.Collection
 }
 
-func newEvents(db *mongo.Database, collectionName string) *Events {
+func newEvents(db *mongo.Database, collectionName string, chatMessagesCollectionName string) *Events {
 	return &Events{
-		db: db.Collection(collectionName),
+		db:           db.Collection(collectionName),
+		chatMessages: db.Collection(chatMessagesCollectionName),
 	}
 }
 
 func (r *Events) CreateEvent(ctx context.Context, event domain.Event) (string, error) {
 	event.ID = uuid.New().String()
-	_, err := r.db.InsertOne(ctx, event)
+	_, err := r.db.InsertOne(ctx, eventDocument{Event: event, Location: newGeoPoint(event.Coordinates)})
 
 	if err != nil {
 		return "", err
@@ -33,7 +49,7 @@ func (r *Events) CreateEvent(ctx context.Context, event domain.Event) (string, e
 }
 
 func (r *Events) GetEventById(ctx context.Context, id string) (domain.Event, error) {
-	filter := bson.D{{Key: "_id", Value: id}, {Key: "state", Value: domain.EventStateOpened}}
+	filter := bson.D{{Key: "_id", Value: id}, activeStateFilter}
 	event := domain.Event{}
 	err := r.db.FindOne(ctx, filter).Decode(&event)
 
@@ -48,7 +64,7 @@ func (r *Events) GetEventById(ctx context.Context, id string) (domain.Event, err
 }
 
 func (r *Events) GetEventByOwnerId(ctx context.Context, ownerId string) (domain.Event, error) {
-	filter := bson.D{{Key: "owner_id", Value: ownerId}, {Key: "state", Value: domain.EventStateOpened}}
+	filter := bson.D{{Key: "owner_id", Value: ownerId}, activeStateFilter}
 	event := domain.Event{}
 	err := r.db.FindOne(ctx, filter).Decode(&event)
 
@@ -63,20 +79,12 @@ func (r *Events) GetEventByOwnerId(ctx context.Context, ownerId string) (domain.
 }
 
 func (r *Events) GetEventsByRange(ctx context.Context, xLeft float64, xRight float64, yLeft float64, yRight float64) ([]domain.EventRangeData, error) {
-	filter := bson.D{{Key: "$and", Value: bson.A{
-		bson.D{{Key: "state", Value: domain.EventStateOpened}},
-		bson.D{{Key: "coordinates.x", Value: bson.D{{Key: "$gte", Value: xLeft}}}},
-		bson.D{{Key: "coordinates.y", Value: bson.D{{Key: "$gte", Value: yLeft}}}},
-		bson.D{{Key: "coordinates.x", Value: bson.D{{Key: "$lte", Value: xRight}}}},
-		bson.D{{Key: "coordinates.y", Value: bson.D{{Key: "$lte", Value: yRight}}}},
-	}}}
-
-	cursor, err := r.db.Find(ctx, filter, options.Find().SetProjection(bson.D{
-		{Key: "_id", Value: 1},
-		{Key: "name", Value: 1},
-		{Key: "coordinates", Value: 1},
-		{Key: "users_count", Value: bson.D{{Key: "$size", Value: "$users"}}},
-	}))
+	if yLeft >= 90 || yRight <= -90 {
+		return []domain.EventRangeData{}, nil
+	}
+
+	filter := bson.D{activeStateFilter, publicVisibilityFilter, rangeFilter(xLeft, xRight, yLeft, yRight)}
+	cursor, err := r.db.Find(ctx, filter, options.Find().SetProjection(rangeDataProjection))
 
 	if err != nil {
 		return nil, err
@@ -92,12 +100,86 @@ func (r *Events) GetEventsByRange(ctx context.Context, xLeft float64, xRight flo
 	return result, nil
 }
 
+func (r *Events) GetEventsNearby(ctx context.Context, center domain.Coordinates, maxDistance float64) ([]domain.EventRangeData, error) {
+	near := newGeoPoint(center)
+
+	if near == nil {
+		return []domain.EventRangeData{}, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$geoNear", Value: bson.D{
+			{Key: "near", Value: near},
+			{Key: "distanceField", Value: "distance"},
+			{Key: "maxDistance", Value: maxDistance},
+			{Key: "spherical", Value: true},
+			{Key: "query", Value: bson.D{activeStateFilter, publicVisibilityFilter}},
+		}}},
+		{{Key: "$project", Value: append(rangeDataProjection, bson.E{Key: "distance", Value: 1})}},
+	}
+	cursor, err := r.db.Aggregate(ctx, pipeline)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.EventRangeData, 0)
+	err = cursor.All(ctx, &result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Events) GetScheduledEventIds(ctx context.Context, startsBefore time.Time) ([]string, error) {
+	return r.findIds(ctx, bson.D{
+		{Key: "state", Value: domain.EventStateScheduled},
+		{Key: "starts_at", Value: bson.D{{Key: "$lte", Value: startsBefore}}},
+	})
+}
+
+func (r *Events) GetEndedEventIds(ctx context.Context, endsBefore time.Time) ([]string, error) {
+	return r.findIds(ctx, bson.D{
+		activeStateFilter,
+		{Key: "ends_at", Value: bson.D{{Key: "$lte", Value: endsBefore}}},
+	})
+}
+
+func (r *Events) GetIdleEventIds(ctx context.Context, startedBefore time.Time) ([]string, error) {
+	return r.findIds(ctx, bson.D{
+		{Key: "state", Value: domain.EventStateOpened},
+		{Key: "users", Value: bson.D{{Key: "$size", Value: 0}}},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "starts_at", Value: bson.D{{Key: "$lte", Value: startedBefore}}}},
+			bson.D{
+				{Key: "starts_at", Value: bson.D{{Key: "$exists", Value: false}}},
+				{Key: "created_at", Value: bson.D{{Key: "$lte", Value: startedBefore}}},
+			},
+		}},
+	})
+}
+
+func (r *Events) OpenEvent(ctx context.Context, id string) error {
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "state", Value: domain.EventStateScheduled}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "state", Value: domain.EventStateOpened}}}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrEventNotFound
+	}
+
+	return nil
+}
+
 func (r *Events) UpdateEvent(ctx context.Context, id string, address string, coordinates domain.Coordinates) error {
-	filter := bson.D{{Key: "_id", Value: id}, {Key: "state", Value: domain.EventStateOpened}}
-	update := bson.D{{Key: "$set", Value: bson.D{
-		{Key: "address", Value: address},
-		{Key: "coordinates", Value: coordinates},
-	}}}
+	filter := bson.D{{Key: "_id", Value: id}, activeStateFilter}
+	update := coordinatesUpdate(bson.D{{Key: "address", Value: address}}, coordinates)
 	result, err := r.db.UpdateOne(ctx, filter, update)
 
 	if err != nil {
@@ -112,7 +194,7 @@ func (r *Events) UpdateEvent(ctx context.Context, id string, address string, coo
 }
 
 func (r *Events) RemoveEvent(ctx context.Context, id string) error {
-	filter := bson.D{{Key: "_id", Value: id}, {Key: "state", Value: domain.EventStateOpened}}
+	filter := bson.D{{Key: "_id", Value: id}, activeStateFilter}
 	update := bson.D{{Key: "$set", Value: bson.D{{Key: "state", Value: domain.EventStateClosed}}}}
 	result, err := r.db.UpdateOne(ctx, filter, update)
 
@@ -128,7 +210,7 @@ func (r *Events) RemoveEvent(ctx context.Context, id string) error {
 }
 
 func (r *Events) AddMedia(ctx context.Context, id string, mediaInfo domain.MediaInfo) error {
-	filter := bson.D{{Key: "_id", Value: id}, {Key: "state", Value: domain.EventStateOpened}}
+	filter := bson.D{{Key: "_id", Value: id}, activeStateFilter}
 	update := bson.D{{Key: "$push", Value: bson.D{{Key: "media", Value: mediaInfo}}}}
 	result, err := r.db.UpdateOne(ctx, filter, update)
 
@@ -144,7 +226,7 @@ func (r *Events) AddMedia(ctx context.Context, id string, mediaInfo domain.Media
 }
 
 func (r *Events) RemoveMedia(ctx context.Context, eventId string, mediaId string) error {
-	filter := bson.D{{Key: "_id", Value: eventId}, {Key: "state", Value: domain.EventStateOpened}}
+	filter := bson.D{{Key: "_id", Value: eventId}, activeStateFilter}
 	update := bson.D{{Key: "$pull", Value: bson.D{{Key: "media", Value: bson.D{{Key: "_id", Value: mediaId}}}}}}
 	result, err := r.db.UpdateOne(ctx, filter, update)
 
@@ -191,9 +273,156 @@ func (r *Events) RemoveUserInfo(ctx context.Context, eventId string, userId stri
 	return err
 }
 
-func (r *Events) AddChatMessage(ctx context.Context, id string, chatMessage domain.ChatMessage) error {
-	filter := bson.D{{Key: "_id", Value: id}, {Key: "state", Value: domain.EventStateOpened}}
-	update := bson.D{{Key: "$push", Value: bson.D{{Key: "chat_messages", Value: chatMessage}}}}
+func (r *Events) AddMember(ctx context.Context, eventId string, userInfo domain.UserInfo) error {
+	filter := bson.D{{Key: "_id", Value: eventId}, activeStateFilter}
+	update := bson.D{{Key: "$addToSet", Value: bson.D{{Key: "members", Value: userInfo}}}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrEventNotFound
+	}
+
+	return nil
+}
+
+func (r *Events) AddJoinRequest(ctx context.Context, eventId string, joinRequest domain.JoinRequest) error {
+	filter := bson.D{
+		{Key: "_id", Value: eventId},
+		activeStateFilter,
+		{Key: "join_requests._id", Value: bson.D{{Key: "$ne", Value: joinRequest.UserID}}},
+	}
+	update := bson.D{{Key: "$push", Value: bson.D{{Key: "join_requests", Value: joinRequest}}}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	count, err := r.db.CountDocuments(ctx, bson.D{{Key: "_id", Value: eventId}, activeStateFilter})
+
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return domain.ErrEventNotFound
+	}
+
+	return domain.ErrJoinRequestAlreadyExist
+}
+
+func (r *Events) ApproveJoinRequest(ctx context.Context, eventId string, userId string) error {
+	return r.removeJoinRequest(ctx, eventId, userId, bson.D{
+		{Key: "$pull", Value: bson.D{{Key: "join_requests", Value: bson.D{{Key: "_id", Value: userId}}}}},
+		{Key: "$addToSet", Value: bson.D{{Key: "members", Value: domain.UserInfo{ID: userId}}}},
+	})
+}
+
+func (r *Events) RemoveJoinRequest(ctx context.Context, eventId string, userId string) error {
+	return r.removeJoinRequest(ctx, eventId, userId, bson.D{
+		{Key: "$pull", Value: bson.D{{Key: "join_requests", Value: bson.D{{Key: "_id", Value: userId}}}}},
+	})
+}
+
+func (r *Events) SetRole(ctx context.Context, eventId string, roleInfo domain.EventRoleInfo) error {
+	filter := bson.D{{Key: "_id", Value: eventId}, activeStateFilter, {Key: "roles._id", Value: roleInfo.UserID}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "roles.$.role", Value: roleInfo.Role}}}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	filter = bson.D{
+		{Key: "_id", Value: eventId},
+		activeStateFilter,
+		{Key: "roles._id", Value: bson.D{{Key: "$ne", Value: roleInfo.UserID}}},
+	}
+	update = bson.D{{Key: "$push", Value: bson.D{{Key: "roles", Value: roleInfo}}}}
+	result, err = r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrEventNotFound
+	}
+
+	return nil
+}
+
+func (r *Events) RemoveRole(ctx context.Context, eventId string, userId string) error {
+	filter := bson.D{{Key: "_id", Value: eventId}, activeStateFilter, {Key: "roles._id", Value: userId}}
+	update := bson.D{{Key: "$pull", Value: bson.D{{Key: "roles", Value: bson.D{{Key: "_id", Value: userId}}}}}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrRoleNotFound
+	}
+
+	return nil
+}
+
+func (r *Events) SetMute(ctx context.Context, eventId string, mute domain.EventMute) error {
+	filter := bson.D{{Key: "_id", Value: eventId}, activeStateFilter, {Key: "mutes._id", Value: mute.UserID}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "mutes.$.until", Value: mute.Until}}}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	filter = bson.D{
+		{Key: "_id", Value: eventId},
+		activeStateFilter,
+		{Key: "mutes._id", Value: bson.D{{Key: "$ne", Value: mute.UserID}}},
+	}
+	update = bson.D{{Key: "$push", Value: bson.D{{Key: "mutes", Value: mute}}}}
+	result, err = r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrEventNotFound
+	}
+
+	return nil
+}
+
+func (r *Events) BanUser(ctx context.Context, eventId string, userId string) error {
+	filter := bson.D{{Key: "_id", Value: eventId}, activeStateFilter}
+	update := bson.D{
+		{Key: "$addToSet", Value: bson.D{{Key: "banned_users", Value: domain.UserInfo{ID: userId}}}},
+		{Key: "$pull", Value: bson.D{
+			{Key: "users", Value: bson.D{{Key: "_id", Value: userId}}},
+			{Key: "members", Value: bson.D{{Key: "_id", Value: userId}}},
+			{Key: "roles", Value: bson.D{{Key: "_id", Value: userId}}},
+			{Key: "join_requests", Value: bson.D{{Key: "_id", Value: userId}}},
+		}},
+	}
 	result, err := r.db.UpdateOne(ctx, filter, update)
 
 	if err != nil {
@@ -206,3 +435,200 @@ func (r *Events) AddChatMessage(ctx context.Context, id string, chatMessage doma
 
 	return nil
 }
+
+func (r *Events) AddChatMessage(ctx context.Context, id string, chatMessage domain.ChatMessage) (string, error) {
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "state", Value: domain.EventStateOpened}}
+	count, err := r.db.CountDocuments(ctx, filter)
+
+	if err != nil {
+		return "", err
+	}
+
+	if count == 0 {
+		return "", domain.ErrEventNotFound
+	}
+
+	chatMessage.ID = uuid.New().String()
+	chatMessage.EventID = id
+	_, err = r.chatMessages.InsertOne(ctx, chatMessage)
+
+	if err != nil {
+		return "", err
+	}
+
+	return chatMessage.ID, nil
+}
+
+func (r *Events) GetChatMessages(ctx context.Context, eventId string, beforeId string, afterId string, limit int) ([]domain.ChatMessage, error) {
+	filter := bson.D{{Key: "event_id", Value: eventId}}
+	sort := bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	isDescending := true
+
+	switch {
+	case beforeId != "":
+		cursorFilter, err := r.getChatCursorFilter(ctx, eventId, beforeId, "$lt")
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, cursorFilter)
+	case afterId != "":
+		cursorFilter, err := r.getChatCursorFilter(ctx, eventId, afterId, "$gt")
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, cursorFilter)
+		sort = bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}
+		isDescending = false
+	}
+
+	cursor, err := r.chatMessages.Find(ctx, filter, options.Find().SetSort(sort).SetLimit(int64(limit)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.ChatMessage, 0)
+	err = cursor.All(ctx, &result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isDescending {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+
+	return result, nil
+}
+
+func (r *Events) GetChatMessage(ctx context.Context, eventId string, messageId string) (domain.ChatMessage, error) {
+	chatMessage := domain.ChatMessage{}
+	err := r.chatMessages.FindOne(ctx, bson.D{{Key: "_id", Value: messageId}, {Key: "event_id", Value: eventId}}).Decode(&chatMessage)
+
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.ChatMessage{}, domain.ErrChatMessageNotFound
+		}
+		return domain.ChatMessage{}, err
+	}
+
+	return chatMessage, nil
+}
+
+func (r *Events) EditChatMessage(ctx context.Context, eventId string, messageId string, message string, edit domain.ChatMessageEdit) (domain.ChatMessage, error) {
+	filter := bson.D{{Key: "_id", Value: messageId}, {Key: "event_id", Value: eventId}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "message", Value: message}}},
+		{Key: "$push", Value: bson.D{{Key: "edits", Value: edit}}},
+	}
+	return r.findAndUpdateChatMessage(ctx, filter, update)
+}
+
+// ToggleChatReaction removes the reaction if the user has already put it, otherwise adds it.
+func (r *Events) ToggleChatReaction(ctx context.Context, eventId string, messageId string, reaction domain.ChatReaction) (domain.ChatMessage, error) {
+	reactionFilter := bson.D{{Key: "$elemMatch", Value: bson.D{{Key: "user_id", Value: reaction.UserID}, {Key: "emoji", Value: reaction.Emoji}}}}
+	filter := bson.D{{Key: "_id", Value: messageId}, {Key: "event_id", Value: eventId}, {Key: "reactions", Value: reactionFilter}}
+	update := bson.D{{Key: "$pull", Value: bson.D{{Key: "reactions", Value: reaction}}}}
+	chatMessage, err := r.findAndUpdateChatMessage(ctx, filter, update)
+
+	if !errors.Is(err, domain.ErrChatMessageNotFound) {
+		return chatMessage, err
+	}
+
+	filter = bson.D{
+		{Key: "_id", Value: messageId},
+		{Key: "event_id", Value: eventId},
+		{Key: "reactions", Value: bson.D{{Key: "$not", Value: reactionFilter}}},
+	}
+	update = bson.D{{Key: "$push", Value: bson.D{{Key: "reactions", Value: reaction}}}}
+	return r.findAndUpdateChatMessage(ctx, filter, update)
+}
+
+func (r *Events) findAndUpdateChatMessage(ctx context.Context, filter bson.D, update bson.D) (domain.ChatMessage, error) {
+	chatMessage := domain.ChatMessage{}
+	err := r.chatMessages.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&chatMessage)
+
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.ChatMessage{}, domain.ErrChatMessageNotFound
+		}
+		return domain.ChatMessage{}, err
+	}
+
+	return chatMessage, nil
+}
+
+func (r *Events) RemoveChatMessage(ctx context.Context, eventId string, messageId string) error {
+	result, err := r.chatMessages.DeleteOne(ctx, bson.D{{Key: "_id", Value: messageId}, {Key: "event_id", Value: eventId}})
+
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrChatMessageNotFound
+	}
+
+	return nil
+}
+
+func (r *Events) removeJoinRequest(ctx context.Context, eventId string, userId string, update bson.D) error {
+	filter := bson.D{{Key: "_id", Value: eventId}, activeStateFilter, {Key: "join_requests._id", Value: userId}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrJoinRequestNotFound
+	}
+
+	return nil
+}
+
+func (r *Events) findIds(ctx context.Context, filter bson.D) ([]string, error) {
+	cursor, err := r.db.Find(ctx, filter, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var items []struct {
+		ID string `bson:"_id"`
+	}
+
+	if err = cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	result := make([]string, len(items))
+	for i, item := range items {
+		result[i] = item.ID
+	}
+
+	return result, nil
+}
+
+func (r *Events) getChatCursorFilter(ctx context.Context, eventId string, id string, operator string) (bson.E, error) {
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "event_id", Value: eventId}}
+	chatMessage := domain.ChatMessage{}
+	err := r.chatMessages.FindOne(ctx, filter).Decode(&chatMessage)
+
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return bson.E{}, domain.ErrChatMessageNotFound
+		}
+		return bson.E{}, err
+	}
+
+	return bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: "created_at", Value: bson.D{{Key: operator, Value: chatMessage.CreatedAt}}}},
+		bson.D{
+			{Key: "created_at", Value: chatMessage.CreatedAt},
+			{Key: "_id", Value: bson.D{{Key: operator, Value: chatMessage.ID}}},
+		},
+	}}, nil
+}
@@ -0,0 +1,102 @@
+package mongo
+
+import (
+	"context"
+	"math"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const maxRangeSegmentWidth = 90
+
+type geoPoint struct {
+	Type        string    `bson:"type"`
+	Coordinates []float64 `bson:"coordinates"`
+}
+
+// eventDocument stores the event coordinates as a GeoJSON point next to the plain x/y pair,
+// events with coordinates outside of the longitude/latitude bounds are left without location.
+type eventDocument struct {
+	domain.Event `bson:",inline"`
+	Location     *geoPoint `bson:"location,omitempty"`
+}
+
+func newGeoPoint(coordinates domain.Coordinates) *geoPoint {
+	if !coordinates.IsValid() {
+		return nil
+	}
+
+	return &geoPoint{
+		Type:        "Point",
+		Coordinates: []float64{coordinates.X, coordinates.Y},
+	}
+}
+
+func coordinatesUpdate(set bson.D, coordinates domain.Coordinates) bson.D {
+	set = append(set, bson.E{Key: "coordinates", Value: coordinates})
+
+	if location := newGeoPoint(coordinates); location != nil {
+		return bson.D{{Key: "$set", Value: append(set, bson.E{Key: "location", Value: location})}}
+	}
+
+	return bson.D{{Key: "$set", Value: set}, {Key: "$unset", Value: bson.D{{Key: "location", Value: ""}}}}
+}
+
+// rangeFilter splits the longitude range into polygons narrower than a hemisphere,
+// so that the 2dsphere index never picks the complementary area. A range with
+// xLeft greater than xRight crosses the antimeridian.
+func rangeFilter(xLeft float64, xRight float64, yLeft float64, yRight float64) bson.E {
+	width := xRight - xLeft
+
+	if width < 0 {
+		width += 360
+	}
+
+	segments := int(math.Ceil(width / maxRangeSegmentWidth))
+
+	if segments == 0 {
+		segments = 1
+	}
+
+	segmentWidth := width / float64(segments)
+	yLeft = math.Max(yLeft, -90)
+	yRight = math.Min(yRight, 90)
+	polygons := make(bson.A, segments)
+
+	for i := range polygons {
+		left := normalizeLongitude(xLeft + segmentWidth*float64(i))
+		right := normalizeLongitude(xLeft + segmentWidth*float64(i+1))
+		polygons[i] = bson.D{{Key: "location", Value: bson.D{{Key: "$geoWithin", Value: bson.D{{Key: "$geometry", Value: bson.D{
+			{Key: "type", Value: "Polygon"},
+			{Key: "coordinates", Value: bson.A{bson.A{
+				bson.A{left, yLeft}, bson.A{right, yLeft}, bson.A{right, yRight}, bson.A{left, yRight}, bson.A{left, yLeft},
+			}}},
+		}}}}}}}
+	}
+
+	return bson.E{Key: "$or", Value: polygons}
+}
+
+func normalizeLongitude(x float64) float64 {
+	if x > 180 {
+		return x - 360
+	}
+
+	return x
+}
+
+func migrateEventLocations(ctx context.Context, events *mongo.Collection) error {
+	filter := bson.D{
+		{Key: "location", Value: bson.D{{Key: "$exists", Value: false}}},
+		{Key: "coordinates.x", Value: bson.D{{Key: "$gte", Value: -180}, {Key: "$lte", Value: 180}}},
+		{Key: "coordinates.y", Value: bson.D{{Key: "$gte", Value: -90}, {Key: "$lte", Value: 90}}},
+	}
+	update := bson.A{bson.D{{Key: "$set", Value: bson.D{{Key: "location", Value: bson.D{
+		{Key: "type", Value: "Point"},
+		{Key: "coordinates", Value: bson.A{"$coordinates.x", "$coordinates.y"}},
+	}}}}}}
+	_, err := events.UpdateMany(ctx, filter, update)
+	return err
+}
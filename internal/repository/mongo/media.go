@@ -52,6 +52,7 @@ func (r *Media) UpdateMedia(ctx context.Context, media domain.Media) error {
 		{Key: "content_type", Value: media.ContentType},
 		{Key: "size", Value: media.Size},
 		{Key: "last_modified_date", Value: media.LastModifiedDate},
+		{Key: "variants", Value: media.Variants},
 	}}}
 	result, err := r.db.UpdateOne(ctx, filter, update)
 
@@ -0,0 +1,237 @@
+package mongo
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	migrationsLockTimeout = 10 * time.Minute
+	migrationsLockRetry   = 500 * time.Millisecond
+	migrationsLockID      = "lock"
+)
+
+type migration struct {
+	version string
+	apply   func(ctx context.Context, db *mongo.Database) error
+}
+
+// migrations are applied once in this order, the applied versions are stored in schema_migrations.
+var migrations = []migration{
+	{version: "0001_chat_messages_indexes", apply: func(ctx context.Context, db *mongo.Database) error {
+		return createChatMessagesIndexes(ctx, db.Collection("chat_messages"))
+	}},
+	{version: "0002_event_locations", apply: func(ctx context.Context, db *mongo.Database) error {
+		return migrateEventLocations(ctx, db.Collection("events"))
+	}},
+	{version: "0003_event_visibility", apply: func(ctx context.Context, db *mongo.Database) error {
+		return migrateEventVisibility(ctx, db.Collection("events"))
+	}},
+	{version: "0004_events_indexes", apply: func(ctx context.Context, db *mongo.Database) error {
+		return createEventsIndexes(ctx, db.Collection("events"))
+	}},
+	{version: "0005_sessions_indexes", apply: func(ctx context.Context, db *mongo.Database) error {
+		return createSessionsIndexes(ctx, db.Collection("sessions"))
+	}},
+	{version: "0006_otp_codes_indexes", apply: func(ctx context.Context, db *mongo.Database) error {
+		return createOtpCodesIndexes(ctx, db.Collection("otp_codes"))
+	}},
+	{version: "0007_embedded_chat_messages", apply: func(ctx context.Context, db *mongo.Database) error {
+		return migrateEmbeddedChatMessages(ctx, db.Collection("events"), db.Collection("chat_messages"))
+	}},
+	{version: "0008_otp_requests_indexes", apply: func(ctx context.Context, db *mongo.Database) error {
+		return createOtpRequestsIndexes(ctx, db.Collection("otp_requests"))
+	}},
+}
+
+// migrate applies the new migrations holding a lock, so replicas starting together don't apply them twice.
+func migrate(ctx context.Context, db *mongo.Database) error {
+	applied := db.Collection("schema_migrations")
+	locks := db.Collection("schema_migrations_lock")
+	owner := uuid.New().String()
+
+	if err := lockMigrations(ctx, locks, owner); err != nil {
+		return err
+	}
+
+	defer unlockMigrations(locks, owner)
+
+	for _, m := range migrations {
+		count, err := applied.CountDocuments(ctx, bson.D{{Key: "_id", Value: m.version}})
+
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			continue
+		}
+
+		if err = m.apply(ctx, db); err != nil {
+			return err
+		}
+
+		if _, err = applied.InsertOne(ctx, bson.D{{Key: "_id", Value: m.version}, {Key: "applied_at", Value: time.Now()}}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockMigrations takes the lock document or waits until it's released or expired,
+// a taken lock makes the upsert insert a duplicate id.
+func lockMigrations(ctx context.Context, locks *mongo.Collection, owner string) error {
+	for {
+		now := time.Now()
+		filter := bson.D{{Key: "_id", Value: migrationsLockID}, {Key: "expires_at", Value: bson.D{{Key: "$lt", Value: now}}}}
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "owner", Value: owner}, {Key: "expires_at", Value: now.Add(migrationsLockTimeout)}}}}
+		_, err := locks.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+
+		if err == nil {
+			return nil
+		}
+
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(migrationsLockRetry):
+		}
+	}
+}
+
+func unlockMigrations(locks *mongo.Collection, owner string) {
+	_, _ = locks.DeleteOne(context.Background(), bson.D{{Key: "_id", Value: migrationsLockID}, {Key: "owner", Value: owner}})
+}
+
+func createChatMessagesIndexes(ctx context.Context, chatMessages *mongo.Collection) error {
+	_, err := chatMessages.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "event_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	return err
+}
+
+func createEventsIndexes(ctx context.Context, events *mongo.Collection) error {
+	_, err := events.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "state", Value: 1}, {Key: "starts_at", Value: 1}}},
+		{Keys: bson.D{{Key: "state", Value: 1}, {Key: "ends_at", Value: 1}}},
+		{Keys: bson.D{{Key: "location", Value: "2dsphere"}}},
+	})
+	return err
+}
+
+func createSessionsIndexes(ctx context.Context, sessions *mongo.Collection) error {
+	_, err := sessions.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "refresh_token", Value: 1}}},
+	})
+	return err
+}
+
+func createOtpCodesIndexes(ctx context.Context, otpCodes *mongo.Collection) error {
+	_, err := otpCodes.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "phone", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(24 * 60 * 60)},
+	})
+	return err
+}
+
+func createOtpRequestsIndexes(ctx context.Context, otpRequests *mongo.Collection) error {
+	_, err := otpRequests.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "last_request_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(24 * 60 * 60),
+	})
+	return err
+}
+
+func migrateEventVisibility(ctx context.Context, events *mongo.Collection) error {
+	filter := bson.D{{Key: "visibility", Value: bson.D{{Key: "$exists", Value: false}}}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "visibility", Value: domain.EventVisibilityPublic}}}}
+	_, err := events.UpdateMany(ctx, filter, update)
+	return err
+}
+
+type embeddedChatMessages struct {
+	ID           string               `bson:"_id"`
+	CreatedAt    time.Time            `bson:"created_at"`
+	ChatMessages []domain.ChatMessage `bson:"chat_messages"`
+}
+
+// migrateEmbeddedChatMessages moves chat messages that were stored inside event documents
+// into the chat_messages collection, keeping their original order. Message ids are derived
+// from the event and the position, so a migration interrupted halfway doesn't copy messages twice.
+func migrateEmbeddedChatMessages(ctx context.Context, events *mongo.Collection, chatMessages *mongo.Collection) error {
+	filter := bson.D{{Key: "chat_messages", Value: bson.D{{Key: "$exists", Value: true}}}}
+	cursor, err := events.Find(ctx, filter)
+
+	if err != nil {
+		return err
+	}
+
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		event := embeddedChatMessages{}
+
+		if err = cursor.Decode(&event); err != nil {
+			return err
+		}
+
+		documents := make([]interface{}, len(event.ChatMessages))
+
+		for i, chatMessage := range event.ChatMessages {
+			chatMessage.ID = embeddedChatMessageId(event.ID, i)
+			chatMessage.EventID = event.ID
+			chatMessage.CreatedAt = event.CreatedAt.Add(time.Duration(i) * time.Millisecond)
+			documents[i] = chatMessage
+		}
+
+		if len(documents) > 0 {
+			_, err = chatMessages.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+
+			if err != nil && !isOnlyDuplicateKeyError(err) {
+				return err
+			}
+		}
+
+		update := bson.D{{Key: "$unset", Value: bson.D{{Key: "chat_messages", Value: ""}}}}
+		if _, err = events.UpdateByID(ctx, event.ID, update); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func embeddedChatMessageId(eventId string, position int) string {
+	return uuid.NewHash(sha1.New(), uuid.NameSpaceOID, []byte(eventId+"/"+strconv.Itoa(position)), 4).String()
+}
+
+func isOnlyDuplicateKeyError(err error) bool {
+	var bulkErr mongo.BulkWriteException
+
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return false
+	}
+
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != 11000 {
+			return false
+		}
+	}
+
+	return true
+}
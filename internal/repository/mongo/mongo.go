@@ -8,25 +8,29 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func NewRepositories(connectionString string, dbName string) (*Media, *Users, *Events, *TestsCleaner, error) {
+func NewRepositories(connectionString string, dbName string) (*Media, *Users, *Sessions, *OtpCodes, *Events, *TestsCleaner, error) {
 	client, err := mongo.NewClient(options.Client().ApplyURI(connectionString))
 
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err = client.Connect(ctx); err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, err
 	}
 
 	if err = client.Ping(context.Background(), nil); err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, err
 	}
 
 	db := client.Database(dbName)
 
-	return newMedia(db, "media"), newUsers(db, "users"), newEvents(db, "events"), newTestsCleaner(db), nil
+	if err = migrate(context.Background(), db); err != nil {
+		return nil, nil, nil, nil, nil, nil, err
+	}
+
+	return newMedia(db, "media"), newUsers(db, "users"), newSessions(db, "sessions"), newOtpCodes(db, "otp_codes", "otp_requests"), newEvents(db, "events", "chat_messages"), newTestsCleaner(db), nil
 }
@@ -0,0 +1,118 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OtpCodes struct {
+	db       *mongo.Collection
+	requests *mongo.Collection
+}
+
+func newOtpCodes(db *mongo.Database, collectionName string, requestsCollectionName string) *OtpCodes {
+	return &OtpCodes{
+		db:       db.Collection(collectionName),
+		requests: db.Collection(requestsCollectionName),
+	}
+}
+
+func (r *OtpCodes) CreateOtpCode(ctx context.Context, code domain.OtpCode) (string, error) {
+	code.ID = uuid.New().String()
+	_, err := r.db.InsertOne(ctx, code)
+
+	if err != nil {
+		return "", err
+	}
+
+	return code.ID, nil
+}
+
+func (r *OtpCodes) GetLastOtpCode(ctx context.Context, phone string) (domain.OtpCode, error) {
+	filter := bson.D{{Key: "phone", Value: phone}}
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	model := domain.OtpCode{}
+
+	if err := r.db.FindOne(ctx, filter, opts).Decode(&model); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model, domain.ErrOtpCodeNotFound
+		}
+		return model, err
+	}
+
+	return model, nil
+}
+
+// AddOtpRequest keeps one counter document per phone. The filter only matches a counter that may grow,
+// otherwise the upsert inserts a duplicate id, which means the limit is exceeded.
+func (r *OtpCodes) AddOtpRequest(ctx context.Context, phone string, now time.Time, limit domain.OtpRequestsLimit) error {
+	windowEnded := now.Add(-limit.Window)
+	filter := bson.D{{Key: "_id", Value: phone}, {Key: "$or", Value: bson.A{
+		bson.D{{Key: "window_start", Value: bson.D{{Key: "$lte", Value: windowEnded}}}},
+		bson.D{
+			{Key: "count", Value: bson.D{{Key: "$lt", Value: limit.Count}}},
+			{Key: "last_request_at", Value: bson.D{{Key: "$lte", Value: now.Add(-limit.Interval)}}},
+		},
+	}}}
+	isNewWindow := bson.D{{Key: "$lte", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$window_start", time.Time{}}}}, windowEnded}}}
+	update := bson.A{bson.D{{Key: "$set", Value: bson.D{
+		{Key: "count", Value: bson.D{{Key: "$cond", Value: bson.A{isNewWindow, 1, bson.D{{Key: "$add", Value: bson.A{"$count", 1}}}}}}},
+		{Key: "window_start", Value: bson.D{{Key: "$cond", Value: bson.A{isNewWindow, now, "$window_start"}}}},
+		{Key: "last_request_at", Value: now},
+	}}}}
+	_, err := r.requests.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrOtpRequestsLimitExceeded
+	}
+
+	return err
+}
+
+func (r *OtpCodes) IncrementOtpAttempts(ctx context.Context, id string, maxAttempts int) error {
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "attempts", Value: bson.D{{Key: "$lt", Value: maxAttempts}}}}
+	update := bson.D{{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	count, err := r.db.CountDocuments(ctx, bson.D{{Key: "_id", Value: id}})
+
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return domain.ErrOtpCodeNotFound
+	}
+
+	return domain.ErrOtpAttemptsLimitExceeded
+}
+
+func (r *OtpCodes) RemoveOtpCode(ctx context.Context, id string) error {
+	filter := bson.D{{Key: "_id", Value: id}}
+	result, err := r.db.DeleteOne(ctx, filter)
+
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrOtpCodeNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Sessions struct {
+	db *mongo.Collection
+}
+
+func newSessions(db *mongo.Database, collectionName string) *Sessions {
+	return &Sessions{
+		db: db.Collection(collectionName),
+	}
+}
+
+func (r *Sessions) CreateSession(ctx context.Context, session domain.Session) (string, error) {
+	session.ID = uuid.New().String()
+	_, err := r.db.InsertOne(ctx, session)
+
+	if err != nil {
+		return "", err
+	}
+
+	return session.ID, nil
+}
+
+func (r *Sessions) GetSessionByID(ctx context.Context, id string) (domain.Session, error) {
+	return r.findOne(ctx, bson.D{{Key: "_id", Value: id}})
+}
+
+func (r *Sessions) GetSessionByRefreshToken(ctx context.Context, refreshToken string) (domain.Session, error) {
+	return r.findOne(ctx, bson.D{{Key: "refresh_token", Value: refreshToken}})
+}
+
+func (r *Sessions) GetUserSessions(ctx context.Context, userId string) ([]domain.Session, error) {
+	filter := bson.D{{Key: "user_id", Value: userId}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.db.Find(ctx, filter, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := []domain.Session{}
+
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Sessions) UpdateRefreshToken(ctx context.Context, id string, oldRefreshToken string, newRefreshToken string, lastUsedAt time.Time, expiresAt time.Time) error {
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "refresh_token", Value: oldRefreshToken}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "refresh_token", Value: newRefreshToken},
+		{Key: "last_used_at", Value: lastUsedAt},
+		{Key: "expires_at", Value: expiresAt},
+	}}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (r *Sessions) RemoveSession(ctx context.Context, id string) error {
+	filter := bson.D{{Key: "_id", Value: id}}
+	result, err := r.db.DeleteOne(ctx, filter)
+
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (r *Sessions) RemoveUserSessions(ctx context.Context, userId string) error {
+	filter := bson.D{{Key: "user_id", Value: userId}}
+	_, err := r.db.DeleteMany(ctx, filter)
+	return err
+}
+
+func (r *Sessions) findOne(ctx context.Context, filter bson.D) (domain.Session, error) {
+	model := domain.Session{}
+
+	if err := r.db.FindOne(ctx, filter).Decode(&model); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model, domain.ErrSessionNotFound
+		}
+		return model, err
+	}
+
+	return model, nil
+}
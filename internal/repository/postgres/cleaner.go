@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+)
+
+type TestsCleaner struct {
+	db *sql.DB
+}
+
+func newTestsCleaner(db *sql.DB) *TestsCleaner {
+	return &TestsCleaner{
+		db: db,
+	}
+}
+
+func (c *TestsCleaner) Clean() error {
+	_, err := c.db.ExecContext(context.Background(), `TRUNCATE users, sessions, otp_codes, otp_requests, media, events, event_users, event_media, event_members, event_join_requests, event_roles, event_mutes, event_bans, chat_messages`)
+	return err
+}
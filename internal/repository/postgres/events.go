@@ -0,0 +1,825 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+const eventColumns = `SELECT id, owner_id, name, address, state, visibility, x, y, created_at, starts_at, ends_at FROM events`
+
+const chatMessageColumns = `id, event_id, user_id, user_name, user_image_id, message, created_at, reply_to_id, attachments, reactions, edits`
+
+type Events struct {
+	db *sql.DB
+}
+
+func newEvents(db *sql.DB) *Events {
+	return &Events{
+		db: db,
+	}
+}
+
+func (r *Events) CreateEvent(ctx context.Context, event domain.Event) (string, error) {
+	event.ID = uuid.New().String()
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback()
+	query := `INSERT INTO events (id, owner_id, name, address, state, visibility, x, y, created_at, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err = tx.ExecContext(ctx, query, event.ID, event.OwnerID, event.Name, event.Address, event.State, event.Visibility,
+		event.Coordinates.X, event.Coordinates.Y, event.CreatedAt, event.StartsAt, event.EndsAt)
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, userInfo := range event.Users {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO event_users (event_id, user_id) VALUES ($1, $2)`, event.ID, userInfo.ID); err != nil {
+			return "", err
+		}
+	}
+
+	for _, mediaInfo := range event.Media {
+		query = `INSERT INTO event_media (event_id, media_id, content_type) VALUES ($1, $2, $3)`
+		if _, err = tx.ExecContext(ctx, query, event.ID, mediaInfo.ID, mediaInfo.ContentType); err != nil {
+			return "", err
+		}
+	}
+
+	for _, userInfo := range event.Members {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO event_members (event_id, user_id) VALUES ($1, $2)`, event.ID, userInfo.ID); err != nil {
+			return "", err
+		}
+	}
+
+	for _, roleInfo := range event.Roles {
+		query = `INSERT INTO event_roles (event_id, user_id, role) VALUES ($1, $2, $3)`
+		if _, err = tx.ExecContext(ctx, query, event.ID, roleInfo.UserID, roleInfo.Role); err != nil {
+			return "", err
+		}
+	}
+
+	for _, mute := range event.Mutes {
+		query = `INSERT INTO event_mutes (event_id, user_id, until) VALUES ($1, $2, $3)`
+		if _, err = tx.ExecContext(ctx, query, event.ID, mute.UserID, mute.Until); err != nil {
+			return "", err
+		}
+	}
+
+	for _, userInfo := range event.BannedUsers {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO event_bans (event_id, user_id) VALUES ($1, $2)`, event.ID, userInfo.ID); err != nil {
+			return "", err
+		}
+	}
+
+	for _, joinRequest := range event.JoinRequests {
+		query = `INSERT INTO event_join_requests (event_id, user_id, user_name, user_image_id, created_at) VALUES ($1, $2, $3, $4, $5)`
+		if _, err = tx.ExecContext(ctx, query, event.ID, joinRequest.UserID, joinRequest.UserName, joinRequest.UserImageID, joinRequest.CreatedAt); err != nil {
+			return "", err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return event.ID, nil
+}
+
+func (r *Events) GetEventById(ctx context.Context, id string) (domain.Event, error) {
+	return r.getEvent(ctx, eventColumns+` WHERE id = $1 AND state <> $2`, id)
+}
+
+func (r *Events) GetEventByOwnerId(ctx context.Context, ownerId string) (domain.Event, error) {
+	return r.getEvent(ctx, eventColumns+` WHERE owner_id = $1 AND state <> $2 ORDER BY seq LIMIT 1`, ownerId)
+}
+
+func (r *Events) GetEventsByRange(ctx context.Context, xLeft float64, xRight float64, yLeft float64, yRight float64) ([]domain.EventRangeData, error) {
+	query := `SELECT e.id, e.name, e.state, e.starts_at, e.x, e.y, (SELECT count(*) FROM event_users u WHERE u.event_id = e.id)
+		FROM events e
+		WHERE e.state <> $1 AND e.visibility = $6 AND e.y >= $3 AND e.y <= $5
+		AND e.x BETWEEN -180 AND 180 AND e.y BETWEEN -90 AND 90
+		AND CASE WHEN $2::float8 <= $4::float8 THEN e.x >= $2 AND e.x <= $4 ELSE e.x >= $2 OR e.x <= $4 END
+		ORDER BY e.seq`
+	rows, err := r.db.QueryContext(ctx, query, domain.EventStateClosed, xLeft, yLeft, xRight, yRight, domain.EventVisibilityPublic)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]domain.EventRangeData, 0)
+
+	for rows.Next() {
+		item := domain.EventRangeData{}
+
+		if err = rows.Scan(&item.ID, &item.Name, &item.State, &item.StartsAt, &item.Coordinates.X, &item.Coordinates.Y, &item.UsersCount); err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Events) GetEventsNearby(ctx context.Context, center domain.Coordinates, maxDistance float64) ([]domain.EventRangeData, error) {
+	query := `SELECT id, name, state, starts_at, x, y, users_count, distance FROM (
+			SELECT e.id, e.name, e.state, e.starts_at, e.x, e.y, e.seq,
+				(SELECT count(*) FROM event_users u WHERE u.event_id = e.id) AS users_count,
+				2 * $4::float8 * asin(least(1, sqrt(
+					power(sin(radians(e.y - $3::float8) / 2), 2) +
+					cos(radians($3::float8)) * cos(radians(e.y)) * power(sin(radians(e.x - $2::float8) / 2), 2)
+				))) AS distance
+			FROM events e
+			WHERE e.state <> $1 AND e.visibility = $7 AND e.y >= $3::float8 - $6::float8 AND e.y <= $3::float8 + $6::float8
+			AND e.x BETWEEN -180 AND 180 AND e.y BETWEEN -90 AND 90
+		) nearby
+		WHERE distance <= $5
+		ORDER BY distance, seq`
+	latitudeDelta := maxDistance / domain.EarthRadiusMeters * 180 / math.Pi
+	rows, err := r.db.QueryContext(ctx, query, domain.EventStateClosed, center.X, center.Y,
+		domain.EarthRadiusMeters, maxDistance, latitudeDelta, domain.EventVisibilityPublic)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]domain.EventRangeData, 0)
+
+	for rows.Next() {
+		item := domain.EventRangeData{}
+
+		if err = rows.Scan(&item.ID, &item.Name, &item.State, &item.StartsAt, &item.Coordinates.X, &item.Coordinates.Y,
+			&item.UsersCount, &item.Distance); err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Events) GetScheduledEventIds(ctx context.Context, startsBefore time.Time) ([]string, error) {
+	return r.getIds(ctx, `SELECT id FROM events WHERE state = $1 AND starts_at <= $2 ORDER BY seq`, domain.EventStateScheduled, startsBefore)
+}
+
+func (r *Events) GetEndedEventIds(ctx context.Context, endsBefore time.Time) ([]string, error) {
+	return r.getIds(ctx, `SELECT id FROM events WHERE state <> $1 AND ends_at <= $2 ORDER BY seq`, domain.EventStateClosed, endsBefore)
+}
+
+func (r *Events) GetIdleEventIds(ctx context.Context, startedBefore time.Time) ([]string, error) {
+	query := `SELECT e.id FROM events e
+		WHERE e.state = $1 AND COALESCE(e.starts_at, e.created_at) <= $2
+		AND NOT EXISTS (SELECT 1 FROM event_users u WHERE u.event_id = e.id)
+		ORDER BY e.seq`
+	return r.getIds(ctx, query, domain.EventStateOpened, startedBefore)
+}
+
+func (r *Events) OpenEvent(ctx context.Context, id string) error {
+	query := `UPDATE events SET state = $3 WHERE id = $1 AND state = $2`
+	result, err := r.db.ExecContext(ctx, query, id, domain.EventStateScheduled, domain.EventStateOpened)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrEventNotFound)
+}
+
+func (r *Events) UpdateEvent(ctx context.Context, id string, address string, coordinates domain.Coordinates) error {
+	query := `UPDATE events SET address = $3, x = $4, y = $5 WHERE id = $1 AND state <> $2`
+	result, err := r.db.ExecContext(ctx, query, id, domain.EventStateClosed, address, coordinates.X, coordinates.Y)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrEventNotFound)
+}
+
+func (r *Events) RemoveEvent(ctx context.Context, id string) error {
+	query := `UPDATE events SET state = $2 WHERE id = $1 AND state <> $2`
+	result, err := r.db.ExecContext(ctx, query, id, domain.EventStateClosed)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrEventNotFound)
+}
+
+func (r *Events) AddMedia(ctx context.Context, id string, mediaInfo domain.MediaInfo) error {
+	query := `INSERT INTO event_media (event_id, media_id, content_type)
+		SELECT id, $3, $4 FROM events WHERE id = $1 AND state <> $2`
+	result, err := r.db.ExecContext(ctx, query, id, domain.EventStateClosed, mediaInfo.ID, mediaInfo.ContentType)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrEventNotFound)
+}
+
+func (r *Events) RemoveMedia(ctx context.Context, eventId string, mediaId string) error {
+	query := `WITH e AS (SELECT id FROM events WHERE id = $1 AND state <> $2),
+		d AS (DELETE FROM event_media WHERE event_id IN (SELECT id FROM e) AND media_id = $3)
+		SELECT count(*) FROM e`
+	return r.execOnOpenedEvent(ctx, query, eventId, domain.EventStateClosed, mediaId)
+}
+
+func (r *Events) AddUserInfo(ctx context.Context, eventId string, userInfo domain.UserInfo) error {
+	query := `INSERT INTO event_users (event_id, user_id)
+		SELECT id, $3 FROM events WHERE id = $1 AND state = $2`
+	result, err := r.db.ExecContext(ctx, query, eventId, domain.EventStateOpened, userInfo.ID)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrEventNotFound)
+}
+
+func (r *Events) RemoveUserInfo(ctx context.Context, eventId string, userId string) error {
+	query := `WITH e AS (SELECT id FROM events WHERE id = $1 AND state = $2),
+		d AS (DELETE FROM event_users WHERE event_id IN (SELECT id FROM e) AND user_id = $3)
+		SELECT count(*) FROM e`
+	return r.execOnOpenedEvent(ctx, query, eventId, domain.EventStateOpened, userId)
+}
+
+func (r *Events) AddMember(ctx context.Context, eventId string, userInfo domain.UserInfo) error {
+	query := `WITH e AS (SELECT id FROM events WHERE id = $1 AND state <> $2),
+		i AS (INSERT INTO event_members (event_id, user_id) SELECT id, $3 FROM e ON CONFLICT DO NOTHING)
+		SELECT count(*) FROM e`
+	return r.execOnOpenedEvent(ctx, query, eventId, domain.EventStateClosed, userInfo.ID)
+}
+
+func (r *Events) AddJoinRequest(ctx context.Context, eventId string, joinRequest domain.JoinRequest) error {
+	query := `WITH e AS (SELECT id FROM events WHERE id = $1 AND state <> $2),
+		i AS (INSERT INTO event_join_requests (event_id, user_id, user_name, user_image_id, created_at)
+			SELECT id, $3, $4, $5, $6 FROM e ON CONFLICT DO NOTHING RETURNING 1)
+		SELECT (SELECT count(*) FROM e), (SELECT count(*) FROM i)`
+	var events, inserted int
+	err := r.db.QueryRowContext(ctx, query, eventId, domain.EventStateClosed, joinRequest.UserID, joinRequest.UserName,
+		joinRequest.UserImageID, joinRequest.CreatedAt).Scan(&events, &inserted)
+
+	if err != nil {
+		return err
+	}
+
+	if events == 0 {
+		return domain.ErrEventNotFound
+	}
+
+	if inserted == 0 {
+		return domain.ErrJoinRequestAlreadyExist
+	}
+
+	return nil
+}
+
+func (r *Events) ApproveJoinRequest(ctx context.Context, eventId string, userId string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = removeJoinRequest(ctx, tx, eventId, userId); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO event_members (event_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	if _, err = tx.ExecContext(ctx, query, eventId, userId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Events) RemoveJoinRequest(ctx context.Context, eventId string, userId string) error {
+	return removeJoinRequest(ctx, r.db, eventId, userId)
+}
+
+func (r *Events) SetRole(ctx context.Context, eventId string, roleInfo domain.EventRoleInfo) error {
+	query := `WITH e AS (SELECT id FROM events WHERE id = $1 AND state <> $2),
+		i AS (INSERT INTO event_roles (event_id, user_id, role) SELECT id, $3, $4 FROM e
+			ON CONFLICT (event_id, user_id) DO UPDATE SET role = EXCLUDED.role)
+		SELECT count(*) FROM e`
+	return r.execOnOpenedEvent(ctx, query, eventId, domain.EventStateClosed, roleInfo.UserID, roleInfo.Role)
+}
+
+func (r *Events) RemoveRole(ctx context.Context, eventId string, userId string) error {
+	query := `DELETE FROM event_roles r USING events e
+		WHERE r.event_id = e.id AND e.id = $1 AND e.state <> $2 AND r.user_id = $3`
+	result, err := r.db.ExecContext(ctx, query, eventId, domain.EventStateClosed, userId)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrRoleNotFound)
+}
+
+func (r *Events) SetMute(ctx context.Context, eventId string, mute domain.EventMute) error {
+	query := `WITH e AS (SELECT id FROM events WHERE id = $1 AND state <> $2),
+		i AS (INSERT INTO event_mutes (event_id, user_id, until) SELECT id, $3, $4 FROM e
+			ON CONFLICT (event_id, user_id) DO UPDATE SET until = EXCLUDED.until)
+		SELECT count(*) FROM e`
+	return r.execOnOpenedEvent(ctx, query, eventId, domain.EventStateClosed, mute.UserID, mute.Until)
+}
+
+func (r *Events) BanUser(ctx context.Context, eventId string, userId string) error {
+	query := `WITH e AS (SELECT id FROM events WHERE id = $1 AND state <> $2),
+		i AS (INSERT INTO event_bans (event_id, user_id) SELECT id, $3 FROM e ON CONFLICT DO NOTHING),
+		u AS (DELETE FROM event_users WHERE event_id IN (SELECT id FROM e) AND user_id = $3),
+		m AS (DELETE FROM event_members WHERE event_id IN (SELECT id FROM e) AND user_id = $3),
+		r AS (DELETE FROM event_roles WHERE event_id IN (SELECT id FROM e) AND user_id = $3),
+		j AS (DELETE FROM event_join_requests WHERE event_id IN (SELECT id FROM e) AND user_id = $3)
+		SELECT count(*) FROM e`
+	return r.execOnOpenedEvent(ctx, query, eventId, domain.EventStateClosed, userId)
+}
+
+func (r *Events) AddChatMessage(ctx context.Context, id string, chatMessage domain.ChatMessage) (string, error) {
+	chatMessage.ID = uuid.New().String()
+	attachments, err := marshalList(chatMessage.Attachments)
+
+	if err != nil {
+		return "", err
+	}
+
+	reactions, err := marshalList(chatMessage.Reactions)
+
+	if err != nil {
+		return "", err
+	}
+
+	edits, err := marshalList(chatMessage.Edits)
+
+	if err != nil {
+		return "", err
+	}
+
+	query := `INSERT INTO chat_messages (` + chatMessageColumns + `)
+		SELECT $3, id, $4, $5, $6, $7, $8, $9, $10, $11, $12 FROM events WHERE id = $1 AND state = $2`
+	result, err := r.db.ExecContext(ctx, query, id, domain.EventStateOpened, chatMessage.ID, chatMessage.UserID, chatMessage.UserName,
+		chatMessage.UserImageID, chatMessage.Message, chatMessage.CreatedAt, chatMessage.ReplyToID, attachments, reactions, edits)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err = checkAffected(result, domain.ErrEventNotFound); err != nil {
+		return "", err
+	}
+
+	return chatMessage.ID, nil
+}
+
+func (r *Events) GetChatMessages(ctx context.Context, eventId string, beforeId string, afterId string, limit int) ([]domain.ChatMessage, error) {
+	const columns = `SELECT ` + chatMessageColumns + ` FROM chat_messages`
+	var rows *sql.Rows
+	var err error
+	isDescending := true
+
+	switch {
+	case beforeId != "":
+		createdAt, cursorErr := r.getChatMessageCreatedAt(ctx, eventId, beforeId)
+		if cursorErr != nil {
+			return nil, cursorErr
+		}
+		rows, err = r.db.QueryContext(ctx, columns+` WHERE event_id = $1 AND (created_at, id COLLATE "C") < ($2, $3)
+			ORDER BY created_at DESC, id COLLATE "C" DESC LIMIT $4`, eventId, createdAt, beforeId, limit)
+	case afterId != "":
+		createdAt, cursorErr := r.getChatMessageCreatedAt(ctx, eventId, afterId)
+		if cursorErr != nil {
+			return nil, cursorErr
+		}
+		isDescending = false
+		rows, err = r.db.QueryContext(ctx, columns+` WHERE event_id = $1 AND (created_at, id COLLATE "C") > ($2, $3)
+			ORDER BY created_at, id COLLATE "C" LIMIT $4`, eventId, createdAt, afterId, limit)
+	default:
+		rows, err = r.db.QueryContext(ctx, columns+` WHERE event_id = $1
+			ORDER BY created_at DESC, id COLLATE "C" DESC LIMIT $2`, eventId, limit)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]domain.ChatMessage, 0)
+
+	for rows.Next() {
+		item, scanErr := scanChatMessage(rows)
+
+		if scanErr != nil {
+			return nil, scanErr
+		}
+
+		result = append(result, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if isDescending {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+
+	return result, nil
+}
+
+func (r *Events) GetChatMessage(ctx context.Context, eventId string, messageId string) (domain.ChatMessage, error) {
+	query := `SELECT ` + chatMessageColumns + ` FROM chat_messages WHERE id = $1 AND event_id = $2`
+	return r.queryChatMessage(ctx, query, messageId, eventId)
+}
+
+func (r *Events) EditChatMessage(ctx context.Context, eventId string, messageId string, message string, edit domain.ChatMessageEdit) (domain.ChatMessage, error) {
+	edits, err := json.Marshal([]domain.ChatMessageEdit{edit})
+
+	if err != nil {
+		return domain.ChatMessage{}, err
+	}
+
+	query := `UPDATE chat_messages SET message = $3, edits = edits || $4::jsonb
+		WHERE id = $1 AND event_id = $2 RETURNING ` + chatMessageColumns
+	return r.queryChatMessage(ctx, query, messageId, eventId, message, string(edits))
+}
+
+// ToggleChatReaction removes the reaction if the user has already put it, otherwise adds it.
+func (r *Events) ToggleChatReaction(ctx context.Context, eventId string, messageId string, reaction domain.ChatReaction) (domain.ChatMessage, error) {
+	item, err := json.Marshal(reaction)
+
+	if err != nil {
+		return domain.ChatMessage{}, err
+	}
+
+	query := `UPDATE chat_messages SET reactions = CASE
+			WHEN reactions @> jsonb_build_array($3::jsonb) THEN (SELECT coalesce(jsonb_agg(item), '[]'::jsonb)
+				FROM jsonb_array_elements(reactions) item WHERE item <> $3::jsonb)
+			ELSE reactions || jsonb_build_array($3::jsonb) END
+		WHERE id = $1 AND event_id = $2 RETURNING ` + chatMessageColumns
+	return r.queryChatMessage(ctx, query, messageId, eventId, string(item))
+}
+
+func (r *Events) queryChatMessage(ctx context.Context, query string, args ...interface{}) (domain.ChatMessage, error) {
+	chatMessage, err := scanChatMessage(r.db.QueryRowContext(ctx, query, args...))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ChatMessage{}, domain.ErrChatMessageNotFound
+		}
+		return domain.ChatMessage{}, err
+	}
+
+	return chatMessage, nil
+}
+
+func (r *Events) RemoveChatMessage(ctx context.Context, eventId string, messageId string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM chat_messages WHERE id = $1 AND event_id = $2`, messageId, eventId)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrChatMessageNotFound)
+}
+
+func (r *Events) getChatMessageCreatedAt(ctx context.Context, eventId string, id string) (time.Time, error) {
+	var createdAt time.Time
+	err := r.db.QueryRowContext(ctx, `SELECT created_at FROM chat_messages WHERE id = $1 AND event_id = $2`, id, eventId).Scan(&createdAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, domain.ErrChatMessageNotFound
+		}
+		return time.Time{}, err
+	}
+
+	return createdAt, nil
+}
+
+func (r *Events) getEvent(ctx context.Context, query string, key string) (domain.Event, error) {
+	event := domain.Event{}
+	err := r.db.QueryRowContext(ctx, query, key, domain.EventStateClosed).Scan(&event.ID, &event.OwnerID, &event.Name,
+		&event.Address, &event.State, &event.Visibility, &event.Coordinates.X, &event.Coordinates.Y, &event.CreatedAt, &event.StartsAt, &event.EndsAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Event{}, domain.ErrEventNotFound
+		}
+		return domain.Event{}, err
+	}
+
+	if event.Users, err = r.getUsers(ctx, event.ID); err != nil {
+		return domain.Event{}, err
+	}
+
+	if event.Media, err = r.getMedia(ctx, event.ID); err != nil {
+		return domain.Event{}, err
+	}
+
+	if event.Members, err = r.getMembers(ctx, event.ID); err != nil {
+		return domain.Event{}, err
+	}
+
+	if event.JoinRequests, err = r.getJoinRequests(ctx, event.ID); err != nil {
+		return domain.Event{}, err
+	}
+
+	if event.Roles, err = r.getRoles(ctx, event.ID); err != nil {
+		return domain.Event{}, err
+	}
+
+	if event.Mutes, err = r.getMutes(ctx, event.ID); err != nil {
+		return domain.Event{}, err
+	}
+
+	if event.BannedUsers, err = r.getBannedUsers(ctx, event.ID); err != nil {
+		return domain.Event{}, err
+	}
+
+	return event, nil
+}
+
+func (r *Events) getIds(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]string, 0)
+
+	for rows.Next() {
+		var id string
+
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		result = append(result, id)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Events) getUsers(ctx context.Context, eventId string) ([]domain.UserInfo, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id FROM event_users WHERE event_id = $1 ORDER BY seq`, eventId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]domain.UserInfo, 0)
+
+	for rows.Next() {
+		item := domain.UserInfo{}
+
+		if err = rows.Scan(&item.ID); err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Events) getMedia(ctx context.Context, eventId string) ([]domain.MediaInfo, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT media_id, content_type FROM event_media WHERE event_id = $1 ORDER BY seq`, eventId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]domain.MediaInfo, 0)
+
+	for rows.Next() {
+		item := domain.MediaInfo{}
+
+		if err = rows.Scan(&item.ID, &item.ContentType); err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Events) getMembers(ctx context.Context, eventId string) ([]domain.UserInfo, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id FROM event_members WHERE event_id = $1 ORDER BY seq`, eventId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]domain.UserInfo, 0)
+
+	for rows.Next() {
+		item := domain.UserInfo{}
+
+		if err = rows.Scan(&item.ID); err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Events) getJoinRequests(ctx context.Context, eventId string) ([]domain.JoinRequest, error) {
+	query := `SELECT user_id, user_name, user_image_id, created_at FROM event_join_requests WHERE event_id = $1 ORDER BY seq`
+	rows, err := r.db.QueryContext(ctx, query, eventId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]domain.JoinRequest, 0)
+
+	for rows.Next() {
+		item := domain.JoinRequest{}
+
+		if err = rows.Scan(&item.UserID, &item.UserName, &item.UserImageID, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Events) getRoles(ctx context.Context, eventId string) ([]domain.EventRoleInfo, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id, role FROM event_roles WHERE event_id = $1 ORDER BY seq`, eventId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]domain.EventRoleInfo, 0)
+
+	for rows.Next() {
+		item := domain.EventRoleInfo{}
+
+		if err = rows.Scan(&item.UserID, &item.Role); err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Events) getBannedUsers(ctx context.Context, eventId string) ([]domain.UserInfo, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id FROM event_bans WHERE event_id = $1 ORDER BY seq`, eventId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]domain.UserInfo, 0)
+
+	for rows.Next() {
+		item := domain.UserInfo{}
+
+		if err = rows.Scan(&item.ID); err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Events) getMutes(ctx context.Context, eventId string) ([]domain.EventMute, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id, until FROM event_mutes WHERE event_id = $1 ORDER BY seq`, eventId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := make([]domain.EventMute, 0)
+
+	for rows.Next() {
+		item := domain.EventMute{}
+
+		if err = rows.Scan(&item.UserID, &item.Until); err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Events) execOnOpenedEvent(ctx context.Context, query string, args ...interface{}) error {
+	var count int
+
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return domain.ErrEventNotFound
+	}
+
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func removeJoinRequest(ctx context.Context, db execer, eventId string, userId string) error {
+	query := `DELETE FROM event_join_requests r USING events e
+		WHERE r.event_id = e.id AND e.id = $1 AND e.state <> $2 AND r.user_id = $3`
+	result, err := db.ExecContext(ctx, query, eventId, domain.EventStateClosed, userId)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrJoinRequestNotFound)
+}
+
+func scanChatMessage(row rowScanner) (domain.ChatMessage, error) {
+	item := domain.ChatMessage{}
+	var attachments, reactions, edits []byte
+	err := row.Scan(&item.ID, &item.EventID, &item.UserID, &item.UserName, &item.UserImageID, &item.Message, &item.CreatedAt,
+		&item.ReplyToID, &attachments, &reactions, &edits)
+
+	if err != nil {
+		return domain.ChatMessage{}, err
+	}
+
+	if err = json.Unmarshal(attachments, &item.Attachments); err != nil {
+		return domain.ChatMessage{}, err
+	}
+
+	if err = json.Unmarshal(reactions, &item.Reactions); err != nil {
+		return domain.ChatMessage{}, err
+	}
+
+	if err = json.Unmarshal(edits, &item.Edits); err != nil {
+		return domain.ChatMessage{}, err
+	}
+
+	return item, nil
+}
+
+// marshalList stores nil slices as empty JSON arrays, so jsonb columns can always be appended to.
+func marshalList(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	if string(data) == "null" {
+		return "[]", nil
+	}
+
+	return string(data), nil
+}
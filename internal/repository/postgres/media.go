@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+type Media struct {
+	db *sql.DB
+}
+
+func newMedia(db *sql.DB) *Media {
+	return &Media{
+		db: db,
+	}
+}
+
+func (r *Media) GetMedia(ctx context.Context, id string) (domain.Media, error) {
+	query := `SELECT id, name, content_type, owner_id, size, last_modified_date, variants FROM media WHERE id = $1`
+	media := domain.Media{}
+	var variants []byte
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&media.ID, &media.Name, &media.ContentType, &media.OwnerID, &media.Size,
+		&media.LastModifiedDate, &variants)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Media{}, domain.ErrMediaNotFound
+		}
+		return domain.Media{}, err
+	}
+
+	if err = json.Unmarshal(variants, &media.Variants); err != nil {
+		return domain.Media{}, err
+	}
+
+	return media, nil
+}
+
+func (r *Media) CreateMedia(ctx context.Context, media domain.Media) (string, error) {
+	media.ID = uuid.New().String()
+	variants, err := marshalList(media.Variants)
+
+	if err != nil {
+		return "", err
+	}
+
+	query := `INSERT INTO media (id, name, content_type, owner_id, size, last_modified_date, variants) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = r.db.ExecContext(ctx, query, media.ID, media.Name, media.ContentType, media.OwnerID, media.Size, media.LastModifiedDate, variants)
+
+	if err != nil {
+		return "", err
+	}
+
+	return media.ID, nil
+}
+
+func (r *Media) UpdateMedia(ctx context.Context, media domain.Media) error {
+	variants, err := marshalList(media.Variants)
+
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE media SET name = $2, content_type = $3, size = $4, last_modified_date = $5, variants = $6 WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, media.ID, media.Name, media.ContentType, media.Size, media.LastModifiedDate, variants)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrMediaNotFound)
+}
+
+func (r *Media) DeleteMedia(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM media WHERE id = $1`, id)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrMediaNotFound)
+}
+
+func checkAffected(result sql.Result, notFoundErr error) error {
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return notFoundErr
+	}
+
+	return nil
+}
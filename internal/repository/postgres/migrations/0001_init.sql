@@ -0,0 +1,62 @@
+CREATE TABLE users (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    phone_code TEXT NOT NULL,
+    phone      TEXT NOT NULL,
+    image_id   TEXT NOT NULL DEFAULT '',
+    password   TEXT NOT NULL
+);
+
+CREATE INDEX users_name_idx ON users (name);
+CREATE INDEX users_phone_idx ON users (phone);
+
+CREATE TABLE media (
+    id                 TEXT PRIMARY KEY,
+    name               TEXT NOT NULL,
+    content_type       TEXT NOT NULL,
+    size               BIGINT NOT NULL,
+    last_modified_date TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE events (
+    id         TEXT PRIMARY KEY,
+    seq        BIGSERIAL NOT NULL,
+    owner_id   TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    address    TEXT NOT NULL,
+    state      INTEGER NOT NULL,
+    x          DOUBLE PRECISION NOT NULL,
+    y          DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX events_owner_id_idx ON events (owner_id) WHERE state = 0;
+CREATE INDEX events_coordinates_idx ON events (x, y) WHERE state = 0;
+
+CREATE TABLE event_users (
+    seq      BIGSERIAL PRIMARY KEY,
+    event_id TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id  TEXT NOT NULL
+);
+
+CREATE INDEX event_users_event_id_idx ON event_users (event_id);
+
+CREATE TABLE event_media (
+    seq          BIGSERIAL PRIMARY KEY,
+    event_id     TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    media_id     TEXT NOT NULL,
+    content_type TEXT NOT NULL
+);
+
+CREATE INDEX event_media_event_id_idx ON event_media (event_id);
+
+CREATE TABLE chat_messages (
+    seq           BIGSERIAL PRIMARY KEY,
+    event_id      TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id       TEXT NOT NULL,
+    user_name     TEXT NOT NULL,
+    user_image_id TEXT NOT NULL,
+    message       TEXT NOT NULL
+);
+
+CREATE INDEX chat_messages_event_id_idx ON chat_messages (event_id);
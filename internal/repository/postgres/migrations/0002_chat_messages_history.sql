@@ -0,0 +1,18 @@
+ALTER TABLE chat_messages ADD COLUMN id TEXT;
+ALTER TABLE chat_messages ADD COLUMN created_at TIMESTAMPTZ;
+
+-- legacy messages keep their order: the event creation time plus one millisecond per position, as in the mongo migration
+UPDATE chat_messages m
+SET id         = gen_random_uuid()::text,
+    created_at = e.created_at + (p.position - 1) * interval '1 millisecond'
+FROM (SELECT seq, row_number() OVER (PARTITION BY event_id ORDER BY seq) AS position FROM chat_messages) p,
+     events e
+WHERE p.seq = m.seq
+  AND e.id = m.event_id;
+
+ALTER TABLE chat_messages ALTER COLUMN id SET NOT NULL;
+ALTER TABLE chat_messages ALTER COLUMN created_at SET NOT NULL;
+
+CREATE UNIQUE INDEX chat_messages_id_idx ON chat_messages (id);
+CREATE INDEX chat_messages_history_idx ON chat_messages (event_id, created_at, id COLLATE "C");
+DROP INDEX chat_messages_event_id_idx;
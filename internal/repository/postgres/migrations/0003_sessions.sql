@@ -0,0 +1,14 @@
+CREATE TABLE sessions (
+    id                TEXT PRIMARY KEY,
+    user_id           TEXT NOT NULL,
+    refresh_token     TEXT NOT NULL,
+    device_name       TEXT NOT NULL,
+    device_user_agent TEXT NOT NULL,
+    device_ip_address TEXT NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL,
+    last_used_at      TIMESTAMPTZ NOT NULL,
+    expires_at        TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id, created_at);
+CREATE UNIQUE INDEX sessions_refresh_token_idx ON sessions (refresh_token);
@@ -0,0 +1,10 @@
+CREATE TABLE otp_codes (
+    id         TEXT PRIMARY KEY,
+    phone      TEXT NOT NULL,
+    code       TEXT NOT NULL,
+    attempts   INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX otp_codes_phone_idx ON otp_codes (phone, created_at);
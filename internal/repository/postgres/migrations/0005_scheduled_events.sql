@@ -0,0 +1,10 @@
+ALTER TABLE events ADD COLUMN starts_at TIMESTAMPTZ NULL;
+ALTER TABLE events ADD COLUMN ends_at TIMESTAMPTZ NULL;
+
+DROP INDEX events_owner_id_idx;
+DROP INDEX events_coordinates_idx;
+
+CREATE INDEX events_owner_id_idx ON events (owner_id) WHERE state <> 1;
+CREATE INDEX events_coordinates_idx ON events (x, y) WHERE state <> 1;
+CREATE INDEX events_starts_at_idx ON events (starts_at) WHERE state = 2;
+CREATE INDEX events_ends_at_idx ON events (ends_at) WHERE state <> 1;
@@ -0,0 +1,18 @@
+ALTER TABLE events ADD COLUMN visibility INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE event_members (
+    seq      BIGSERIAL PRIMARY KEY,
+    event_id TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id  TEXT NOT NULL,
+    UNIQUE (event_id, user_id)
+);
+
+CREATE TABLE event_join_requests (
+    seq           BIGSERIAL PRIMARY KEY,
+    event_id      TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id       TEXT NOT NULL,
+    user_name     TEXT NOT NULL,
+    user_image_id TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    UNIQUE (event_id, user_id)
+);
@@ -0,0 +1,7 @@
+CREATE TABLE event_roles (
+    seq      BIGSERIAL PRIMARY KEY,
+    event_id TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id  TEXT NOT NULL,
+    role     INTEGER NOT NULL,
+    UNIQUE (event_id, user_id)
+);
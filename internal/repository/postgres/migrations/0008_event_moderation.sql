@@ -0,0 +1,14 @@
+CREATE TABLE event_mutes (
+    seq      BIGSERIAL PRIMARY KEY,
+    event_id TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id  TEXT NOT NULL,
+    until    TIMESTAMPTZ NOT NULL,
+    UNIQUE (event_id, user_id)
+);
+
+CREATE TABLE event_bans (
+    seq      BIGSERIAL PRIMARY KEY,
+    event_id TEXT NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+    user_id  TEXT NOT NULL,
+    UNIQUE (event_id, user_id)
+);
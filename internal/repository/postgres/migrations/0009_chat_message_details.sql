@@ -0,0 +1,4 @@
+ALTER TABLE chat_messages ADD COLUMN reply_to_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE chat_messages ADD COLUMN attachments JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE chat_messages ADD COLUMN reactions JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE chat_messages ADD COLUMN edits JSONB NOT NULL DEFAULT '[]';
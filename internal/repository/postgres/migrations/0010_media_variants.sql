@@ -0,0 +1 @@
+ALTER TABLE media ADD COLUMN variants JSONB NOT NULL DEFAULT '[]';
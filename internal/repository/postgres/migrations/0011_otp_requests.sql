@@ -0,0 +1,6 @@
+CREATE TABLE otp_requests (
+    phone           TEXT PRIMARY KEY,
+    window_start    TIMESTAMPTZ NOT NULL,
+    count           INTEGER NOT NULL,
+    last_request_at TIMESTAMPTZ NOT NULL
+);
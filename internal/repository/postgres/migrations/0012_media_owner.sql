@@ -0,0 +1 @@
+ALTER TABLE media ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+type OtpCodes struct {
+	db *sql.DB
+}
+
+func newOtpCodes(db *sql.DB) *OtpCodes {
+	return &OtpCodes{
+		db: db,
+	}
+}
+
+func (r *OtpCodes) CreateOtpCode(ctx context.Context, code domain.OtpCode) (string, error) {
+	code.ID = uuid.New().String()
+	query := `INSERT INTO otp_codes (id, phone, code, attempts, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.db.ExecContext(ctx, query, code.ID, code.Phone, code.Code, code.Attempts, code.CreatedAt, code.ExpiresAt)
+
+	if err != nil {
+		return "", err
+	}
+
+	return code.ID, nil
+}
+
+func (r *OtpCodes) GetLastOtpCode(ctx context.Context, phone string) (domain.OtpCode, error) {
+	query := `SELECT id, phone, code, attempts, created_at, expires_at FROM otp_codes WHERE phone = $1 ORDER BY created_at DESC, id LIMIT 1`
+	model := domain.OtpCode{}
+	err := r.db.QueryRowContext(ctx, query, phone).Scan(&model.ID, &model.Phone, &model.Code, &model.Attempts, &model.CreatedAt, &model.ExpiresAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.OtpCode{}, domain.ErrOtpCodeNotFound
+		}
+		return domain.OtpCode{}, err
+	}
+
+	return model, nil
+}
+
+// AddOtpRequest starts a new window or grows the counter of the current one, the conflict update
+// only touches a counter that may grow, so no affected rows means the limit is exceeded.
+func (r *OtpCodes) AddOtpRequest(ctx context.Context, phone string, now time.Time, limit domain.OtpRequestsLimit) error {
+	query := `INSERT INTO otp_requests (phone, window_start, count, last_request_at) VALUES ($1, $2, 1, $2)
+		ON CONFLICT (phone) DO UPDATE SET
+			count = CASE WHEN otp_requests.window_start <= $3 THEN 1 ELSE otp_requests.count + 1 END,
+			window_start = CASE WHEN otp_requests.window_start <= $3 THEN $2 ELSE otp_requests.window_start END,
+			last_request_at = $2
+		WHERE otp_requests.window_start <= $3 OR (otp_requests.count < $4 AND otp_requests.last_request_at <= $5)`
+	result, err := r.db.ExecContext(ctx, query, phone, now, now.Add(-limit.Window), limit.Count, now.Add(-limit.Interval))
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrOtpRequestsLimitExceeded)
+}
+
+func (r *OtpCodes) IncrementOtpAttempts(ctx context.Context, id string, maxAttempts int) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE otp_codes SET attempts = attempts + 1 WHERE id = $1 AND attempts < $2`, id, maxAttempts)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	var count int
+
+	if err = r.db.QueryRowContext(ctx, `SELECT count(*) FROM otp_codes WHERE id = $1`, id).Scan(&count); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return domain.ErrOtpCodeNotFound
+	}
+
+	return domain.ErrOtpAttemptsLimitExceeded
+}
+
+func (r *OtpCodes) RemoveOtpCode(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM otp_codes WHERE id = $1`, id)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrOtpCodeNotFound)
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func NewRepositories(connectionString string) (*Media, *Users, *Sessions, *OtpCodes, *Events, *TestsCleaner, error) {
+	db, err := sql.Open("postgres", connectionString)
+
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err = db.PingContext(ctx); err != nil {
+		return nil, nil, nil, nil, nil, nil, err
+	}
+
+	if err = migrate(ctx, db); err != nil {
+		return nil, nil, nil, nil, nil, nil, err
+	}
+
+	return newMedia(db), newUsers(db), newSessions(db), newOtpCodes(db), newEvents(db), newTestsCleaner(db), nil
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+const sessionColumns = `id, user_id, refresh_token, device_name, device_user_agent, device_ip_address, created_at, last_used_at, expires_at`
+
+type Sessions struct {
+	db *sql.DB
+}
+
+func newSessions(db *sql.DB) *Sessions {
+	return &Sessions{
+		db: db,
+	}
+}
+
+func (r *Sessions) CreateSession(ctx context.Context, session domain.Session) (string, error) {
+	session.ID = uuid.New().String()
+	query := `INSERT INTO sessions (` + sessionColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := r.db.ExecContext(ctx, query, session.ID, session.UserID, session.RefreshToken,
+		session.Device.Name, session.Device.UserAgent, session.Device.IPAddress,
+		session.CreatedAt, session.LastUsedAt, session.ExpiresAt)
+
+	if err != nil {
+		return "", err
+	}
+
+	return session.ID, nil
+}
+
+func (r *Sessions) GetSessionByID(ctx context.Context, id string) (domain.Session, error) {
+	return r.getSession(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = $1`, id)
+}
+
+func (r *Sessions) GetSessionByRefreshToken(ctx context.Context, refreshToken string) (domain.Session, error) {
+	return r.getSession(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE refresh_token = $1`, refreshToken)
+}
+
+func (r *Sessions) GetUserSessions(ctx context.Context, userId string) ([]domain.Session, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE user_id = $1 ORDER BY created_at, id`, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	result := []domain.Session{}
+
+	for rows.Next() {
+		session, err := scanSession(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, session)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Sessions) UpdateRefreshToken(ctx context.Context, id string, oldRefreshToken string, newRefreshToken string, lastUsedAt time.Time, expiresAt time.Time) error {
+	query := `UPDATE sessions SET refresh_token = $3, last_used_at = $4, expires_at = $5 WHERE id = $1 AND refresh_token = $2`
+	result, err := r.db.ExecContext(ctx, query, id, oldRefreshToken, newRefreshToken, lastUsedAt, expiresAt)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrSessionNotFound)
+}
+
+func (r *Sessions) RemoveSession(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, id)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrSessionNotFound)
+}
+
+func (r *Sessions) RemoveUserSessions(ctx context.Context, userId string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userId)
+	return err
+}
+
+func (r *Sessions) getSession(ctx context.Context, query string, args ...interface{}) (domain.Session, error) {
+	session, err := scanSession(r.db.QueryRowContext(ctx, query, args...))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Session{}, domain.ErrSessionNotFound
+		}
+		return domain.Session{}, err
+	}
+
+	return session, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSession(row rowScanner) (domain.Session, error) {
+	session := domain.Session{}
+	err := row.Scan(&session.ID, &session.UserID, &session.RefreshToken,
+		&session.Device.Name, &session.Device.UserAgent, &session.Device.IPAddress,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt)
+	return session, err
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+type Users struct {
+	db *sql.DB
+}
+
+func newUsers(db *sql.DB) *Users {
+	return &Users{
+		db: db,
+	}
+}
+
+func (r *Users) GetNamesCount(ctx context.Context, name string) (int64, error) {
+	var count int64
+
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM users WHERE name = $1`, name).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *Users) GetPhonesCount(ctx context.Context, phone string) (int64, error) {
+	var count int64
+
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM users WHERE phone = $1`, phone).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *Users) CreateUser(ctx context.Context, user domain.User) (string, error) {
+	user.ID = uuid.New().String()
+	query := `INSERT INTO users (id, name, phone_code, phone, image_id, password) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.Name, user.PhoneCode, user.Phone, user.ImageID, user.Password)
+
+	if err != nil {
+		return "", err
+	}
+
+	return user.ID, nil
+}
+
+func (r *Users) GetUserByID(ctx context.Context, id string) (domain.User, error) {
+	return r.getUser(ctx, `SELECT id, name, phone_code, phone, image_id, password FROM users WHERE id = $1`, id)
+}
+
+func (r *Users) GetUserByPhone(ctx context.Context, phone string) (domain.User, error) {
+	return r.getUser(ctx, `SELECT id, name, phone_code, phone, image_id, password FROM users WHERE phone = $1 LIMIT 1`, phone)
+}
+
+func (r *Users) ChangePassword(ctx context.Context, id string, password string) error {
+	return r.update(ctx, `UPDATE users SET password = $2 WHERE id = $1`, id, password)
+}
+
+func (r *Users) SetImageId(ctx context.Context, userId string, imageId string) error {
+	return r.update(ctx, `UPDATE users SET image_id = $2 WHERE id = $1`, userId, imageId)
+}
+
+func (r *Users) UpdateName(ctx context.Context, userId string, name string) error {
+	return r.update(ctx, `UPDATE users SET name = $2 WHERE id = $1`, userId, name)
+}
+
+func (r *Users) UpdatePhone(ctx context.Context, userId string, phone string) error {
+	return r.update(ctx, `UPDATE users SET phone = $2 WHERE id = $1`, userId, phone)
+}
+
+func (r *Users) UpdateNameAndPhone(ctx context.Context, userId string, name string, phone string) error {
+	return r.update(ctx, `UPDATE users SET name = $2, phone = $3 WHERE id = $1`, userId, name, phone)
+}
+
+func (r *Users) getUser(ctx context.Context, query string, args ...interface{}) (domain.User, error) {
+	model := domain.User{}
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&model.ID, &model.Name, &model.PhoneCode, &model.Phone, &model.ImageID, &model.Password)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, domain.ErrUserNotFound
+		}
+		return domain.User{}, err
+	}
+
+	return model, nil
+}
+
+func (r *Users) update(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result, domain.ErrUserNotFound)
+}
@@ -2,14 +2,27 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository/memory"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository/mongo"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository/postgres"
 )
 
+const (
+	DriverMongo    = "mongo"
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+)
+
+var ErrUnknownDriver = errors.New("unknown db driver")
+
 type Media interface {
 	GetMedia(ctx context.Context, id string) (domain.Media, error)
 	CreateMedia(ctx context.Context, media domain.Media) (string, error)
+	// UpdateMedia replaces the file metadata, the owner is set only on create.
 	UpdateMedia(ctx context.Context, media domain.Media) error
 	DeleteMedia(ctx context.Context, id string) error
 }
@@ -27,39 +40,110 @@ type Users interface {
 	UpdateNameAndPhone(ctx context.Context, userId string, name string, phone string) error
 }
 
+type Sessions interface {
+	CreateSession(ctx context.Context, session domain.Session) (string, error)
+	GetSessionByID(ctx context.Context, id string) (domain.Session, error)
+	GetSessionByRefreshToken(ctx context.Context, refreshToken string) (domain.Session, error)
+	GetUserSessions(ctx context.Context, userId string) ([]domain.Session, error)
+	UpdateRefreshToken(ctx context.Context, id string, oldRefreshToken string, newRefreshToken string, lastUsedAt time.Time, expiresAt time.Time) error
+	RemoveSession(ctx context.Context, id string) error
+	RemoveUserSessions(ctx context.Context, userId string) error
+}
+
+type OtpCodes interface {
+	CreateOtpCode(ctx context.Context, code domain.OtpCode) (string, error)
+	GetLastOtpCode(ctx context.Context, phone string) (domain.OtpCode, error)
+	// AddOtpRequest counts a code request in one atomic step or fails with domain.ErrOtpRequestsLimitExceeded.
+	AddOtpRequest(ctx context.Context, phone string, now time.Time, limit domain.OtpRequestsLimit) error
+	// IncrementOtpAttempts takes an attempt only while less than maxAttempts are taken,
+	// otherwise fails with domain.ErrOtpAttemptsLimitExceeded.
+	IncrementOtpAttempts(ctx context.Context, id string, maxAttempts int) error
+	RemoveOtpCode(ctx context.Context, id string) error
+}
+
 type Events interface {
 	CreateEvent(ctx context.Context, event domain.Event) (string, error)
 	GetEventById(ctx context.Context, id string) (domain.Event, error)
 	GetEventByOwnerId(ctx context.Context, ownerId string) (domain.Event, error)
 	GetEventsByRange(ctx context.Context, xLeft float64, xRight float64, yLeft float64, yRight float64) ([]domain.EventRangeData, error)
+	GetEventsNearby(ctx context.Context, center domain.Coordinates, maxDistance float64) ([]domain.EventRangeData, error)
+	GetScheduledEventIds(ctx context.Context, startsBefore time.Time) ([]string, error)
+	GetEndedEventIds(ctx context.Context, endsBefore time.Time) ([]string, error)
+	GetIdleEventIds(ctx context.Context, startedBefore time.Time) ([]string, error)
+	OpenEvent(ctx context.Context, id string) error
 	UpdateEvent(ctx context.Context, id string, address string, coordinates domain.Coordinates) error
 	RemoveEvent(ctx context.Context, id string) error
 	AddMedia(ctx context.Context, id string, mediaInfo domain.MediaInfo) error
 	RemoveMedia(ctx context.Context, eventId string, mediaId string) error
 	AddUserInfo(ctx context.Context, eventId string, userInfo domain.UserInfo) error
 	RemoveUserInfo(ctx context.Context, eventId string, userId string) error
-	AddChatMessage(ctx context.Context, id string, chatMessage domain.ChatMessage) error
+	AddMember(ctx context.Context, eventId string, userInfo domain.UserInfo) error
+	AddJoinRequest(ctx context.Context, eventId string, joinRequest domain.JoinRequest) error
+	ApproveJoinRequest(ctx context.Context, eventId string, userId string) error
+	RemoveJoinRequest(ctx context.Context, eventId string, userId string) error
+	SetRole(ctx context.Context, eventId string, roleInfo domain.EventRoleInfo) error
+	RemoveRole(ctx context.Context, eventId string, userId string) error
+	SetMute(ctx context.Context, eventId string, mute domain.EventMute) error
+	// BanUser removes the user from the attendees, members, roles and join requests along with the ban.
+	BanUser(ctx context.Context, eventId string, userId string) error
+	AddChatMessage(ctx context.Context, id string, chatMessage domain.ChatMessage) (string, error)
+	GetChatMessages(ctx context.Context, eventId string, beforeId string, afterId string, limit int) ([]domain.ChatMessage, error)
+	GetChatMessage(ctx context.Context, eventId string, messageId string) (domain.ChatMessage, error)
+	EditChatMessage(ctx context.Context, eventId string, messageId string, message string, edit domain.ChatMessageEdit) (domain.ChatMessage, error)
+	ToggleChatReaction(ctx context.Context, eventId string, messageId string, reaction domain.ChatReaction) (domain.ChatMessage, error)
+	RemoveChatMessage(ctx context.Context, eventId string, messageId string) error
 }
 
 type Repositories struct {
-	Media  Media
-	Users  Users
-	Events Events
+	Media    Media
+	Users    Users
+	Sessions Sessions
+	OtpCodes OtpCodes
+	Events   Events
 }
 
 type TestsCleaner interface {
 	Clean() error
 }
 
-func NewRepositories(connectionString string, dbName string) (*Repositories, TestsCleaner, error) {
-	media, users, events, cleaner, err := mongo.NewRepositories(connectionString, dbName)
-	if err != nil {
-		return nil, nil, err
-	}
+func NewRepositories(driver string, connectionString string, dbName string) (*Repositories, TestsCleaner, error) {
+	switch driver {
+	case DriverMongo:
+		media, users, sessions, otpCodes, events, cleaner, err := mongo.NewRepositories(connectionString, dbName)
+		if err != nil {
+			return nil, nil, err
+		}
 
-	return &Repositories{
-		Media:  media,
-		Users:  users,
-		Events: events,
-	}, cleaner, nil
+		return &Repositories{
+			Media:    media,
+			Users:    users,
+			Sessions: sessions,
+			OtpCodes: otpCodes,
+			Events:   events,
+		}, cleaner, nil
+	case DriverPostgres:
+		media, users, sessions, otpCodes, events, cleaner, err := postgres.NewRepositories(connectionString)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &Repositories{
+			Media:    media,
+			Users:    users,
+			Sessions: sessions,
+			OtpCodes: otpCodes,
+			Events:   events,
+		}, cleaner, nil
+	case DriverMemory:
+		media, users, sessions, otpCodes, events, cleaner := memory.NewRepositories()
+		return &Repositories{
+			Media:    media,
+			Users:    users,
+			Sessions: sessions,
+			OtpCodes: otpCodes,
+			Events:   events,
+		}, cleaner, nil
+	default:
+		return nil, nil, ErrUnknownDriver
+	}
 }
@@ -0,0 +1,957 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+type testBackend struct {
+	Driver           string
+	ConnectionString string
+}
+
+func TestRepositories(t *testing.T) {
+	backends := []testBackend{{Driver: DriverMemory}}
+
+	if connection := os.Getenv("TEST_MONGO_CONNECTION"); connection != "" {
+		backends = append(backends, testBackend{Driver: DriverMongo, ConnectionString: connection})
+	}
+
+	if connection := os.Getenv("TEST_POSTGRES_CONNECTION"); connection != "" {
+		backends = append(backends, testBackend{Driver: DriverPostgres, ConnectionString: connection})
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.Driver, func(t *testing.T) {
+			repositories, cleaner, err := NewRepositories(backend.Driver, backend.ConnectionString, "repository_tests")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = cleaner.Clean(); err != nil {
+				t.Fatal(err)
+			}
+
+			defer cleaner.Clean()
+			t.Run("users", func(t *testing.T) { testUsersContract(t, repositories.Users) })
+			t.Run("sessions", func(t *testing.T) { testSessionsContract(t, repositories.Sessions) })
+			t.Run("otp codes", func(t *testing.T) { testOtpCodesContract(t, repositories.OtpCodes) })
+			t.Run("media", func(t *testing.T) { testMediaContract(t, repositories.Media) })
+			t.Run("events", func(t *testing.T) { testEventsContract(t, repositories.Events) })
+			t.Run("scheduled events", func(t *testing.T) { testScheduledEventsContract(t, repositories.Events) })
+			t.Run("geo events", func(t *testing.T) { testGeoEventsContract(t, repositories.Events) })
+			t.Run("event visibility", func(t *testing.T) { testEventVisibilityContract(t, repositories.Events) })
+			t.Run("event roles", func(t *testing.T) { testEventRolesContract(t, repositories.Events) })
+			t.Run("event moderation", func(t *testing.T) { testEventModerationContract(t, repositories.Events) })
+			t.Run("rich chat", func(t *testing.T) { testRichChatContract(t, repositories.Events) })
+		})
+	}
+}
+
+func TestNewRepositoriesUnknownDriver(t *testing.T) {
+	if _, _, err := NewRepositories("unknown", "", ""); !errors.Is(err, ErrUnknownDriver) {
+		t.Fatalf("Expected error: %v\nActual error: %v", ErrUnknownDriver, err)
+	}
+}
+
+func testUsersContract(t *testing.T, users Users) {
+	ctx := context.Background()
+	id, err := users.CreateUser(ctx, domain.User{Name: "contract", PhoneCode: "+7", Phone: "1234567890", Password: "hash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertCount(t, users.GetNamesCount, "contract", 1)
+	assertCount(t, users.GetPhonesCount, "1234567890", 1)
+	assertCount(t, users.GetNamesCount, "missing", 0)
+
+	user, err := users.GetUserByPhone(ctx, "1234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.ID != id || user.Name != "contract" || user.PhoneCode != "+7" || user.Password != "hash" {
+		t.Fatalf("Incorrect user: %+v", user)
+	}
+
+	if err = users.UpdateNameAndPhone(ctx, id, "contract_updated", "0987654321"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = users.ChangePassword(ctx, id, "new_hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = users.SetImageId(ctx, id, "image_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err = users.GetUserByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.Name != "contract_updated" || user.Phone != "0987654321" || user.Password != "new_hash" || user.ImageID != "image_id" {
+		t.Fatalf("Incorrect updated user: %+v", user)
+	}
+
+	assertErr(t, func() error { _, err := users.GetUserByID(ctx, "missing"); return err }, domain.ErrUserNotFound)
+	assertErr(t, func() error { _, err := users.GetUserByPhone(ctx, "0000000000"); return err }, domain.ErrUserNotFound)
+	assertErr(t, func() error { return users.ChangePassword(ctx, "missing", "hash") }, domain.ErrUserNotFound)
+	assertErr(t, func() error { return users.SetImageId(ctx, "missing", "image") }, domain.ErrUserNotFound)
+	assertErr(t, func() error { return users.UpdateName(ctx, "missing", "name") }, domain.ErrUserNotFound)
+	assertErr(t, func() error { return users.UpdatePhone(ctx, "missing", "phone") }, domain.ErrUserNotFound)
+	assertErr(t, func() error { return users.UpdateNameAndPhone(ctx, "missing", "name", "phone") }, domain.ErrUserNotFound)
+}
+
+func testSessionsContract(t *testing.T, sessions Sessions) {
+	ctx := context.Background()
+	created := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	ids := make([]string, 2)
+
+	for i := range ids {
+		id, err := sessions.CreateSession(ctx, domain.Session{
+			UserID:       "contract_user",
+			RefreshToken: "refresh_" + strconv.Itoa(i),
+			Device:       domain.DeviceInfo{Name: "phone", UserAgent: "agent", IPAddress: "127.0.0.1"},
+			CreatedAt:    created.Add(time.Duration(i) * time.Minute),
+			LastUsedAt:   created,
+			ExpiresAt:    created.Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids[i] = id
+	}
+
+	session, err := sessions.GetSessionByRefreshToken(ctx, "refresh_0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if session.ID != ids[0] || session.UserID != "contract_user" || session.Device.UserAgent != "agent" || !session.ExpiresAt.Equal(created.Add(time.Hour)) {
+		t.Fatalf("Incorrect session: %+v", session)
+	}
+
+	used := created.Add(time.Minute * 5)
+	if err = sessions.UpdateRefreshToken(ctx, ids[0], "refresh_0", "rotated", used, used.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err = sessions.GetSessionByID(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if session.RefreshToken != "rotated" || !session.LastUsedAt.Equal(used) {
+		t.Fatalf("Incorrect rotated session: %+v", session)
+	}
+
+	assertErr(t, func() error {
+		return sessions.UpdateRefreshToken(ctx, ids[0], "refresh_0", "reused", used, used)
+	}, domain.ErrSessionNotFound)
+
+	userSessions, err := sessions.GetUserSessions(ctx, "contract_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(userSessions) != 2 || userSessions[0].ID != ids[0] || userSessions[1].ID != ids[1] {
+		t.Fatalf("Incorrect user sessions: %+v", userSessions)
+	}
+
+	if err = sessions.RemoveSession(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { _, err := sessions.GetSessionByID(ctx, ids[0]); return err }, domain.ErrSessionNotFound)
+	assertErr(t, func() error { return sessions.RemoveSession(ctx, ids[0]) }, domain.ErrSessionNotFound)
+
+	if err = sessions.RemoveUserSessions(ctx, "contract_user"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { _, err := sessions.GetSessionByRefreshToken(ctx, "refresh_1"); return err }, domain.ErrSessionNotFound)
+}
+
+func testOtpCodesContract(t *testing.T, otpCodes OtpCodes) {
+	ctx := context.Background()
+	created := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	ids := make([]string, 3)
+
+	for i := range ids {
+		id, err := otpCodes.CreateOtpCode(ctx, domain.OtpCode{
+			Phone:     "1234567890",
+			Code:      "code_" + strconv.Itoa(i),
+			CreatedAt: created.Add(time.Duration(i) * time.Minute),
+			ExpiresAt: created.Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids[i] = id
+	}
+
+	if err := otpCodes.IncrementOtpAttempts(ctx, ids[2], 1); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { return otpCodes.IncrementOtpAttempts(ctx, ids[2], 1) }, domain.ErrOtpAttemptsLimitExceeded)
+
+	code, err := otpCodes.GetLastOtpCode(ctx, "1234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code.ID != ids[2] || code.Code != "code_2" || code.Attempts != 1 || !code.ExpiresAt.Equal(created.Add(time.Hour)) {
+		t.Fatalf("Incorrect otp code: %+v", code)
+	}
+
+	if err = otpCodes.RemoveOtpCode(ctx, ids[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	code, err = otpCodes.GetLastOtpCode(ctx, "1234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code.ID != ids[1] {
+		t.Fatalf("Incorrect otp code after removal: %+v", code)
+	}
+
+	assertErr(t, func() error { _, err := otpCodes.GetLastOtpCode(ctx, "0000000000"); return err }, domain.ErrOtpCodeNotFound)
+	assertErr(t, func() error { return otpCodes.IncrementOtpAttempts(ctx, ids[2], 5) }, domain.ErrOtpCodeNotFound)
+	assertErr(t, func() error { return otpCodes.RemoveOtpCode(ctx, ids[2]) }, domain.ErrOtpCodeNotFound)
+
+	// parallel attempts never take more than the limit
+	if succeeded := countParallel(20, func() error { return otpCodes.IncrementOtpAttempts(ctx, ids[0], 5) }); succeeded != 5 {
+		t.Fatalf("Expected attempts: 5\nActual attempts: %d", succeeded)
+	}
+
+	limit := domain.OtpRequestsLimit{Interval: time.Minute, Window: time.Hour, Count: 2}
+
+	if succeeded := countParallel(10, func() error { return otpCodes.AddOtpRequest(ctx, "1234567890", created, limit) }); succeeded != 1 {
+		t.Fatalf("Expected requests: 1\nActual requests: %d", succeeded)
+	}
+
+	assertErr(t, func() error { return otpCodes.AddOtpRequest(ctx, "1234567890", created.Add(30*time.Second), limit) }, domain.ErrOtpRequestsLimitExceeded)
+
+	if err = otpCodes.AddOtpRequest(ctx, "1234567890", created.Add(time.Minute), limit); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { return otpCodes.AddOtpRequest(ctx, "1234567890", created.Add(2*time.Minute), limit) }, domain.ErrOtpRequestsLimitExceeded)
+
+	if err = otpCodes.AddOtpRequest(ctx, "0000000000", created.Add(2*time.Minute), limit); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = otpCodes.AddOtpRequest(ctx, "1234567890", created.Add(time.Hour), limit); err != nil {
+		t.Fatalf("Expected a new window, got error: %v", err)
+	}
+}
+
+// countParallel runs action in n goroutines at once and returns how many of them succeeded.
+func countParallel(n int, action func() error) int {
+	var succeeded int64
+	wg := sync.WaitGroup{}
+	wg.Add(n)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			if action() == nil {
+				atomic.AddInt64(&succeeded, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return int(succeeded)
+}
+
+func testMediaContract(t *testing.T, media Media) {
+	ctx := context.Background()
+	modified := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	id, err := media.CreateMedia(ctx, domain.Media{Name: "photo.jpg", ContentType: "image/jpeg", OwnerID: "uploader", Size: 10, LastModifiedDate: modified})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variants := []domain.MediaVariant{{Size: 64, Width: 64, Height: 48, ContentType: "image/jpeg", FileSize: 5}}
+	if err = media.UpdateMedia(ctx, domain.Media{ID: id, Name: "photo.png", ContentType: "image/png", Size: 20, LastModifiedDate: modified, Variants: variants}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := media.GetMedia(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Name != "photo.png" || result.ContentType != "image/png" || result.OwnerID != "uploader" || result.Size != 20 ||
+		!result.LastModifiedDate.Equal(modified) ||
+		len(result.Variants) != 1 || result.Variants[0] != variants[0] {
+		t.Fatalf("Incorrect media: %+v", result)
+	}
+
+	if err = media.DeleteMedia(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { _, err := media.GetMedia(ctx, id); return err }, domain.ErrMediaNotFound)
+	assertErr(t, func() error { return media.UpdateMedia(ctx, domain.Media{ID: id}) }, domain.ErrMediaNotFound)
+	assertErr(t, func() error { return media.DeleteMedia(ctx, id) }, domain.ErrMediaNotFound)
+}
+
+func testEventsContract(t *testing.T, events Events) {
+	ctx := context.Background()
+	id, err := events.CreateEvent(ctx, domain.Event{
+		OwnerID:     "contract_owner",
+		Name:        "contract",
+		Address:     "contract",
+		State:       domain.EventStateOpened,
+		Coordinates: domain.Coordinates{X: 1, Y: 1},
+		Users:       []domain.UserInfo{{ID: "user_1"}},
+		Media:       []domain.MediaInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closedId, err := events.CreateEvent(ctx, domain.Event{
+		OwnerID:     "contract_closed_owner",
+		Name:        "contract_closed",
+		Address:     "contract_closed",
+		State:       domain.EventStateClosed,
+		Coordinates: domain.Coordinates{X: 2, Y: 2},
+		Users:       []domain.UserInfo{},
+		Media:       []domain.MediaInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { _, err := events.GetEventById(ctx, closedId); return err }, domain.ErrEventNotFound)
+	assertErr(t, func() error { _, err := events.GetEventByOwnerId(ctx, "contract_closed_owner"); return err }, domain.ErrEventNotFound)
+
+	if err = events.AddUserInfo(ctx, id, domain.UserInfo{ID: "user_2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.AddMedia(ctx, id, domain.MediaInfo{ID: "media_1", ContentType: "image/jpeg"}); err != nil {
+		t.Fatal(err)
+	}
+
+	chatMessageIds := make([]string, 3)
+	for i := range chatMessageIds {
+		chatMessageIds[i], err = events.AddChatMessage(ctx, id, domain.ChatMessage{
+			UserID:    "user_1",
+			UserName:  "user",
+			Message:   "hello",
+			CreatedAt: time.Date(2022, 6, 1, 12, i, 0, 0, time.UTC),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertChatMessages(t, events, id, "", "", 2, chatMessageIds[1:])
+	assertChatMessages(t, events, id, chatMessageIds[2], "", 5, chatMessageIds[:2])
+	assertChatMessages(t, events, id, chatMessageIds[1], "", 1, chatMessageIds[:1])
+	assertChatMessages(t, events, id, "", chatMessageIds[0], 1, chatMessageIds[1:2])
+	assertChatMessages(t, events, id, "", chatMessageIds[2], 1, []string{})
+	assertErr(t, func() error { _, err := events.GetChatMessages(ctx, closedId, chatMessageIds[0], "", 1); return err }, domain.ErrChatMessageNotFound)
+
+	if err = events.UpdateEvent(ctx, id, "updated", domain.Coordinates{X: 3, Y: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := events.GetEventByOwnerId(ctx, "contract_owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event.ID != id || event.Address != "updated" || event.Coordinates.X != 3 || len(event.Users) != 2 || len(event.Media) != 1 {
+		t.Fatalf("Incorrect event: %+v", event)
+	}
+
+	rangeData, err := events.GetEventsByRange(ctx, 0, 5, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rangeData) != 1 || rangeData[0].ID != id || rangeData[0].UsersCount != 2 || rangeData[0].Coordinates.Y != 3 {
+		t.Fatalf("Incorrect range data: %+v", rangeData)
+	}
+
+	if err = events.RemoveUserInfo(ctx, id, "user_1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.RemoveMedia(ctx, id, "media_1"); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err = events.GetEventById(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(event.Users) != 1 || event.Users[0].ID != "user_2" || len(event.Media) != 0 {
+		t.Fatalf("Incorrect event after removal: %+v", event)
+	}
+
+	if err = events.RemoveEvent(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	rangeData, err = events.GetEventsByRange(ctx, 0, 5, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rangeData) != 0 {
+		t.Fatalf("Expected empty range data, actual: %+v", rangeData)
+	}
+
+	assertErr(t, func() error { return events.RemoveEvent(ctx, id) }, domain.ErrEventNotFound)
+	assertErr(t, func() error { return events.UpdateEvent(ctx, id, "", domain.Coordinates{}) }, domain.ErrEventNotFound)
+	assertErr(t, func() error { return events.AddMedia(ctx, id, domain.MediaInfo{}) }, domain.ErrEventNotFound)
+	assertErr(t, func() error { return events.RemoveMedia(ctx, id, "media_1") }, domain.ErrEventNotFound)
+	assertErr(t, func() error { return events.AddUserInfo(ctx, id, domain.UserInfo{}) }, domain.ErrEventNotFound)
+	assertErr(t, func() error { return events.RemoveUserInfo(ctx, id, "user_2") }, domain.ErrEventNotFound)
+	assertErr(t, func() error { _, err := events.AddChatMessage(ctx, id, domain.ChatMessage{}); return err }, domain.ErrEventNotFound)
+}
+
+func testScheduledEventsContract(t *testing.T, events Events) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	startsAt := now.Add(time.Hour)
+	endsAt := now.Add(time.Hour * 2)
+	scheduledId, err := events.CreateEvent(ctx, domain.Event{
+		OwnerID:     "scheduled_owner",
+		Name:        "scheduled",
+		Address:     "scheduled",
+		State:       domain.EventStateScheduled,
+		Coordinates: domain.Coordinates{X: 11, Y: 11},
+		CreatedAt:   now,
+		StartsAt:    &startsAt,
+		EndsAt:      &endsAt,
+		Users:       []domain.UserInfo{},
+		Media:       []domain.MediaInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idleId, err := events.CreateEvent(ctx, domain.Event{
+		OwnerID:     "idle_owner",
+		Name:        "idle",
+		Address:     "idle",
+		State:       domain.EventStateOpened,
+		Coordinates: domain.Coordinates{X: 12, Y: 12},
+		CreatedAt:   now.Add(-time.Hour),
+		Users:       []domain.UserInfo{},
+		Media:       []domain.MediaInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = events.CreateEvent(ctx, domain.Event{
+		OwnerID:     "busy_owner",
+		Name:        "busy",
+		Address:     "busy",
+		State:       domain.EventStateOpened,
+		Coordinates: domain.Coordinates{X: 13, Y: 13},
+		CreatedAt:   now.Add(-time.Hour),
+		Users:       []domain.UserInfo{{ID: "user_1"}},
+		Media:       []domain.MediaInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := events.GetEventByOwnerId(ctx, "scheduled_owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event.ID != scheduledId || event.State != domain.EventStateScheduled || event.StartsAt == nil || !event.StartsAt.Equal(startsAt) ||
+		event.EndsAt == nil || !event.EndsAt.Equal(endsAt) {
+		t.Fatalf("Incorrect scheduled event: %+v", event)
+	}
+
+	rangeData, err := events.GetEventsByRange(ctx, 10, 14, 10, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rangeData) != 3 || rangeData[0].State != domain.EventStateScheduled || rangeData[0].StartsAt == nil || rangeData[1].StartsAt != nil {
+		t.Fatalf("Incorrect range data: %+v", rangeData)
+	}
+
+	assertErr(t, func() error { return events.AddUserInfo(ctx, scheduledId, domain.UserInfo{ID: "user_1"}) }, domain.ErrEventNotFound)
+	assertIds(t, func() ([]string, error) { return events.GetScheduledEventIds(ctx, now) }, []string{})
+	assertIds(t, func() ([]string, error) { return events.GetScheduledEventIds(ctx, startsAt) }, []string{scheduledId})
+	assertIds(t, func() ([]string, error) { return events.GetIdleEventIds(ctx, now.Add(-time.Minute)) }, []string{idleId})
+	assertIds(t, func() ([]string, error) { return events.GetIdleEventIds(ctx, now.Add(-time.Hour*2)) }, []string{})
+
+	if err = events.OpenEvent(ctx, scheduledId); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { return events.OpenEvent(ctx, scheduledId) }, domain.ErrEventNotFound)
+	assertErr(t, func() error { return events.OpenEvent(ctx, idleId) }, domain.ErrEventNotFound)
+	assertIds(t, func() ([]string, error) { return events.GetScheduledEventIds(ctx, startsAt) }, []string{})
+	assertIds(t, func() ([]string, error) { return events.GetIdleEventIds(ctx, startsAt) }, []string{scheduledId, idleId})
+	assertIds(t, func() ([]string, error) { return events.GetEndedEventIds(ctx, startsAt) }, []string{})
+	assertIds(t, func() ([]string, error) { return events.GetEndedEventIds(ctx, endsAt) }, []string{scheduledId})
+
+	if err = events.AddUserInfo(ctx, scheduledId, domain.UserInfo{ID: "user_1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertIds(t, func() ([]string, error) { return events.GetIdleEventIds(ctx, startsAt) }, []string{idleId})
+
+	for _, id := range []string{scheduledId, idleId} {
+		if err = events.RemoveEvent(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertIds(t, func() ([]string, error) { return events.GetEndedEventIds(ctx, endsAt) }, []string{})
+	assertIds(t, func() ([]string, error) { return events.GetIdleEventIds(ctx, startsAt) }, []string{})
+}
+
+func testGeoEventsContract(t *testing.T, events Events) {
+	ctx := context.Background()
+	ids := make([]string, 3)
+	for i, x := range []float64{-179.5, 179.8, 170} {
+		id, err := events.CreateEvent(ctx, domain.Event{
+			OwnerID:     "geo_owner",
+			Name:        "geo",
+			Address:     "geo",
+			State:       domain.EventStateOpened,
+			Coordinates: domain.Coordinates{X: x, Y: -60},
+			Users:       []domain.UserInfo{},
+			Media:       []domain.MediaInfo{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+
+	legacyId, err := events.CreateEvent(ctx, domain.Event{
+		OwnerID:     "geo_legacy_owner",
+		Name:        "geo",
+		Address:     "geo",
+		State:       domain.EventStateOpened,
+		Coordinates: domain.Coordinates{X: 180.2, Y: -60},
+		Users:       []domain.UserInfo{},
+		Media:       []domain.MediaInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rangeData, err := events.GetEventsByRange(ctx, 179, -179, -61, -59)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rangeData) != 2 || rangeData[0].ID != ids[0] || rangeData[1].ID != ids[1] {
+		t.Fatalf("Incorrect range data across antimeridian: %+v", rangeData)
+	}
+
+	nearby, err := events.GetEventsNearby(ctx, domain.Coordinates{X: 180, Y: -60}, 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nearby) != 2 || nearby[0].ID != ids[1] || nearby[1].ID != ids[0] {
+		t.Fatalf("Incorrect nearby events: %+v", nearby)
+	}
+
+	if nearby[0].Distance == nil || *nearby[0].Distance < 10000 || *nearby[0].Distance > 12000 ||
+		nearby[1].Distance == nil || *nearby[1].Distance < 27000 || *nearby[1].Distance > 29000 {
+		t.Fatalf("Incorrect nearby distances: %v, %v", nearby[0].Distance, nearby[1].Distance)
+	}
+
+	for _, id := range append(ids, legacyId) {
+		if err = events.RemoveEvent(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nearby, err = events.GetEventsNearby(ctx, domain.Coordinates{X: 180, Y: -60}, 50000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nearby) != 0 {
+		t.Fatalf("Expected no nearby events, actual: %+v", nearby)
+	}
+}
+
+func testEventVisibilityContract(t *testing.T, events Events) {
+	ctx := context.Background()
+	id, err := events.CreateEvent(ctx, domain.Event{
+		OwnerID:     "private_owner",
+		Name:        "private",
+		Address:     "private",
+		State:       domain.EventStateOpened,
+		Visibility:  domain.EventVisibilityApproval,
+		Coordinates: domain.Coordinates{X: 30, Y: 30},
+		Users:       []domain.UserInfo{},
+		Media:       []domain.MediaInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rangeData, err := events.GetEventsByRange(ctx, 29, 31, 29, 31)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nearby, err := events.GetEventsNearby(ctx, domain.Coordinates{X: 30, Y: 30}, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rangeData) != 0 || len(nearby) != 0 {
+		t.Fatalf("Expected private event to be hidden, range: %+v, nearby: %+v", rangeData, nearby)
+	}
+
+	createdAt := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, userId := range []string{"requester_1", "requester_2"} {
+		joinRequest := domain.JoinRequest{UserID: userId, UserName: userId, UserImageID: "image_id", CreatedAt: createdAt}
+		if err = events.AddJoinRequest(ctx, id, joinRequest); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertErr(t, func() error {
+		return events.AddJoinRequest(ctx, id, domain.JoinRequest{UserID: "requester_1", CreatedAt: createdAt})
+	}, domain.ErrJoinRequestAlreadyExist)
+
+	if err = events.ApproveJoinRequest(ctx, id, "requester_1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.RemoveJoinRequest(ctx, id, "requester_2"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { return events.RemoveJoinRequest(ctx, id, "requester_2") }, domain.ErrJoinRequestNotFound)
+
+	if err = events.AddMember(ctx, id, domain.UserInfo{ID: "invited"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.AddMember(ctx, id, domain.UserInfo{ID: "invited"}); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := events.GetEventById(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event.Visibility != domain.EventVisibilityApproval || len(event.JoinRequests) != 0 || len(event.Members) != 2 ||
+		event.Members[0].ID != "requester_1" || event.Members[1].ID != "invited" {
+		t.Fatalf("Incorrect event access: %+v", event)
+	}
+
+	if err = events.RemoveEvent(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { return events.AddMember(ctx, id, domain.UserInfo{ID: "late"}) }, domain.ErrEventNotFound)
+}
+
+func testEventRolesContract(t *testing.T, events Events) {
+	ctx := context.Background()
+	id, err := events.CreateEvent(ctx, domain.Event{
+		OwnerID:     "roles_owner",
+		Name:        "roles",
+		Address:     "roles",
+		State:       domain.EventStateOpened,
+		Coordinates: domain.Coordinates{X: 40, Y: 40},
+		Users:       []domain.UserInfo{},
+		Media:       []domain.MediaInfo{},
+		Roles:       []domain.EventRoleInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roles := []domain.EventRoleInfo{
+		{UserID: "moderator", Role: domain.EventRoleModerator},
+		{UserID: "co_host", Role: domain.EventRoleModerator},
+		{UserID: "co_host", Role: domain.EventRoleCoHost},
+	}
+
+	for _, role := range roles {
+		if err = events.SetRole(ctx, id, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = events.RemoveRole(ctx, id, "moderator"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { return events.RemoveRole(ctx, id, "moderator") }, domain.ErrRoleNotFound)
+
+	event, err := events.GetEventById(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(event.Roles) != 1 || event.Roles[0].UserID != "co_host" || event.Roles[0].Role != domain.EventRoleCoHost {
+		t.Fatalf("Incorrect event roles: %+v", event.Roles)
+	}
+
+	if err = events.RemoveEvent(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error {
+		return events.SetRole(ctx, id, domain.EventRoleInfo{UserID: "late", Role: domain.EventRoleModerator})
+	}, domain.ErrEventNotFound)
+}
+
+func testEventModerationContract(t *testing.T, events Events) {
+	ctx := context.Background()
+	id, err := events.CreateEvent(ctx, domain.Event{
+		OwnerID:     "moderation_owner",
+		Name:        "moderation",
+		Address:     "moderation",
+		State:       domain.EventStateOpened,
+		Coordinates: domain.Coordinates{X: 50, Y: 50},
+		Users:       []domain.UserInfo{},
+		Media:       []domain.MediaInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	until := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, mute := range []domain.EventMute{{UserID: "muted", Until: until}, {UserID: "muted", Until: until.Add(time.Hour)}} {
+		if err = events.SetMute(ctx, id, mute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = events.AddUserInfo(ctx, id, domain.UserInfo{ID: "banned"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.AddMember(ctx, id, domain.UserInfo{ID: "banned"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.SetRole(ctx, id, domain.EventRoleInfo{UserID: "banned", Role: domain.EventRoleModerator}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.AddJoinRequest(ctx, id, domain.JoinRequest{UserID: "banned", CreatedAt: until}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = events.BanUser(ctx, id, "banned"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	event, err := events.GetEventById(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(event.Mutes) != 1 || event.Mutes[0].UserID != "muted" || !event.Mutes[0].Until.Equal(until.Add(time.Hour)) {
+		t.Fatalf("Incorrect event mutes: %+v", event.Mutes)
+	}
+
+	if len(event.BannedUsers) != 1 || event.BannedUsers[0].ID != "banned" || len(event.Users) != 0 ||
+		len(event.Members) != 0 || len(event.Roles) != 0 || len(event.JoinRequests) != 0 {
+		t.Fatalf("Incorrect banned user: %+v", event)
+	}
+
+	messageId, err := events.AddChatMessage(ctx, id, domain.ChatMessage{UserID: "muted", Message: "message", CreatedAt: until})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { return events.RemoveChatMessage(ctx, "other_event", messageId) }, domain.ErrChatMessageNotFound)
+
+	if err = events.RemoveChatMessage(ctx, id, messageId); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { return events.RemoveChatMessage(ctx, id, messageId) }, domain.ErrChatMessageNotFound)
+	assertChatMessages(t, events, id, "", "", 10, []string{})
+
+	if err = events.RemoveEvent(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	assertErr(t, func() error { return events.BanUser(ctx, id, "late") }, domain.ErrEventNotFound)
+}
+
+func testRichChatContract(t *testing.T, events Events) {
+	ctx := context.Background()
+	id, err := events.CreateEvent(ctx, domain.Event{
+		OwnerID:     "rich_chat_owner",
+		Name:        "rich_chat",
+		Address:     "rich_chat",
+		State:       domain.EventStateOpened,
+		Coordinates: domain.Coordinates{X: 60, Y: 60},
+		Users:       []domain.UserInfo{},
+		Media:       []domain.MediaInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createdAt := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	firstId, err := events.AddChatMessage(ctx, id, domain.ChatMessage{UserID: "author", Message: "first", CreatedAt: createdAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replyId, err := events.AddChatMessage(ctx, id, domain.ChatMessage{
+		UserID:      "author",
+		Message:     "reply",
+		CreatedAt:   createdAt.Add(time.Minute),
+		ReplyToID:   firstId,
+		Attachments: []domain.MediaInfo{{ID: "media_id", ContentType: "image/png"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited, err := events.EditChatMessage(ctx, id, firstId, "edited", domain.ChatMessageEdit{Message: "first", EditedAt: createdAt.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if edited.Message != "edited" || len(edited.Edits) != 1 || edited.Edits[0].Message != "first" || !edited.Edits[0].EditedAt.Equal(createdAt.Add(time.Hour)) {
+		t.Fatalf("Incorrect edited message: %+v", edited)
+	}
+
+	reactions := []domain.ChatReaction{{UserID: "user_1", Emoji: "fire"}, {UserID: "user_2", Emoji: "fire"}, {UserID: "user_1", Emoji: "fire"}}
+	var reacted domain.ChatMessage
+	for _, reaction := range reactions {
+		if reacted, err = events.ToggleChatReaction(ctx, id, replyId, reaction); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(reacted.Reactions) != 1 || reacted.Reactions[0] != reactions[1] {
+		t.Fatalf("Incorrect reactions: %+v", reacted.Reactions)
+	}
+
+	reply, err := events.GetChatMessage(ctx, id, replyId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reply.ReplyToID != firstId || len(reply.Attachments) != 1 || reply.Attachments[0].ID != "media_id" ||
+		reply.Attachments[0].ContentType != "image/png" || len(reply.Reactions) != 1 || len(reply.Edits) != 0 {
+		t.Fatalf("Incorrect reply: %+v", reply)
+	}
+
+	assertChatMessages(t, events, id, "", "", 10, []string{firstId, replyId})
+	assertErr(t, func() error {
+		_, err := events.GetChatMessage(ctx, "other_event", replyId)
+		return err
+	}, domain.ErrChatMessageNotFound)
+	assertErr(t, func() error {
+		_, err := events.EditChatMessage(ctx, id, "unknown", "edited", domain.ChatMessageEdit{EditedAt: createdAt})
+		return err
+	}, domain.ErrChatMessageNotFound)
+	assertErr(t, func() error {
+		_, err := events.ToggleChatReaction(ctx, id, "unknown", reactions[0])
+		return err
+	}, domain.ErrChatMessageNotFound)
+
+	if err = events.RemoveEvent(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertIds(t *testing.T, get func() ([]string, error), expected []string) {
+	t.Helper()
+	actual, err := get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actual) != len(expected) {
+		t.Fatalf("Incorrect ids\nExpected: %v\nActual: %v", expected, actual)
+	}
+
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("Incorrect ids\nExpected: %v\nActual: %v", expected, actual)
+		}
+	}
+}
+
+func assertCount(t *testing.T, count func(ctx context.Context, value string) (int64, error), value string, expected int64) {
+	t.Helper()
+	actual, err := count(context.Background(), value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual != expected {
+		t.Fatalf("Incorrect count for %s\nExpected: %d\nActual: %d", value, expected, actual)
+	}
+}
+
+func assertErr(t *testing.T, action func() error, expected error) {
+	t.Helper()
+	if err := action(); !errors.Is(err, expected) {
+		t.Fatalf("Expected error: %v\nActual error: %v", expected, err)
+	}
+}
+
+func assertChatMessages(t *testing.T, events Events, eventId string, beforeId string, afterId string, limit int, expected []string) {
+	t.Helper()
+	chatMessages, err := events.GetChatMessages(context.Background(), eventId, beforeId, afterId, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := make([]string, len(chatMessages))
+	for i, chatMessage := range chatMessages {
+		actual[i] = chatMessage.ID
+	}
+
+	if len(actual) != len(expected) {
+		t.Fatalf("Incorrect chat messages\nExpected: %v\nActual: %v", expected, actual)
+	}
+
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("Incorrect chat messages\nExpected: %v\nActual: %v", expected, actual)
+		}
+	}
+}
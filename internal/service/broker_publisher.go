@@ -0,0 +1,106 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/broker"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+)
+
+type brokerMessage struct {
+	EventID string `json:"eventId"`
+	Close   bool   `json:"close"`
+	Message []byte `json:"message,omitempty"`
+}
+
+type brokerPublisher struct {
+	logger logger.Logger
+	broker broker.Broker
+	local  *publisher
+}
+
+func newBrokerPublisher(logger logger.Logger, broker broker.Broker, options PublisherOptions) (Publisher, error) {
+	local, err := newPublisher(options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &brokerPublisher{
+		logger: logger,
+		broker: broker,
+		local:  local,
+	}
+
+	if err = broker.Listen(p.onBrokerMessage); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *brokerPublisher) Subscribe(eventId string, subscriber Subscriber) {
+	p.local.Subscribe(eventId, subscriber)
+}
+
+// Resume asks the broker for the number of the last message of the event,
+// so a subscriber resumed on an instance that didn't get that message yet still resyncs to it.
+func (p *brokerPublisher) Resume(eventId string, lastSeq uint64, subscriber Subscriber) {
+	currentSeq, err := p.broker.Seq(eventId)
+
+	if err != nil {
+		p.logger.LogError(err)
+	}
+
+	p.local.resume(eventId, lastSeq, currentSeq, subscriber)
+}
+
+func (p *brokerPublisher) Unsubscribe(eventId string, subscriber Subscriber) {
+	p.local.Unsubscribe(eventId, subscriber)
+}
+
+func (p *brokerPublisher) Publish(eventId string, message []byte) {
+	p.publish(brokerMessage{EventID: eventId, Message: message})
+}
+
+func (p *brokerPublisher) Close(eventId string) {
+	p.publish(brokerMessage{EventID: eventId, Close: true})
+}
+
+// CloseAll closes the local subscribers only, the broker is shared with the presence and is closed by its owner.
+func (p *brokerPublisher) CloseAll() {
+	p.local.CloseAll()
+}
+
+func (p *brokerPublisher) Metrics() PublisherMetrics {
+	return p.local.Metrics()
+}
+
+func (p *brokerPublisher) publish(message brokerMessage) {
+	data, err := json.Marshal(message)
+
+	if err != nil {
+		p.logger.LogError(err)
+		return
+	}
+
+	if err = p.broker.Publish(message.EventID, data); err != nil {
+		p.logger.LogError(err)
+	}
+}
+
+func (p *brokerPublisher) onBrokerMessage(seq uint64, data []byte) {
+	message := brokerMessage{}
+
+	if err := json.Unmarshal(data, &message); err != nil {
+		p.logger.LogError(err)
+		return
+	}
+
+	if message.Close {
+		p.local.Close(message.EventID)
+		return
+	}
+
+	p.local.publish(message.EventID, seq, message.Message)
+}
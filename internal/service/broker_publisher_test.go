@@ -0,0 +1,162 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/broker"
+)
+
+type testLogger struct {
+	t *testing.T
+}
+
+func (l testLogger) LogError(err error) {
+	l.t.Error(err)
+}
+
+func (l testLogger) LogInfo(string) {}
+
+func (l testLogger) LogWarning(string) {}
+
+func (l testLogger) LogHttpRequest(string, string, string, string) {}
+
+func (l testLogger) LogHttpResponse(string, string, int, string, string) {}
+
+type testSubscriber struct {
+	messages chan string
+	closed   chan struct{}
+	seqs     chan uint64
+}
+
+func newTestSubscriber() *testSubscriber {
+	return &testSubscriber{
+		messages: make(chan string, 10),
+		closed:   make(chan struct{}, 1),
+	}
+}
+
+func (s *testSubscriber) OnReceive(seq uint64, message []byte) {
+	if s.seqs != nil {
+		s.seqs <- seq
+	}
+	s.messages <- string(message)
+}
+
+func (s *testSubscriber) OnClose() {
+	s.closed <- struct{}{}
+}
+
+func TestBrokerPublisherFanOut(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer server.Close()
+	first := newTestBrokerPublisher(t, "redis://"+server.Addr())
+	second := newTestBrokerPublisher(t, "redis://"+server.Addr())
+	defer first.CloseAll()
+	defer second.CloseAll()
+
+	firstSubscriber := newTestSubscriber()
+	secondSubscriber := newTestSubscriber()
+	otherEventSubscriber := newTestSubscriber()
+	first.Subscribe("event_id", firstSubscriber)
+	second.Subscribe("event_id", secondSubscriber)
+	second.Subscribe("other_event_id", otherEventSubscriber)
+
+	first.Publish("event_id", []byte("chatMessage/hello"))
+	assertReceived(t, firstSubscriber, "chatMessage/hello")
+	assertReceived(t, secondSubscriber, "chatMessage/hello")
+
+	second.Close("event_id")
+	assertClosed(t, firstSubscriber)
+	assertClosed(t, secondSubscriber)
+
+	select {
+	case message := <-otherEventSubscriber.messages:
+		t.Fatalf("Unexpected message for other event: %s", message)
+	case <-otherEventSubscriber.closed:
+		t.Fatal("Unexpected close for other event")
+	default:
+	}
+}
+
+func TestBrokerPublisherSharedSequence(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer server.Close()
+	first := newTestBrokerPublisher(t, "redis://"+server.Addr())
+	second := newTestBrokerPublisher(t, "redis://"+server.Addr())
+	defer first.CloseAll()
+	defer second.CloseAll()
+
+	live := newTestSequencedSubscriber()
+	first.Subscribe("event_id", live)
+	first.Publish("event_id", []byte("chatMessage/1"))
+	assertReceivedSeq(t, live, "chatMessage/1", 1)
+	second.Publish("event_id", []byte("chatMessage/2"))
+	assertReceivedSeq(t, live, "chatMessage/2", 2)
+	first.Publish("event_id", []byte("chatMessage/3"))
+	assertReceivedSeq(t, live, "chatMessage/3", 3)
+
+	resumed := newTestSequencedSubscriber()
+	second.Resume("event_id", 1, resumed)
+	assertReceivedSeq(t, resumed, "chatMessage/2", 2)
+	assertReceivedSeq(t, resumed, "chatMessage/3", 3)
+
+	third := newTestBrokerPublisher(t, "redis://"+server.Addr())
+	defer third.CloseAll()
+	reconnected := newTestSequencedSubscriber()
+	third.Resume("event_id", 3, reconnected)
+	assertReceivedSeq(t, reconnected, resyncMessage, 3)
+
+	second.Publish("event_id", []byte("chatMessage/4"))
+	assertReceivedSeq(t, live, "chatMessage/4", 4)
+	assertReceivedSeq(t, resumed, "chatMessage/4", 4)
+	assertReceivedSeq(t, reconnected, "chatMessage/4", 4)
+}
+
+func newTestBrokerPublisher(t *testing.T, connectionString string) Publisher {
+	t.Helper()
+	messageBroker, err := broker.NewRedisBroker(connectionString, "vpiska:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = messageBroker.Close()
+	})
+	pub, err := newBrokerPublisher(testLogger{t: t}, messageBroker, PublisherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pub
+}
+
+func assertReceived(t *testing.T, subscriber *testSubscriber, expected string) {
+	t.Helper()
+	select {
+	case message := <-subscriber.messages:
+		if message != expected {
+			t.Fatalf("Incorrect message\nExpected: %s\nActual: %s", expected, message)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("Message %s was not received", expected)
+	}
+}
+
+func assertClosed(t *testing.T, subscriber *testSubscriber) {
+	t.Helper()
+	select {
+	case <-subscriber.closed:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Subscriber was not closed")
+	}
+}
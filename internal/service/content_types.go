@@ -0,0 +1,81 @@
+package service
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// sniffLength is how many first bytes of a file are enough to detect its type.
+const sniffLength = 512
+
+var (
+	ImageContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "image/heic"}
+	VideoContentTypes = []string{"video/mp4", "video/quicktime", "video/webm"}
+	MediaContentTypes = append(append([]string{}, ImageContentTypes...), VideoContentTypes...)
+)
+
+// ftypBrands maps the brands of ISO media files, which http.DetectContentType mostly doesn't know, to their types.
+var ftypBrands = map[string]string{
+	"qt  ": "video/quicktime",
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"hevc": "image/heic",
+	"heim": "image/heic",
+	"heis": "image/heic",
+	"mif1": "image/heic",
+	"msf1": "image/heic",
+	"isom": "video/mp4",
+	"iso2": "video/mp4",
+	"iso4": "video/mp4",
+	"iso5": "video/mp4",
+	"iso6": "video/mp4",
+	"mp41": "video/mp4",
+	"mp42": "video/mp4",
+	"avc1": "video/mp4",
+	"M4V ": "video/mp4",
+	"M4VP": "video/mp4",
+	"dash": "video/mp4",
+	"mmp4": "video/mp4",
+	"MSNV": "video/mp4",
+	"3gp4": "video/mp4",
+	"3gp5": "video/mp4",
+	"3gp6": "video/mp4",
+	"3g2a": "video/mp4",
+}
+
+// detectContentType detects the type by the magic bytes of the file start, the declared type is never trusted.
+func detectContentType(head []byte) string {
+	if len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")) {
+		if contentType, ok := ftypBrands[string(head[8:12])]; ok {
+			return contentType
+		}
+	}
+
+	contentType := http.DetectContentType(head)
+
+	if index := strings.Index(contentType, ";"); index >= 0 {
+		contentType = contentType[:index]
+	}
+
+	return contentType
+}
+
+// IsMediaContentType reports whether files of the type can be served inline.
+func IsMediaContentType(contentType string) bool {
+	return containsContentType(MediaContentTypes, contentType)
+}
+
+func isImageContentType(contentType string) bool {
+	return containsContentType(ImageContentTypes, contentType)
+}
+
+func containsContentType(contentTypes []string, contentType string) bool {
+	for _, item := range contentTypes {
+		if item == contentType {
+			return true
+		}
+	}
+
+	return false
+}
@@ -4,26 +4,42 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"math"
 	"time"
 
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
 )
 
+const (
+	latestChatMessagesCount = 50
+	defaultChatHistoryLimit = 50
+
+	// maxClusterZoom is the map zoom level starting from which events are returned individually.
+	maxClusterZoom = 15
+	// clusterCellTiles is the number of 256px map tiles along the side of a grid cell, one cell covers 64px.
+	clusterCellTiles = 0.25
+
+	inviteTokenTTL = time.Hour * 24 * 7
+)
+
 type eventService struct {
 	logger      logger.Logger
 	repository  repository.Events
 	publisher   Publisher
 	fileStorage Media
+	auth        auth.TokenManager
 }
 
-func NewEventService(logger logger.Logger, events repository.Events, publisher Publisher, fileStorage Media) Events {
+func NewEventService(logger logger.Logger, events repository.Events, publisher Publisher, fileStorage Media, auth auth.TokenManager) Events {
 	return &eventService{
 		logger:      logger,
 		repository:  events,
 		publisher:   publisher,
 		fileStorage: fileStorage,
+		auth:        auth,
 	}
 }
 
@@ -39,15 +55,22 @@ func (s *eventService) Create(ctx context.Context, input CreateEventInput) (doma
 	}
 
 	event := domain.Event{
-		OwnerID:      input.OwnerID,
-		Name:         input.Name,
-		Address:      input.Address,
-		Coordinates:  input.Coordinates,
-		State:        domain.EventStateOpened,
-		CreatedAt:    time.Now(),
-		Users:        []domain.UserInfo{},
-		Media:        []domain.MediaInfo{},
-		ChatMessages: []domain.ChatMessage{},
+		OwnerID:     input.OwnerID,
+		Name:        input.Name,
+		Address:     input.Address,
+		Coordinates: input.Coordinates,
+		State:       domain.EventStateOpened,
+		Visibility:  input.Visibility,
+		CreatedAt:   time.Now(),
+		StartsAt:    input.StartsAt,
+		EndsAt:      input.EndsAt,
+		Users:       []domain.UserInfo{},
+		Media:       []domain.MediaInfo{},
+		Roles:       []domain.EventRoleInfo{},
+	}
+
+	if event.StartsAt != nil && event.StartsAt.After(event.CreatedAt) {
+		event.State = domain.EventStateScheduled
 	}
 
 	id, err := s.repository.CreateEvent(ctx, event)
@@ -61,30 +84,84 @@ func (s *eventService) Create(ctx context.Context, input CreateEventInput) (doma
 		OwnerID:      event.OwnerID,
 		Name:         event.Name,
 		Address:      event.Address,
+		State:        event.State,
+		Visibility:   event.Visibility,
+		StartsAt:     event.StartsAt,
+		EndsAt:       event.EndsAt,
 		Coordinates:  event.Coordinates,
 		UsersCount:   len(event.Users),
 		Media:        event.Media,
-		ChatMessages: event.ChatMessages,
+		Roles:        event.Roles,
+		ChatMessages: []domain.ChatMessage{},
 	}, nil
 }
 
 func (s *eventService) Close(ctx context.Context, eventId string, userId string) error {
-	event, err := s.repository.GetEventById(ctx, eventId)
+	if _, err := s.authorize(ctx, eventId, userId, permissionCloseEvent); err != nil {
+		return err
+	}
+
+	return s.closeEvent(ctx, eventId)
+}
+
+func (s *eventService) OpenScheduled(ctx context.Context, now time.Time) error {
+	ids, err := s.repository.GetScheduledEventIds(ctx, now)
 
 	if err != nil {
 		return err
 	}
 
-	if event.OwnerID != userId {
-		return domain.ErrUserIsNotOwner
+	for _, id := range ids {
+		if err = s.repository.OpenEvent(ctx, id); err != nil && !errors.Is(err, domain.ErrEventNotFound) {
+			return err
+		}
 	}
 
-	err = s.repository.RemoveEvent(ctx, eventId)
+	return nil
+}
+
+func (s *eventService) CloseExpired(ctx context.Context, now time.Time, inactivityTimeout time.Duration) error {
+	ids, err := s.repository.GetEndedEventIds(ctx, now)
 
 	if err != nil {
 		return err
 	}
 
+	if inactivityTimeout > 0 {
+		inactiveSince := now.Add(-inactivityTimeout)
+		idleIds, err := s.repository.GetIdleEventIds(ctx, inactiveSince)
+
+		if err != nil {
+			return err
+		}
+
+		for _, id := range idleIds {
+			lastChatMessages, err := s.repository.GetChatMessages(ctx, id, "", "", 1)
+
+			if err != nil {
+				return err
+			}
+
+			if len(lastChatMessages) == 0 || !lastChatMessages[0].CreatedAt.After(inactiveSince) {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	for _, id := range ids {
+		if err = s.closeEvent(ctx, id); err != nil && !errors.Is(err, domain.ErrEventNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *eventService) closeEvent(ctx context.Context, eventId string) error {
+	if err := s.repository.RemoveEvent(ctx, eventId); err != nil {
+		return err
+	}
+
 	s.publisher.Publish(eventId, []byte("closeEvent/"))
 	s.publisher.Close(eventId)
 	return nil
@@ -99,16 +176,12 @@ type eventUpdated struct {
 }
 
 func (s *eventService) Update(ctx context.Context, input UpdateEventInput) error {
-	event, err := s.repository.GetEventById(ctx, input.EventID)
+	event, err := s.authorize(ctx, input.EventID, input.UserID, permissionUpdateEvent)
 
 	if err != nil {
 		return err
 	}
 
-	if event.OwnerID != input.UserID {
-		return domain.ErrUserIsNotOwner
-	}
-
 	err = s.repository.UpdateEvent(ctx, input.EventID, input.Address, input.Coordinates)
 
 	if err != nil {
@@ -131,30 +204,404 @@ func (s *eventService) Update(ctx context.Context, input UpdateEventInput) error
 	return nil
 }
 
-func (s *eventService) GetByID(ctx context.Context, id string) (domain.EventInfo, error) {
-	event, err := s.repository.GetEventById(ctx, id)
+func (s *eventService) GetByID(ctx context.Context, input EventAccessInput) (domain.EventInfo, error) {
+	event, err := s.repository.GetEventById(ctx, input.EventID)
+
+	if err != nil {
+		return domain.EventInfo{}, err
+	}
+
+	if err = s.checkAccess(ctx, event, input.UserID, input.InviteToken); err != nil {
+		return domain.EventInfo{}, err
+	}
+
+	chatMessages, err := s.repository.GetChatMessages(ctx, input.EventID, "", "", latestChatMessagesCount)
 
 	if err != nil {
 		return domain.EventInfo{}, err
 	}
 
+	if event.Roles == nil {
+		event.Roles = []domain.EventRoleInfo{}
+	}
+
 	return domain.EventInfo{
 		ID:           event.ID,
 		OwnerID:      event.OwnerID,
 		Name:         event.Name,
 		Address:      event.Address,
+		State:        event.State,
+		Visibility:   event.Visibility,
+		StartsAt:     event.StartsAt,
+		EndsAt:       event.EndsAt,
 		Coordinates:  event.Coordinates,
 		UsersCount:   len(event.Users),
 		Media:        event.Media,
-		ChatMessages: event.ChatMessages,
+		Roles:        event.Roles,
+		ChatMessages: chatMessages,
+	}, nil
+}
+
+func (s *eventService) CheckAccess(ctx context.Context, input EventAccessInput) error {
+	event, err := s.repository.GetEventById(ctx, input.EventID)
+
+	if err != nil {
+		return err
+	}
+
+	return s.checkAccess(ctx, event, input.UserID, input.InviteToken)
+}
+
+// checkAccess lets the owner and members into any event, anyone into public events and
+// holders of a valid invite token into link-only events. Invited users are remembered as
+// members, so that they do not need the token again.
+func (s *eventService) checkAccess(ctx context.Context, event domain.Event, userId string, inviteToken string) error {
+	if userId != "" && containsUser(event.BannedUsers, userId) {
+		return domain.ErrUserBanned
+	}
+
+	if event.Visibility == domain.EventVisibilityPublic {
+		return nil
+	}
+
+	if roleOf(event, userId) > domain.EventRoleGuest || (userId != "" && containsUser(event.Members, userId)) {
+		return nil
+	}
+
+	if event.Visibility != domain.EventVisibilityLink || inviteToken == "" {
+		return domain.ErrEventAccessDenied
+	}
+
+	eventId, err := s.auth.ParseInviteToken(inviteToken)
+
+	if err != nil || eventId != event.ID {
+		return domain.ErrInvalidInviteToken
+	}
+
+	if userId == "" {
+		return nil
+	}
+
+	return s.repository.AddMember(ctx, event.ID, domain.UserInfo{ID: userId})
+}
+
+func (s *eventService) CreateInvite(ctx context.Context, eventId string, userId string) (string, error) {
+	event, err := s.authorize(ctx, eventId, userId, permissionManageAccess)
+
+	if err != nil {
+		return "", err
+	}
+
+	if event.Visibility != domain.EventVisibilityLink {
+		return "", domain.ErrInviteNotAllowed
+	}
+
+	return s.auth.GetInviteToken(eventId, inviteTokenTTL)
+}
+
+func (s *eventService) RequestJoin(ctx context.Context, input JoinRequestInput) error {
+	event, err := s.repository.GetEventById(ctx, input.EventID)
+
+	if err != nil {
+		return err
+	}
+
+	if event.Visibility != domain.EventVisibilityApproval {
+		return domain.ErrJoinRequestNotAllowed
+	}
+
+	if containsUser(event.BannedUsers, input.UserID) {
+		return domain.ErrUserBanned
+	}
+
+	if roleOf(event, input.UserID) > domain.EventRoleGuest || containsUser(event.Members, input.UserID) {
+		return domain.ErrUserAlreadyMember
+	}
+
+	return s.repository.AddJoinRequest(ctx, input.EventID, domain.JoinRequest{
+		UserID:      input.UserID,
+		UserName:    input.UserName,
+		UserImageID: input.UserImageID,
+		CreatedAt:   time.Now(),
+	})
+}
+
+func (s *eventService) GetJoinRequests(ctx context.Context, eventId string, userId string) ([]domain.JoinRequest, error) {
+	event, err := s.authorize(ctx, eventId, userId, permissionManageAccess)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if event.JoinRequests == nil {
+		return []domain.JoinRequest{}, nil
+	}
+
+	return event.JoinRequests, nil
+}
+
+func (s *eventService) ApproveJoinRequest(ctx context.Context, input ReviewJoinRequestInput) error {
+	if _, err := s.authorize(ctx, input.EventID, input.UserID, permissionManageAccess); err != nil {
+		return err
+	}
+
+	if err := s.repository.ApproveJoinRequest(ctx, input.EventID, input.RequesterID); err != nil {
+		return err
+	}
+
+	return s.notifyUser(input.RequesterID, "joinRequestApproved/", eventNotification{EventID: input.EventID})
+}
+
+func (s *eventService) RejectJoinRequest(ctx context.Context, input ReviewJoinRequestInput) error {
+	if _, err := s.authorize(ctx, input.EventID, input.UserID, permissionManageAccess); err != nil {
+		return err
+	}
+
+	if err := s.repository.RemoveJoinRequest(ctx, input.EventID, input.RequesterID); err != nil {
+		return err
+	}
+
+	return s.notifyUser(input.RequesterID, "joinRequestRejected/", eventNotification{EventID: input.EventID})
+}
+
+type roleChanged struct {
+	EventID string           `json:"eventId,omitempty"`
+	UserID  string           `json:"userId"`
+	Role    domain.EventRole `json:"role"`
+}
+
+func (s *eventService) GrantRole(ctx context.Context, input EventRoleInput) error {
+	event, err := s.authorize(ctx, input.EventID, input.UserID, permissionManageRoles)
+
+	if err != nil {
+		return err
+	}
+
+	if input.Role != domain.EventRoleModerator && input.Role != domain.EventRoleCoHost {
+		return domain.ErrInvalidRole
+	}
+
+	if input.TargetUserID == event.OwnerID {
+		return domain.ErrPermissionDenied
+	}
+
+	if err = s.repository.SetRole(ctx, input.EventID, domain.EventRoleInfo{UserID: input.TargetUserID, Role: input.Role}); err != nil {
+		return err
+	}
+
+	return s.publishRoleChanged(input.EventID, input.TargetUserID, input.Role)
+}
+
+func (s *eventService) RevokeRole(ctx context.Context, input EventRoleInput) error {
+	if _, err := s.authorize(ctx, input.EventID, input.UserID, permissionManageRoles); err != nil {
+		return err
+	}
+
+	if err := s.repository.RemoveRole(ctx, input.EventID, input.TargetUserID); err != nil {
+		return err
+	}
+
+	return s.publishRoleChanged(input.EventID, input.TargetUserID, domain.EventRoleGuest)
+}
+
+func (s *eventService) publishRoleChanged(eventId string, userId string, role domain.EventRole) error {
+	data, err := json.Marshal(roleChanged{
+		UserID: userId,
+		Role:   role,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	s.publisher.Publish(eventId, []byte("roleChanged/"+string(data)))
+	return s.notifyUser(userId, "roleChanged/", roleChanged{EventID: eventId, UserID: userId, Role: role})
+}
+
+type eventNotification struct {
+	EventID string `json:"eventId"`
+}
+
+type chatReply struct {
+	EventID string             `json:"eventId"`
+	Message domain.ChatMessage `json:"message"`
+}
+
+// UserTopic is the publisher topic of the notifications addressed to the user, like role changes or replies.
+func UserTopic(userId string) string {
+	return "user:" + userId
+}
+
+func (s *eventService) notifyUser(userId string, prefix string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	s.publisher.Publish(UserTopic(userId), []byte(prefix+string(data)))
+	return nil
+}
+
+type userMuted struct {
+	UserID string    `json:"userId"`
+	Until  time.Time `json:"until"`
+}
+
+type userRemoved struct {
+	UserID string `json:"userId"`
+}
+
+func (s *eventService) DeleteChatMessage(ctx context.Context, input DeleteChatMessageInput) error {
+	if _, err := s.authorize(ctx, input.EventID, input.UserID, permissionModerateChat); err != nil {
+		return err
+	}
+
+	if err := s.repository.RemoveChatMessage(ctx, input.EventID, input.MessageID); err != nil {
+		return err
+	}
+
+	s.publisher.Publish(input.EventID, []byte("chatMessageDeleted/"+input.MessageID))
+	return nil
+}
+
+func (s *eventService) MuteUser(ctx context.Context, input MuteUserInput) error {
+	if _, err := s.authorizeOver(ctx, input.EventID, input.UserID, input.TargetUserID, permissionModerateChat); err != nil {
+		return err
+	}
+
+	mute := domain.EventMute{UserID: input.TargetUserID, Until: time.Now().Add(input.Duration).UTC()}
+
+	if err := s.repository.SetMute(ctx, input.EventID, mute); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(userMuted(mute))
+
+	if err != nil {
+		return err
+	}
+
+	s.publisher.Publish(input.EventID, []byte("userMuted/"+string(data)))
+	return nil
+}
+
+// KickUser disconnects the user from the event, websocket handlers close the connection on userKicked.
+// Unlike BanUser it keeps the membership, so the user is able to come back.
+func (s *eventService) KickUser(ctx context.Context, input ModerateUserInput) error {
+	if _, err := s.authorizeOver(ctx, input.EventID, input.UserID, input.TargetUserID, permissionRemoveAttendees); err != nil {
+		return err
+	}
+
+	return s.publishUserRemoved(input.EventID, "userKicked/", input.TargetUserID)
+}
+
+func (s *eventService) BanUser(ctx context.Context, input ModerateUserInput) error {
+	if _, err := s.authorizeOver(ctx, input.EventID, input.UserID, input.TargetUserID, permissionRemoveAttendees); err != nil {
+		return err
+	}
+
+	if err := s.repository.BanUser(ctx, input.EventID, input.TargetUserID); err != nil {
+		return err
+	}
+
+	return s.publishUserRemoved(input.EventID, "userBanned/", input.TargetUserID)
+}
+
+func (s *eventService) publishUserRemoved(eventId string, prefix string, userId string) error {
+	data, err := json.Marshal(userRemoved{UserID: userId})
+
+	if err != nil {
+		return err
+	}
+
+	s.publisher.Publish(eventId, []byte(prefix+string(data)))
+	return nil
+}
+
+func isMuted(event domain.Event, userId string, now time.Time) bool {
+	for _, mute := range event.Mutes {
+		if mute.UserID == userId {
+			return mute.Until.After(now)
+		}
+	}
+
+	return false
+}
+
+func containsMedia(media []domain.MediaInfo, mediaId string) bool {
+	for _, mediaInfo := range media {
+		if mediaInfo.ID == mediaId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsUser(users []domain.UserInfo, userId string) bool {
+	for _, userInfo := range users {
+		if userInfo.ID == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *eventService) GetChatHistory(ctx context.Context, input ChatHistoryInput) (domain.ChatHistory, error) {
+	event, err := s.repository.GetEventById(ctx, input.EventID)
+
+	if err != nil {
+		return domain.ChatHistory{}, err
+	}
+
+	if err = s.checkAccess(ctx, event, input.UserID, input.InviteToken); err != nil {
+		return domain.ChatHistory{}, err
+	}
+
+	limit := input.Limit
+
+	if limit <= 0 {
+		limit = defaultChatHistoryLimit
+	}
+
+	chatMessages, err := s.repository.GetChatMessages(ctx, input.EventID, input.BeforeID, input.AfterID, limit+1)
+
+	if err != nil {
+		return domain.ChatHistory{}, err
+	}
+
+	hasMore := len(chatMessages) > limit
+
+	if hasMore {
+		if input.AfterID != "" {
+			chatMessages = chatMessages[:limit]
+		} else {
+			chatMessages = chatMessages[1:]
+		}
+	}
+
+	return domain.ChatHistory{
+		ChatMessages: chatMessages,
+		HasMore:      hasMore,
 	}, nil
 }
 
 func (s *eventService) GetByRange(ctx context.Context, input GetByRangeInput) ([]domain.EventRangeData, error) {
+	if input.HorizontalRange <= 0 || input.VerticalRange <= 0 {
+		return []domain.EventRangeData{}, nil
+	}
+
 	halfHorizontalRange := input.HorizontalRange / 2
 	halfVerticalRange := input.VerticalRange / 2
-	xLeft := input.Coordinates.X - halfHorizontalRange
-	xRight := input.Coordinates.X + halfHorizontalRange
+	xLeft, xRight := -180.0, 180.0
+
+	if input.HorizontalRange < 360 {
+		xLeft = normalizeLongitude(input.Coordinates.X - halfHorizontalRange)
+		xRight = normalizeLongitude(input.Coordinates.X + halfHorizontalRange)
+	}
+
 	yLeft := input.Coordinates.Y - halfVerticalRange
 	yRight := input.Coordinates.Y + halfVerticalRange
 	result, err := s.repository.GetEventsByRange(ctx, xLeft, xRight, yLeft, yRight)
@@ -166,6 +613,91 @@ func (s *eventService) GetByRange(ctx context.Context, input GetByRangeInput) ([
 	return result, err
 }
 
+func (s *eventService) GetNearby(ctx context.Context, input GetNearbyInput) ([]domain.EventRangeData, error) {
+	return s.repository.GetEventsNearby(ctx, input.Coordinates, input.Distance)
+}
+
+func (s *eventService) GetClusters(ctx context.Context, input GetClustersInput) (domain.EventClusters, error) {
+	events, err := s.GetByRange(ctx, GetByRangeInput{
+		HorizontalRange: input.HorizontalRange,
+		VerticalRange:   input.VerticalRange,
+		Coordinates:     input.Coordinates,
+	})
+
+	if err != nil {
+		return domain.EventClusters{}, err
+	}
+
+	if input.Zoom >= maxClusterZoom {
+		return domain.EventClusters{
+			Clusters: []domain.EventCluster{},
+			Events:   events,
+		}, nil
+	}
+
+	type cell struct {
+		x int
+		y int
+	}
+
+	cellSize := 360 / math.Pow(2, float64(input.Zoom)) * clusterCellTiles
+	cells := make(map[cell][]domain.EventRangeData)
+	order := make([]cell, 0)
+
+	for _, event := range events {
+		key := cell{
+			x: int(math.Floor((event.Coordinates.X + 180) / cellSize)),
+			y: int(math.Floor((event.Coordinates.Y + 90) / cellSize)),
+		}
+
+		if _, ok := cells[key]; !ok {
+			order = append(order, key)
+		}
+
+		cells[key] = append(cells[key], event)
+	}
+
+	result := domain.EventClusters{
+		Clusters: []domain.EventCluster{},
+		Events:   []domain.EventRangeData{},
+	}
+
+	for _, key := range order {
+		cellEvents := cells[key]
+
+		if len(cellEvents) == 1 {
+			result.Events = append(result.Events, cellEvents[0])
+			continue
+		}
+
+		cluster := domain.EventCluster{EventsCount: len(cellEvents)}
+
+		for _, event := range cellEvents {
+			cluster.Coordinates.X += event.Coordinates.X
+			cluster.Coordinates.Y += event.Coordinates.Y
+			cluster.UsersCount += event.UsersCount
+		}
+
+		cluster.Coordinates.X /= float64(len(cellEvents))
+		cluster.Coordinates.Y /= float64(len(cellEvents))
+		result.Clusters = append(result.Clusters, cluster)
+	}
+
+	return result, nil
+}
+
+// normalizeLongitude wraps the longitude into [-180, 180], so that a range crossing
+// the antimeridian comes out with the left bound greater than the right one.
+func normalizeLongitude(x float64) float64 {
+	x = math.Mod(x+180, 360)
+
+	if x < 0 {
+		x += 360
+	}
+
+	return x - 180
+}
+
 func (s *eventService) AddUserInfo(ctx context.Context, input AddUserInfoInput) error {
 	event, err := s.repository.GetEventById(ctx, input.EventID)
 
@@ -173,6 +705,10 @@ func (s *eventService) AddUserInfo(ctx context.Context, input AddUserInfoInput)
 		return err
 	}
 
+	if event.State == domain.EventStateScheduled {
+		return domain.ErrEventNotStarted
+	}
+
 	for _, userInfo := range event.Users {
 		if userInfo.ID == input.UserID {
 			return domain.ErrUserAlreadyExist
@@ -239,18 +775,55 @@ func (s *eventService) RemoveUserInfo(ctx context.Context, eventId string, userI
 }
 
 func (s *eventService) SendChatMessage(ctx context.Context, input ChatMessageInput) error {
+	event, err := s.repository.GetEventById(ctx, input.EventID)
+
+	if err != nil {
+		return err
+	}
+
 	chatMessage := domain.ChatMessage{
 		UserID:      input.UserID,
 		UserName:    input.UserName,
 		UserImageID: input.UserImageID,
 		Message:     input.Message,
+		CreatedAt:   time.Now(),
+		ReplyToID:   input.ReplyToID,
+	}
+
+	if isMuted(event, input.UserID, chatMessage.CreatedAt) {
+		return domain.ErrUserMuted
 	}
-	err := s.repository.AddChatMessage(ctx, input.EventID, chatMessage)
+
+	replyTo := domain.ChatMessage{}
+
+	if input.ReplyToID != "" {
+		if replyTo, err = s.repository.GetChatMessage(ctx, input.EventID, input.ReplyToID); err != nil {
+			return err
+		}
+	}
+
+	for _, mediaId := range input.AttachmentIDs {
+		media, mediaErr := s.fileStorage.GetMetadata(ctx, mediaId)
+
+		if mediaErr != nil {
+			return mediaErr
+		}
+
+		if media.OwnerID != input.UserID && !containsMedia(event.Media, media.ID) {
+			return domain.ErrPermissionDenied
+		}
+
+		chatMessage.Attachments = append(chatMessage.Attachments, domain.MediaInfo{ID: media.ID, ContentType: media.ContentType})
+	}
+
+	id, err := s.repository.AddChatMessage(ctx, input.EventID, chatMessage)
 
 	if err != nil {
 		return err
 	}
 
+	chatMessage.ID = id
+
 	data, err := json.Marshal(chatMessage)
 
 	if err != nil {
@@ -258,34 +831,113 @@ func (s *eventService) SendChatMessage(ctx context.Context, input ChatMessageInp
 	}
 
 	s.publisher.Publish(input.EventID, []byte("chatMessage/"+string(data)))
-	return nil
+
+	if replyTo.UserID == "" || replyTo.UserID == input.UserID {
+		return nil
+	}
+
+	return s.notifyUser(replyTo.UserID, "chatReply/", chatReply{EventID: input.EventID, Message: chatMessage})
 }
 
-func (s *eventService) AddMedia(ctx context.Context, input AddMediaInput) error {
+// EditChatMessage replaces the text of the user's own message and keeps the previous one in the edit history.
+func (s *eventService) EditChatMessage(ctx context.Context, input EditChatMessageInput) error {
 	event, err := s.repository.GetEventById(ctx, input.EventID)
 
 	if err != nil {
 		return err
 	}
 
-	if event.OwnerID != input.UserID {
-		return domain.ErrUserIsNotOwner
+	now := time.Now()
+
+	if isMuted(event, input.UserID, now) {
+		return domain.ErrUserMuted
+	}
+
+	chatMessage, err := s.repository.GetChatMessage(ctx, input.EventID, input.MessageID)
+
+	if err != nil {
+		return err
+	}
+
+	if chatMessage.UserID != input.UserID {
+		return domain.ErrPermissionDenied
+	}
+
+	chatMessage, err = s.repository.EditChatMessage(ctx, input.EventID, input.MessageID, input.Message, domain.ChatMessageEdit{
+		Message:  chatMessage.Message,
+		EditedAt: now,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return s.publishChatMessageUpdated(input.EventID, chatMessage)
+}
+
+// ToggleChatReaction adds the emoji reaction of the user to the message or removes it if it was already added.
+func (s *eventService) ToggleChatReaction(ctx context.Context, input ChatReactionInput) error {
+	event, err := s.repository.GetEventById(ctx, input.EventID)
+
+	if err != nil {
+		return err
+	}
+
+	if isMuted(event, input.UserID, time.Now()) {
+		return domain.ErrUserMuted
+	}
+
+	chatMessage, err := s.repository.ToggleChatReaction(ctx, input.EventID, input.MessageID, domain.ChatReaction{
+		UserID: input.UserID,
+		Emoji:  input.Emoji,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return s.publishChatMessageUpdated(input.EventID, chatMessage)
+}
+
+func (s *eventService) publishChatMessageUpdated(eventId string, chatMessage domain.ChatMessage) error {
+	data, err := json.Marshal(chatMessage)
+
+	if err != nil {
+		return err
+	}
+
+	s.publisher.Publish(eventId, []byte("chatMessageUpdated/"+string(data)))
+	return nil
+}
+
+func (s *eventService) AddMedia(ctx context.Context, input AddMediaInput) error {
+	if _, err := s.authorize(ctx, input.EventID, input.UserID, permissionAddMedia); err != nil {
+		return err
 	}
 
 	mediaId, err := s.fileStorage.Create(ctx, CreateMediaInput{
-		Name:        input.FileName,
-		ContentType: input.ContentType,
-		Size:        input.FileSize,
-		Data:        input.FileData,
+		Name:         input.FileName,
+		ContentType:  input.ContentType,
+		OwnerID:      input.UserID,
+		Size:         input.FileSize,
+		Data:         input.FileData,
+		AllowedTypes: MediaContentTypes,
 	})
 
 	if err != nil {
 		return err
 	}
 
+	// the stored type is detected from the file rather than declared by the client
+	media, err := s.fileStorage.GetMetadata(ctx, mediaId)
+
+	if err != nil {
+		return err
+	}
+
 	mediaInfo := domain.MediaInfo{
 		ID:          mediaId,
-		ContentType: input.ContentType,
+		ContentType: media.ContentType,
 	}
 	err = s.repository.AddMedia(ctx, input.EventID, mediaInfo)
 
@@ -304,16 +956,12 @@ func (s *eventService) AddMedia(ctx context.Context, input AddMediaInput) error
 }
 
 func (s *eventService) RemoveMedia(ctx context.Context, input RemoveMediaInput) error {
-	event, err := s.repository.GetEventById(ctx, input.EventID)
+	event, err := s.authorize(ctx, input.EventID, input.UserID, permissionRemoveMedia)
 
 	if err != nil {
 		return err
 	}
 
-	if event.OwnerID != input.UserID {
-		return domain.ErrUserIsNotOwner
-	}
-
 	for _, mediaInfo := range event.Media {
 		if mediaInfo.ID == input.MediaID {
 			err = s.fileStorage.Delete(ctx, input.MediaID)
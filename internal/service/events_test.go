@@ -0,0 +1,578 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
+)
+
+func TestEventsSchedule(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	events := NewEventService(testLogger{t: t}, repositories.Events, pub, nil, nil)
+	now := time.Now()
+	startsAt := now.Add(time.Hour)
+	endsAt := now.Add(time.Hour * 3)
+
+	scheduled, err := events.Create(ctx, CreateEventInput{OwnerID: "scheduled_owner", Name: "scheduled", StartsAt: &startsAt, EndsAt: &endsAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if scheduled.State != domain.EventStateScheduled {
+		t.Fatalf("Incorrect state\nExpected: %d\nActual: %d", domain.EventStateScheduled, scheduled.State)
+	}
+
+	if err = events.AddUserInfo(ctx, AddUserInfoInput{EventID: scheduled.ID, UserID: "user_id"}); err != domain.ErrEventNotStarted {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrEventNotStarted, err)
+	}
+
+	idleId := createTestEvent(t, repositories.Events, "idle_owner", now.Add(-time.Hour*2))
+	chattingId := createTestEvent(t, repositories.Events, "chatting_owner", now.Add(-time.Hour*2))
+	idleSubscriber := newTestSubscriber()
+	chattingSubscriber := newTestSubscriber()
+	scheduledSubscriber := newTestSubscriber()
+	pub.Subscribe(idleId, idleSubscriber)
+	pub.Subscribe(chattingId, chattingSubscriber)
+	pub.Subscribe(scheduled.ID, scheduledSubscriber)
+
+	if err = events.OpenScheduled(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEventState(t, events, scheduled.ID, domain.EventStateScheduled)
+
+	if err = events.OpenScheduled(ctx, startsAt); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEventState(t, events, scheduled.ID, domain.EventStateOpened)
+
+	if err = events.SendChatMessage(ctx, ChatMessageInput{EventID: chattingId, UserID: "user_id", Message: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceivedPrefix(t, chattingSubscriber, "chatMessage/")
+
+	if err = events.CloseExpired(ctx, now, time.Hour*3); err != nil {
+		t.Fatal(err)
+	}
+
+	assertEventState(t, events, idleId, domain.EventStateOpened)
+
+	if err = events.CloseExpired(ctx, now, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, idleSubscriber, "closeEvent/")
+	assertClosed(t, idleSubscriber)
+	assertEventState(t, events, chattingId, domain.EventStateOpened)
+	assertEventState(t, events, scheduled.ID, domain.EventStateOpened)
+
+	if err = events.CloseExpired(ctx, endsAt, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, scheduledSubscriber, "closeEvent/")
+	assertClosed(t, scheduledSubscriber)
+	assertEventState(t, events, chattingId, domain.EventStateOpened)
+
+	if _, err = events.GetByID(ctx, EventAccessInput{EventID: idleId}); err != domain.ErrEventNotFound {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrEventNotFound, err)
+	}
+}
+
+func TestEventsVisibility(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	tokenManager := auth.NewJwtManager("key", "issuer", "audience", time.Minute)
+	events := NewEventService(testLogger{t: t}, repositories.Events, pub, nil, tokenManager)
+	coordinates := domain.Coordinates{X: 10, Y: 10}
+
+	link, err := events.Create(ctx, CreateEventInput{OwnerID: "link_owner", Name: "link", Coordinates: coordinates, Visibility: domain.EventVisibilityLink})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approval, err := events.Create(ctx, CreateEventInput{OwnerID: "approval_owner", Name: "approval", Coordinates: coordinates, Visibility: domain.EventVisibilityApproval})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rangeData, err := events.GetByRange(ctx, GetByRangeInput{HorizontalRange: 2, VerticalRange: 2, Coordinates: coordinates})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rangeData) != 0 {
+		t.Fatalf("Expected private events to be hidden, actual: %+v", rangeData)
+	}
+
+	inviteToken, err := events.CreateInvite(ctx, link.ID, "link_owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accessToken, err := tokenManager.GetAccessToken(auth.TokenData{ID: "guest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.CheckAccess(ctx, EventAccessInput{EventID: link.ID, UserID: "guest", InviteToken: accessToken}); err != domain.ErrInvalidInviteToken {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrInvalidInviteToken, err)
+	}
+
+	if err = events.CheckAccess(ctx, EventAccessInput{EventID: approval.ID, UserID: "guest", InviteToken: inviteToken}); err != domain.ErrEventAccessDenied {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrEventAccessDenied, err)
+	}
+
+	if err = events.CheckAccess(ctx, EventAccessInput{EventID: link.ID, UserID: "guest", InviteToken: inviteToken}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.CheckAccess(ctx, EventAccessInput{EventID: link.ID, UserID: "guest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.RequestJoin(ctx, JoinRequestInput{EventID: approval.ID, UserID: "guest", UserName: "guest_name"}); err != nil {
+		t.Fatal(err)
+	}
+
+	joinRequests, err := events.GetJoinRequests(ctx, approval.ID, "approval_owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(joinRequests) != 1 || joinRequests[0].UserID != "guest" || joinRequests[0].UserName != "guest_name" {
+		t.Fatalf("Incorrect join requests: %+v", joinRequests)
+	}
+
+	if err = events.CheckAccess(ctx, EventAccessInput{EventID: approval.ID, UserID: "guest"}); err != domain.ErrEventAccessDenied {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrEventAccessDenied, err)
+	}
+
+	if err = events.ApproveJoinRequest(ctx, ReviewJoinRequestInput{EventID: approval.ID, UserID: "approval_owner", RequesterID: "guest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.CheckAccess(ctx, EventAccessInput{EventID: approval.ID, UserID: "guest"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEventsRoles(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	events := NewEventService(testLogger{t: t}, repositories.Events, pub, nil, nil)
+
+	event, err := events.Create(ctx, CreateEventInput{OwnerID: "roles_owner", Name: "roles"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber := newTestSubscriber()
+	pub.Subscribe(event.ID, subscriber)
+	updateInput := UpdateEventInput{EventID: event.ID, UserID: "co_host", Address: "updated"}
+
+	if err = events.Update(ctx, updateInput); err != domain.ErrUserIsNotOwner {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrUserIsNotOwner, err)
+	}
+
+	if err = events.GrantRole(ctx, EventRoleInput{EventID: event.ID, UserID: "roles_owner", TargetUserID: "co_host", Role: domain.EventRoleOwner}); err != domain.ErrInvalidRole {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrInvalidRole, err)
+	}
+
+	if err = events.GrantRole(ctx, EventRoleInput{EventID: event.ID, UserID: "roles_owner", TargetUserID: "co_host", Role: domain.EventRoleCoHost}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, subscriber, `roleChanged/{"userId":"co_host","role":2}`)
+
+	if err = events.Update(ctx, updateInput); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceivedPrefix(t, subscriber, "eventUpdated/")
+
+	if err = events.GrantRole(ctx, EventRoleInput{EventID: event.ID, UserID: "co_host", TargetUserID: "guest", Role: domain.EventRoleModerator}); err != domain.ErrPermissionDenied {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrPermissionDenied, err)
+	}
+
+	if err = events.Close(ctx, event.ID, "co_host"); err != domain.ErrUserIsNotOwner {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrUserIsNotOwner, err)
+	}
+
+	if err = events.RevokeRole(ctx, EventRoleInput{EventID: event.ID, UserID: "roles_owner", TargetUserID: "co_host"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, subscriber, `roleChanged/{"userId":"co_host","role":0}`)
+
+	if err = events.Update(ctx, updateInput); err != domain.ErrUserIsNotOwner {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrUserIsNotOwner, err)
+	}
+}
+
+func TestEventsModeration(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	events := NewEventService(testLogger{t: t}, repositories.Events, pub, nil, nil)
+
+	event, err := events.Create(ctx, CreateEventInput{OwnerID: "moderation_owner", Name: "moderation"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.GrantRole(ctx, EventRoleInput{EventID: event.ID, UserID: "moderation_owner", TargetUserID: "moderator", Role: domain.EventRoleModerator}); err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber := newTestSubscriber()
+	pub.Subscribe(event.ID, subscriber)
+
+	if err = events.SendChatMessage(ctx, ChatMessageInput{EventID: event.ID, UserID: "guest", Message: "spam"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceivedPrefix(t, subscriber, "chatMessage/")
+	history, err := events.GetChatHistory(ctx, ChatHistoryInput{EventID: event.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.DeleteChatMessage(ctx, DeleteChatMessageInput{EventID: event.ID, UserID: "guest", MessageID: history.ChatMessages[0].ID}); err != domain.ErrPermissionDenied {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrPermissionDenied, err)
+	}
+
+	if err = events.DeleteChatMessage(ctx, DeleteChatMessageInput{EventID: event.ID, UserID: "moderator", MessageID: history.ChatMessages[0].ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, subscriber, "chatMessageDeleted/"+history.ChatMessages[0].ID)
+
+	if err = events.MuteUser(ctx, MuteUserInput{EventID: event.ID, UserID: "moderator", TargetUserID: "moderation_owner", Duration: time.Hour}); err != domain.ErrPermissionDenied {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrPermissionDenied, err)
+	}
+
+	if err = events.MuteUser(ctx, MuteUserInput{EventID: event.ID, UserID: "moderator", TargetUserID: "guest", Duration: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceivedPrefix(t, subscriber, `userMuted/{"userId":"guest","until":`)
+
+	if err = events.SendChatMessage(ctx, ChatMessageInput{EventID: event.ID, UserID: "guest", Message: "spam"}); err != domain.ErrUserMuted {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrUserMuted, err)
+	}
+
+	if err = events.KickUser(ctx, ModerateUserInput{EventID: event.ID, UserID: "moderator", TargetUserID: "guest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, subscriber, `userKicked/{"userId":"guest"}`)
+
+	if err = events.CheckAccess(ctx, EventAccessInput{EventID: event.ID, UserID: "guest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = repositories.Events.AddMember(ctx, event.ID, domain.UserInfo{ID: "guest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = repositories.Events.AddJoinRequest(ctx, event.ID, domain.JoinRequest{UserID: "guest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.BanUser(ctx, ModerateUserInput{EventID: event.ID, UserID: "moderator", TargetUserID: "guest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, subscriber, `userBanned/{"userId":"guest"}`)
+	joinRequests, err := events.GetJoinRequests(ctx, event.ID, "moderation_owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(joinRequests) != 0 {
+		t.Fatalf("Expected no join requests, actual: %+v", joinRequests)
+	}
+
+	bannedEvent, err := repositories.Events.GetEventById(ctx, event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if containsUser(bannedEvent.Members, "guest") || containsUser(bannedEvent.Users, "guest") {
+		t.Fatalf("Banned user is still in the event: %+v", bannedEvent)
+	}
+
+	if err = events.CheckAccess(ctx, EventAccessInput{EventID: event.ID, UserID: "guest"}); err != domain.ErrUserBanned {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrUserBanned, err)
+	}
+}
+
+func TestEventsRichChat(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	events := NewEventService(testLogger{t: t}, repositories.Events, pub, newMediaService(repositories.Media, nil, MediaOptions{}), nil)
+
+	event, err := events.Create(ctx, CreateEventInput{OwnerID: "rich_chat_owner", Name: "rich_chat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaId, err := repositories.Media.CreateMedia(ctx, domain.Media{Name: "photo", ContentType: "image/png", OwnerID: "guest", Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foreignMediaId, err := repositories.Media.CreateMedia(ctx, domain.Media{Name: "photo", ContentType: "image/png", OwnerID: "other", Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventMediaId, err := repositories.Media.CreateMedia(ctx, domain.Media{Name: "photo", ContentType: "image/png", OwnerID: "rich_chat_owner", Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = repositories.Events.AddMedia(ctx, event.ID, domain.MediaInfo{ID: eventMediaId, ContentType: "image/png"}); err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber := newTestSubscriber()
+	pub.Subscribe(event.ID, subscriber)
+
+	if err = events.SendChatMessage(ctx, ChatMessageInput{EventID: event.ID, UserID: "author", Message: "hello", ReplyToID: "unknown"}); err != domain.ErrChatMessageNotFound {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrChatMessageNotFound, err)
+	}
+
+	if err = events.SendChatMessage(ctx, ChatMessageInput{EventID: event.ID, UserID: "author", AttachmentIDs: []string{"unknown"}}); err != domain.ErrMediaNotFound {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrMediaNotFound, err)
+	}
+
+	if err = events.SendChatMessage(ctx, ChatMessageInput{EventID: event.ID, UserID: "author", AttachmentIDs: []string{foreignMediaId}}); err != domain.ErrPermissionDenied {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrPermissionDenied, err)
+	}
+
+	if err = events.SendChatMessage(ctx, ChatMessageInput{EventID: event.ID, UserID: "author", Message: "hello", AttachmentIDs: []string{eventMediaId}}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceivedPrefix(t, subscriber, "chatMessage/")
+	history, err := events.GetChatHistory(ctx, ChatHistoryInput{EventID: event.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firstId := history.ChatMessages[0].ID
+	if err = events.SendChatMessage(ctx, ChatMessageInput{EventID: event.ID, UserID: "guest", ReplyToID: firstId, AttachmentIDs: []string{mediaId}}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceivedPrefix(t, subscriber, `chatMessage/{"id":`)
+	history, err = events.GetChatHistory(ctx, ChatHistoryInput{EventID: event.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply := history.ChatMessages[1]
+	if reply.ReplyToID != firstId || len(reply.Attachments) != 1 || reply.Attachments[0].ID != mediaId || reply.Attachments[0].ContentType != "image/png" {
+		t.Fatalf("Incorrect reply: %+v", reply)
+	}
+
+	if err = events.EditChatMessage(ctx, EditChatMessageInput{EventID: event.ID, UserID: "guest", MessageID: firstId, Message: "edited"}); err != domain.ErrPermissionDenied {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrPermissionDenied, err)
+	}
+
+	if err = events.EditChatMessage(ctx, EditChatMessageInput{EventID: event.ID, UserID: "author", MessageID: firstId, Message: "edited"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceivedPrefix(t, subscriber, fmt.Sprintf(`chatMessageUpdated/{"id":"%s","userId":"author","userName":"","userImageId":"","message":"edited"`, firstId))
+
+	if err = events.ToggleChatReaction(ctx, ChatReactionInput{EventID: event.ID, UserID: "guest", MessageID: firstId, Emoji: "fire"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceivedPrefix(t, subscriber, "chatMessageUpdated/")
+	history, err = events.GetChatHistory(ctx, ChatHistoryInput{EventID: event.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited := history.ChatMessages[0]
+	if edited.Message != "edited" || len(edited.Edits) != 1 || edited.Edits[0].Message != "hello" ||
+		len(edited.Reactions) != 1 || edited.Reactions[0] != (domain.ChatReaction{UserID: "guest", Emoji: "fire"}) {
+		t.Fatalf("Incorrect edited message: %+v", edited)
+	}
+
+	if err = repositories.Events.SetMute(ctx, event.ID, domain.EventMute{UserID: "guest", Until: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.ToggleChatReaction(ctx, ChatReactionInput{EventID: event.ID, UserID: "guest", MessageID: firstId, Emoji: "fire"}); err != domain.ErrUserMuted {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrUserMuted, err)
+	}
+}
+
+func createTestEvent(t *testing.T, events repository.Events, ownerId string, createdAt time.Time) string {
+	t.Helper()
+	id, err := events.CreateEvent(context.Background(), domain.Event{
+		OwnerID:   ownerId,
+		Name:      ownerId,
+		State:     domain.EventStateOpened,
+		CreatedAt: createdAt,
+		Users:     []domain.UserInfo{},
+		Media:     []domain.MediaInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func assertEventState(t *testing.T, events Events, eventId string, expected domain.EventState) {
+	t.Helper()
+	event, err := events.GetByID(context.Background(), EventAccessInput{EventID: eventId})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event.State != expected {
+		t.Fatalf("Incorrect state\nExpected: %d\nActual: %d", expected, event.State)
+	}
+}
+
+func assertReceivedPrefix(t *testing.T, subscriber *testSubscriber, prefix string) {
+	t.Helper()
+	select {
+	case message := <-subscriber.messages:
+		if len(message) < len(prefix) || message[:len(prefix)] != prefix {
+			t.Fatalf("Incorrect message\nExpected prefix: %s\nActual: %s", prefix, message)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Message was not received")
+	}
+}
+
+func TestEventsUserNotifications(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	events := NewEventService(testLogger{t: t}, repositories.Events, pub, nil, nil)
+
+	event, err := events.Create(ctx, CreateEventInput{OwnerID: "notifications_owner", Name: "notifications", Visibility: domain.EventVisibilityApproval})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	owner := newTestSubscriber()
+	guest := newTestSubscriber()
+	pub.Subscribe(UserTopic("notifications_owner"), owner)
+	pub.Subscribe(UserTopic("guest"), guest)
+
+	for _, requesterId := range []string{"guest", "rejected"} {
+		if err = events.RequestJoin(ctx, JoinRequestInput{EventID: event.ID, UserID: requesterId}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = events.ApproveJoinRequest(ctx, ReviewJoinRequestInput{EventID: event.ID, UserID: "notifications_owner", RequesterID: "guest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, guest, `joinRequestApproved/{"eventId":"`+event.ID+`"}`)
+
+	if err = events.RejectJoinRequest(ctx, ReviewJoinRequestInput{EventID: event.ID, UserID: "notifications_owner", RequesterID: "rejected"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = events.GrantRole(ctx, EventRoleInput{EventID: event.ID, UserID: "notifications_owner", TargetUserID: "guest", Role: domain.EventRoleModerator}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, guest, `roleChanged/{"eventId":"`+event.ID+`","userId":"guest","role":1}`)
+
+	if err = events.SendChatMessage(ctx, ChatMessageInput{EventID: event.ID, UserID: "notifications_owner", Message: "question"}); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := events.GetChatHistory(ctx, ChatHistoryInput{EventID: event.ID, UserID: "notifications_owner", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, userId := range []string{"notifications_owner", "guest"} {
+		if err = events.SendChatMessage(ctx, ChatMessageInput{EventID: event.ID, UserID: userId, Message: "answer", ReplyToID: history.ChatMessages[0].ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertReceivedPrefix(t, owner, `chatReply/{"eventId":"`+event.ID+`","message":{"id":`)
+
+	select {
+	case message := <-owner.messages:
+		t.Fatalf("Unexpected message: %s", message)
+	case message := <-guest.messages:
+		t.Fatalf("Unexpected message: %s", message)
+	default:
+	}
+}
+
+func TestNormalizeLongitude(t *testing.T) {
+	tests := map[float64]float64{
+		0:    0,
+		179:  179,
+		181:  -179,
+		-181: 179,
+		540:  -180,
+		-90:  -90,
+	}
+
+	for x, expected := range tests {
+		if actual := normalizeLongitude(x); actual != expected {
+			t.Fatalf("Incorrect longitude for %v\nExpected: %v\nActual: %v", x, expected, actual)
+		}
+	}
+}
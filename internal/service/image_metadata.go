@@ -0,0 +1,714 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+)
+
+// errInvalidImage is returned for images whose structure can't be parsed, so their metadata can't be removed.
+var errInvalidImage = errors.New("invalid image structure")
+
+var (
+	exifHeader     = []byte("Exif\x00\x00")
+	iccHeader      = []byte("ICC_PROFILE\x00")
+	pngSignature   = []byte("\x89PNG\r\n\x1a\n")
+	orientationTag = uint16(0x0112)
+)
+
+// stripImageMetadata removes EXIF, XMP, IPTC and comments, which may hold the location and the device of a photo.
+// Only the orientation survives, so the image is still shown the right way up.
+func stripImageMetadata(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEGMetadata(data)
+	case "image/png":
+		return stripPNGMetadata(data)
+	case "image/heic":
+		return stripHEICMetadata(data)
+	case "image/webp":
+		return stripWebPMetadata(data)
+	default:
+		return data, nil
+	}
+}
+
+// imageOrientation returns the EXIF orientation of a jpeg or png, 1 when it's not set.
+func imageOrientation(contentType string, data []byte) int {
+	switch contentType {
+	case "image/jpeg":
+		return jpegOrientation(data)
+	case "image/png":
+		return pngOrientation(data)
+	default:
+		return 1
+	}
+}
+
+type jpegSegment struct {
+	marker byte
+	data   []byte
+}
+
+// readJPEGSegments returns the segments before the scan and the rest of the image up to the end marker.
+func readJPEGSegments(data []byte) ([]jpegSegment, []byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, nil, errInvalidImage
+	}
+
+	var segments []jpegSegment
+	position := 2
+
+	for {
+		for position < len(data) && data[position] == 0xFF && position+1 < len(data) && data[position+1] == 0xFF {
+			position++
+		}
+
+		if position+4 > len(data) || data[position] != 0xFF {
+			return nil, nil, errInvalidImage
+		}
+
+		marker := data[position+1]
+		length := int(binary.BigEndian.Uint16(data[position+2:]))
+
+		if length < 2 || position+2+length > len(data) {
+			return nil, nil, errInvalidImage
+		}
+
+		if marker == 0xDA {
+			return segments, data[position:jpegEnd(data, position+2+length)], nil
+		}
+
+		segments = append(segments, jpegSegment{marker: marker, data: data[position+4 : position+2+length]})
+		position += 2 + length
+	}
+}
+
+// jpegEnd finds the end of image marker, data appended after it, like MPF pictures, is dropped.
+func jpegEnd(data []byte, position int) int {
+	for i := position; i+1 < len(data); i++ {
+		if data[i] == 0xFF && data[i+1] == 0xD9 {
+			return i + 2
+		}
+
+		// 0xFF00 is an escaped byte of the scan, other markers, like restarts and progressive scans, are skipped
+		if data[i] == 0xFF && data[i+1] != 0x00 {
+			i++
+		}
+	}
+
+	return len(data)
+}
+
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	segments, scan, err := readJPEGSegments(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	orientation := 1
+	result := bytes.Buffer{}
+	result.Write([]byte{0xFF, 0xD8})
+	exifWritten := false
+
+	for _, segment := range segments {
+		if segment.marker == 0xE1 && bytes.HasPrefix(segment.data, exifHeader) {
+			orientation = exifOrientation(segment.data[len(exifHeader):])
+		}
+	}
+
+	for _, segment := range segments {
+		if !keepJPEGSegment(segment) {
+			continue
+		}
+
+		// the exif goes right after jfif, which must be the first segment
+		if !exifWritten && segment.marker != 0xE0 {
+			writeOrientationSegment(&result, orientation)
+			exifWritten = true
+		}
+
+		writeJPEGSegment(&result, segment.marker, segment.data)
+	}
+
+	if !exifWritten {
+		writeOrientationSegment(&result, orientation)
+	}
+
+	result.Write(scan)
+	return result.Bytes(), nil
+}
+
+// keepJPEGSegment keeps the segments needed to decode the image: tables, frames, jfif, color profile and adobe color transform.
+func keepJPEGSegment(segment jpegSegment) bool {
+	switch {
+	case segment.marker == 0xE0 || segment.marker == 0xEE:
+		return true
+	case segment.marker == 0xE2:
+		return bytes.HasPrefix(segment.data, iccHeader)
+	case segment.marker >= 0xE1 && segment.marker <= 0xEF, segment.marker == 0xFE:
+		return false
+	default:
+		return true
+	}
+}
+
+func writeOrientationSegment(result *bytes.Buffer, orientation int) {
+	if orientation > 1 {
+		writeJPEGSegment(result, 0xE1, append(append([]byte{}, exifHeader...), orientationExif(orientation)...))
+	}
+}
+
+func writeJPEGSegment(result *bytes.Buffer, marker byte, data []byte) {
+	result.Write([]byte{0xFF, marker})
+	_ = binary.Write(result, binary.BigEndian, uint16(len(data)+2))
+	result.Write(data)
+}
+
+func jpegOrientation(data []byte) int {
+	segments, _, err := readJPEGSegments(data)
+
+	if err != nil {
+		return 1
+	}
+
+	for _, segment := range segments {
+		if segment.marker == 0xE1 && bytes.HasPrefix(segment.data, exifHeader) {
+			return exifOrientation(segment.data[len(exifHeader):])
+		}
+	}
+
+	return 1
+}
+
+// exifOrientation reads the orientation tag of the first image directory of tiff structured exif data.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:]) == orientationTag {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+
+			return 1
+		}
+	}
+
+	return 1
+}
+
+// orientationExif builds tiff structured exif data with only the orientation tag.
+func orientationExif(orientation int) []byte {
+	result := bytes.Buffer{}
+	result.WriteString("MM\x00*")
+	// the directory right after the header holds one short value entry, then no next directory
+	for _, value := range []interface{}{uint32(8), uint16(1), orientationTag, uint16(3), uint32(1), uint16(orientation), uint16(0), uint32(0)} {
+		_ = binary.Write(&result, binary.BigEndian, value)
+	}
+
+	return result.Bytes()
+}
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+func readPNGChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errInvalidImage
+	}
+
+	var chunks []pngChunk
+	position := len(pngSignature)
+
+	for position < len(data) {
+		if position+12 > len(data) {
+			return nil, errInvalidImage
+		}
+
+		length := int(binary.BigEndian.Uint32(data[position:]))
+
+		if length < 0 || position+12+length > len(data) {
+			return nil, errInvalidImage
+		}
+
+		chunk := pngChunk{kind: string(data[position+4 : position+8]), data: data[position+8 : position+8+length]}
+		chunks = append(chunks, chunk)
+		position += 12 + length
+
+		if chunk.kind == "IEND" {
+			return chunks, nil
+		}
+	}
+
+	return nil, errInvalidImage
+}
+
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	chunks, err := readPNGChunks(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	orientation := pngChunksOrientation(chunks)
+	result := bytes.Buffer{}
+	result.Write(pngSignature)
+
+	for _, chunk := range chunks {
+		switch chunk.kind {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+			continue
+		case "IDAT":
+			if orientation > 1 {
+				writePNGChunk(&result, "eXIf", orientationExif(orientation))
+				orientation = 1
+			}
+		}
+
+		writePNGChunk(&result, chunk.kind, chunk.data)
+	}
+
+	return result.Bytes(), nil
+}
+
+func writePNGChunk(result *bytes.Buffer, kind string, data []byte) {
+	_ = binary.Write(result, binary.BigEndian, uint32(len(data)))
+	result.WriteString(kind)
+	result.Write(data)
+	checksum := crc32.NewIEEE()
+	_, _ = checksum.Write([]byte(kind))
+	_, _ = checksum.Write(data)
+	_ = binary.Write(result, binary.BigEndian, checksum.Sum32())
+}
+
+func pngOrientation(data []byte) int {
+	chunks, err := readPNGChunks(data)
+
+	if err != nil {
+		return 1
+	}
+
+	return pngChunksOrientation(chunks)
+}
+
+func pngChunksOrientation(chunks []pngChunk) int {
+	for _, chunk := range chunks {
+		if chunk.kind == "eXIf" {
+			return exifOrientation(chunk.data)
+		}
+	}
+
+	return 1
+}
+
+type isoBox struct {
+	kind  string
+	start int
+	body  int
+	end   int
+}
+
+func readISOBoxes(data []byte, start int, end int) ([]isoBox, error) {
+	var boxes []isoBox
+
+	for position := start; position < end; {
+		if position+8 > end {
+			return nil, errInvalidImage
+		}
+
+		size := int(binary.BigEndian.Uint32(data[position:]))
+		box := isoBox{kind: string(data[position+4 : position+8]), start: position, body: position + 8}
+
+		switch size {
+		case 0:
+			box.end = end
+		case 1:
+			if position+16 > end {
+				return nil, errInvalidImage
+			}
+
+			largeSize := binary.BigEndian.Uint64(data[position+8:])
+
+			if largeSize > uint64(end-position) {
+				return nil, errInvalidImage
+			}
+
+			box.body = position + 16
+			box.end = position + int(largeSize)
+		default:
+			box.end = position + size
+		}
+
+		if box.end < box.body || box.end > end {
+			return nil, errInvalidImage
+		}
+
+		boxes = append(boxes, box)
+		position = box.end
+	}
+
+	return boxes, nil
+}
+
+func findISOBox(boxes []isoBox, kind string) (isoBox, bool) {
+	for _, box := range boxes {
+		if box.kind == kind {
+			return box, true
+		}
+	}
+
+	return isoBox{}, false
+}
+
+// stripHEICMetadata zeroes the exif and xmp items in place, so the offsets of the other items stay valid.
+// The orientation of heic is stored in the irot and imir properties rather than exif, so it's kept.
+func stripHEICMetadata(data []byte) ([]byte, error) {
+	result := append([]byte{}, data...)
+	boxes, err := readISOBoxes(result, 0, len(result))
+
+	if err != nil {
+		return nil, err
+	}
+
+	meta, ok := findISOBox(boxes, "meta")
+
+	if !ok || meta.body+4 > meta.end {
+		return nil, errInvalidImage
+	}
+
+	children, err := readISOBoxes(result, meta.body+4, meta.end)
+
+	if err != nil {
+		return nil, err
+	}
+
+	iinf, hasItems := findISOBox(children, "iinf")
+	iloc, hasLocations := findISOBox(children, "iloc")
+
+	if !hasItems || !hasLocations {
+		return result, nil
+	}
+
+	items, err := heicMetadataItems(result, iinf)
+
+	if err != nil || len(items) == 0 {
+		return result, err
+	}
+
+	idatStart := -1
+
+	if idat, ok := findISOBox(children, "idat"); ok {
+		idatStart = idat.body
+	}
+
+	return result, zeroHEICItems(result, iloc, items, idatStart)
+}
+
+// heicMetadataItems returns the ids of the exif and xmp items.
+func heicMetadataItems(data []byte, iinf isoBox) (map[uint32]bool, error) {
+	reader := newBoxReader(data[iinf.body:iinf.end])
+	version := reader.uint8()
+	reader.skip(3)
+
+	if version == 0 {
+		reader.uint16()
+	} else {
+		reader.uint32()
+	}
+
+	if reader.err != nil {
+		return nil, reader.err
+	}
+
+	entries, err := readISOBoxes(data, iinf.body+reader.position, iinf.end)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint32]bool)
+
+	for _, entry := range entries {
+		if entry.kind != "infe" {
+			continue
+		}
+
+		reader = newBoxReader(data[entry.body:entry.end])
+		version = reader.uint8()
+		reader.skip(3)
+
+		if version < 2 {
+			continue
+		}
+
+		var id uint32
+
+		if version == 2 {
+			id = uint32(reader.uint16())
+		} else {
+			id = reader.uint32()
+		}
+
+		reader.uint16()
+		itemType := string(reader.bytes(4))
+
+		if reader.err != nil {
+			return nil, reader.err
+		}
+
+		// the content type of mime items goes after the item name
+		name := bytes.IndexByte(reader.rest(), 0)
+
+		if itemType == "Exif" || itemType == "mime" && name >= 0 && bytes.HasPrefix(reader.rest()[name+1:], []byte("application/rdf+xml")) {
+			result[id] = true
+		}
+	}
+
+	return result, nil
+}
+
+func zeroHEICItems(data []byte, iloc isoBox, items map[uint32]bool, idatStart int) error {
+	reader := newBoxReader(data[iloc.body:iloc.end])
+	version := reader.uint8()
+	reader.skip(3)
+	sizes := reader.uint16()
+	offsetSize, lengthSize, baseOffsetSize := int(sizes>>12), int(sizes>>8&0xF), int(sizes>>4&0xF)
+	indexSize := 0
+
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0xF)
+	}
+
+	var count uint32
+
+	if version < 2 {
+		count = uint32(reader.uint16())
+	} else {
+		count = reader.uint32()
+	}
+
+	for i := uint32(0); i < count && reader.err == nil; i++ {
+		var id uint32
+
+		if version < 2 {
+			id = uint32(reader.uint16())
+		} else {
+			id = reader.uint32()
+		}
+
+		method := 0
+
+		if version == 1 || version == 2 {
+			method = int(reader.uint16() & 0xF)
+		}
+
+		reader.uint16()
+		baseOffset := reader.sized(baseOffsetSize)
+		extents := int(reader.uint16())
+
+		for j := 0; j < extents && reader.err == nil; j++ {
+			reader.sized(indexSize)
+			offset := baseOffset + reader.sized(offsetSize)
+			length := reader.sized(lengthSize)
+
+			if !items[id] || reader.err != nil {
+				continue
+			}
+
+			switch {
+			case method == 1 && idatStart >= 0:
+				offset += uint64(idatStart)
+			case method != 0:
+				return errInvalidImage
+			}
+
+			if length == 0 || offset+length > uint64(len(data)) {
+				return errInvalidImage
+			}
+
+			for k := offset; k < offset+length; k++ {
+				data[k] = 0
+			}
+		}
+	}
+
+	return reader.err
+}
+
+type boxReader struct {
+	data     []byte
+	position int
+	err      error
+}
+
+func newBoxReader(data []byte) *boxReader {
+	return &boxReader{data: data}
+}
+
+func (r *boxReader) bytes(n int) []byte {
+	if r.err != nil || r.position+n > len(r.data) {
+		r.err = errInvalidImage
+		return make([]byte, n)
+	}
+
+	result := r.data[r.position : r.position+n]
+	r.position += n
+	return result
+}
+
+func (r *boxReader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *boxReader) rest() []byte {
+	return r.data[r.position:]
+}
+
+func (r *boxReader) uint8() uint8 {
+	return r.bytes(1)[0]
+}
+
+func (r *boxReader) uint16() uint16 {
+	return binary.BigEndian.Uint16(r.bytes(2))
+}
+
+func (r *boxReader) uint32() uint32 {
+	return binary.BigEndian.Uint32(r.bytes(4))
+}
+
+// sized reads an unsigned number of 0, 4 or 8 bytes, the sizes iloc fields use.
+func (r *boxReader) sized(size int) uint64 {
+	switch size {
+	case 0:
+		return 0
+	case 4:
+		return uint64(r.uint32())
+	case 8:
+		return binary.BigEndian.Uint64(r.bytes(8))
+	default:
+		r.err = errInvalidImage
+		return 0
+	}
+}
+
+// stripWebPMetadata drops the EXIF and XMP chunks and their flags in the extended format header.
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errInvalidImage
+	}
+
+	result := bytes.Buffer{}
+	result.Write(data[:12])
+
+	for position := 12; position < len(data); {
+		if position+8 > len(data) {
+			return nil, errInvalidImage
+		}
+
+		kind := string(data[position : position+4])
+		size := int(binary.LittleEndian.Uint32(data[position+4:]))
+		end := position + 8 + size + size%2
+
+		if size < 0 || end > len(data) {
+			return nil, errInvalidImage
+		}
+
+		switch kind {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte{}, data[position:end]...)
+
+			if size > 0 {
+				chunk[8] &^= 0x08 | 0x04
+			}
+
+			result.Write(chunk)
+		default:
+			result.Write(data[position:end])
+		}
+
+		position = end
+	}
+
+	stripped := result.Bytes()
+	binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+	return stripped, nil
+}
+
+// applyOrientation turns the image the way the EXIF orientation says it should be shown.
+func applyOrientation(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	resultWidth, resultHeight := width, height
+
+	if orientation >= 5 {
+		resultWidth, resultHeight = height, width
+	}
+
+	result := image.NewRGBA(image.Rect(0, 0, resultWidth, resultHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			resultX, resultY := x, y
+
+			switch orientation {
+			case 2:
+				resultX = width - 1 - x
+			case 3:
+				resultX, resultY = width-1-x, height-1-y
+			case 4:
+				resultY = height - 1 - y
+			case 5:
+				resultX, resultY = y, x
+			case 6:
+				resultX, resultY = height-1-y, x
+			case 7:
+				resultX, resultY = height-1-y, width-1-x
+			case 8:
+				resultX, resultY = y, width-1-x
+			}
+
+			source := img.PixOffset(img.Bounds().Min.X+x, img.Bounds().Min.Y+y)
+			target := result.PixOffset(resultX, resultY)
+			copy(result.Pix[target:target+4], img.Pix[source:source+4])
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+
+	// decoders of the formats variants are made from
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	variantContentType = "image/jpeg"
+	variantQuality     = 85
+	// larger images are not resized, so a small file can't make the server decode a huge bitmap
+	maxVariantSourcePixels = 50 * 1000 * 1000
+)
+
+// variantSizes go from the largest, so each variant is scaled down from the previous one.
+var variantSizes = []int{1024, 256, 64}
+
+type imageVariant struct {
+	info domain.MediaVariant
+	data []byte
+}
+
+// resizeImage returns the variants smaller than the image, nothing if the data is not a supported image.
+// Variants are turned by the EXIF orientation, as they have no metadata.
+func resizeImage(source io.Reader, contentType string) ([]imageVariant, error) {
+	data, err := ioutil.ReadAll(source)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+
+	if err != nil || config.Width*config.Height > maxVariantSourcePixels {
+		return nil, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, nil
+	}
+
+	orientation := imageOrientation(contentType, data)
+	var result []imageVariant
+
+	for _, size := range variantSizes {
+		bounds := img.Bounds()
+
+		if bounds.Dx() <= size && bounds.Dy() <= size {
+			continue
+		}
+
+		scaled := applyOrientation(scaleImage(img, size), orientation)
+		// the next variants are scaled from this one, which is already turned
+		img, orientation = scaled, 1
+		buffer := bytes.Buffer{}
+
+		if err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: variantQuality}); err != nil {
+			return nil, err
+		}
+
+		result = append(result, imageVariant{
+			info: domain.MediaVariant{
+				Size:        size,
+				Width:       img.Bounds().Dx(),
+				Height:      img.Bounds().Dy(),
+				ContentType: variantContentType,
+				FileSize:    int64(buffer.Len()),
+			},
+			data: buffer.Bytes(),
+		})
+	}
+
+	return result, nil
+}
+
+// scaleImage fits the image into a size x size square keeping the aspect ratio,
+// transparent pixels become white as jpeg has no alpha channel.
+func scaleImage(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := size, bounds.Dy()*size/bounds.Dx()
+
+	if bounds.Dy() > bounds.Dx() {
+		width, height = bounds.Dx()*size/bounds.Dy(), size
+	}
+
+	if width < 1 {
+		width = 1
+	}
+
+	if height < 1 {
+		height = 1
+	}
+
+	result := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(result, result.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(result, result.Bounds(), img, bounds, draw.Over, nil)
+	return result
+}
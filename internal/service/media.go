@@ -1,7 +1,11 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
 	"time"
 
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
@@ -9,22 +13,55 @@ import (
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/storage"
 )
 
+const (
+	defaultMaxImageSize = 10 << 20
+	defaultMaxVideoSize = 100 << 20
+)
+
+type MediaOptions struct {
+	MaxImageSize int64
+	MaxVideoSize int64
+	// KeepMetadata stores photos with their EXIF, like the location and the device, which is stripped by default
+	KeepMetadata bool
+}
+
 type mediaService struct {
 	repository repository.Media
 	storage    storage.FileStorage
+	options    MediaOptions
 }
 
-func newMediaService(repository repository.Media, fileStorage storage.FileStorage) Media {
+func newMediaService(repository repository.Media, fileStorage storage.FileStorage, options MediaOptions) Media {
+	if options.MaxImageSize <= 0 {
+		options.MaxImageSize = defaultMaxImageSize
+	}
+
+	if options.MaxVideoSize <= 0 {
+		options.MaxVideoSize = defaultMaxVideoSize
+	}
+
 	return &mediaService{
 		repository: repository,
 		storage:    fileStorage,
+		options:    options,
 	}
 }
 
 func (s *mediaService) Create(ctx context.Context, input CreateMediaInput) (string, error) {
+	input, err := s.checkUpload(input)
+
+	if err != nil {
+		return "", err
+	}
+
+	if input, err = s.stripMetadata(input); err != nil {
+		return "", err
+	}
+
 	media := domain.Media{
 		Name:             input.Name,
 		ContentType:      input.ContentType,
+		OwnerID:          input.OwnerID,
 		Size:             input.Size,
 		LastModifiedDate: time.Now(),
 	}
@@ -35,10 +72,24 @@ func (s *mediaService) Create(ctx context.Context, input CreateMediaInput) (stri
 		return "", err
 	}
 
-	err = s.storage.Upload(mediaId, input.Data)
+	err = s.storage.Upload(ctx, mediaId, input.ContentType, input.Data, input.Size)
+
+	if err != nil {
+		_ = s.repository.DeleteMedia(ctx, mediaId)
+		return "", err
+	}
+
+	media.ID = mediaId
+	media.Variants, err = s.createVariants(ctx, mediaId, input.ContentType)
+
+	if err == nil && len(media.Variants) > 0 {
+		err = s.repository.UpdateMedia(ctx, media)
+	}
 
 	if err != nil {
 		_ = s.repository.DeleteMedia(ctx, mediaId)
+		_ = s.storage.Delete(ctx, mediaId)
+		_ = s.deleteVariants(ctx, mediaId, media.Variants)
 		return "", err
 	}
 
@@ -46,57 +97,292 @@ func (s *mediaService) Create(ctx context.Context, input CreateMediaInput) (stri
 }
 
 func (s *mediaService) Update(ctx context.Context, mediaId string, input CreateMediaInput) error {
-	err := s.repository.UpdateMedia(ctx, domain.Media{
+	input, err := s.checkUpload(input)
+
+	if err != nil {
+		return err
+	}
+
+	if input, err = s.stripMetadata(input); err != nil {
+		return err
+	}
+
+	old, err := s.repository.GetMedia(ctx, mediaId)
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.storage.Upload(ctx, mediaId, input.ContentType, input.Data, input.Size); err != nil {
+		return err
+	}
+
+	variants, err := s.createVariants(ctx, mediaId, input.ContentType)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.repository.UpdateMedia(ctx, domain.Media{
 		ID:               mediaId,
 		Name:             input.Name,
 		ContentType:      input.ContentType,
 		Size:             input.Size,
 		LastModifiedDate: time.Now(),
+		Variants:         variants,
 	})
 
 	if err != nil {
 		return err
 	}
 
-	return s.storage.Upload(mediaId, input.Data)
+	// variants of the same size are overwritten, the ones the new image is too small for are left
+	sizes := make(map[int]bool, len(variants))
+
+	for _, variant := range variants {
+		sizes[variant.Size] = true
+	}
+
+	var stale []domain.MediaVariant
+
+	for _, variant := range old.Variants {
+		if !sizes[variant.Size] {
+			stale = append(stale, variant)
+		}
+	}
+
+	return s.deleteVariants(ctx, mediaId, stale)
+}
+
+func (s *mediaService) MaxSize(contentTypes []string) int64 {
+	var result int64
+
+	for _, contentType := range contentTypes {
+		if size := s.maxSize(contentType); size > result {
+			result = size
+		}
+	}
+
+	return result
 }
 
 func (s *mediaService) GetMetadata(ctx context.Context, id string) (domain.Media, error) {
 	return s.repository.GetMedia(ctx, id)
 }
 
-func (s *mediaService) GetFile(ctx context.Context, id string) (domain.FileData, error) {
+func (s *mediaService) GetFile(ctx context.Context, id string, size int) (domain.FileData, error) {
 	metadata, err := s.repository.GetMedia(ctx, id)
 
 	if err != nil {
 		return domain.FileData{}, err
 	}
 
-	fileData, err := s.storage.Get(id)
+	result := domain.FileData{
+		ContentType:      metadata.ContentType,
+		Size:             metadata.Size,
+		LastModifiedDate: metadata.LastModifiedDate,
+	}
+	fileId := id
 
-	if err != nil {
+	if variant, ok := findVariant(metadata.Variants, size); ok {
+		fileId = variantId(id, variant.Size)
+		result.ContentType = variant.ContentType
+		result.Size = variant.FileSize
+	}
+
+	if result.Data, err = s.storage.Get(ctx, fileId); err != nil {
 		return domain.FileData{}, err
 	}
 
-	return domain.FileData{
-		ContentType: metadata.ContentType,
-		Size:        metadata.Size,
-		Data:        fileData,
-	}, nil
+	return result, nil
+}
+
+func (s *mediaService) GetFileURL(ctx context.Context, id string, size int) (string, error) {
+	signer, ok := s.storage.(storage.URLSigner)
+
+	if !ok {
+		return "", nil
+	}
+
+	metadata, err := s.repository.GetMedia(ctx, id)
+
+	if err != nil {
+		return "", err
+	}
+
+	// files uploaded before the type checks may be anything, like html, the API serves them only for download
+	if !IsMediaContentType(metadata.ContentType) {
+		return "", nil
+	}
+
+	if variant, ok := findVariant(metadata.Variants, size); ok {
+		return signer.GetURL(ctx, variantId(id, variant.Size), variant.ContentType)
+	}
+
+	return signer.GetURL(ctx, id, metadata.ContentType)
 }
 
 func (s *mediaService) Delete(ctx context.Context, id string) error {
-	err := s.repository.DeleteMedia(ctx, id)
+	metadata, err := s.repository.GetMedia(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.repository.DeleteMedia(ctx, id)
 
 	if err != nil {
 		return err
 	}
 
-	err = s.storage.Delete(id)
+	err = s.storage.Delete(ctx, id)
 
 	if err != nil {
 		return err
 	}
 
+	return s.deleteVariants(ctx, id, metadata.Variants)
+}
+
+// checkUpload replaces the declared type with the one detected from the data
+// and checks it against the allowed types and their size limits.
+func (s *mediaService) checkUpload(input CreateMediaInput) (CreateMediaInput, error) {
+	if input.Size <= 0 {
+		return input, domain.ErrFileIsEmpty
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(input.Data, head)
+
+	if err == io.EOF {
+		return input, domain.ErrFileIsEmpty
+	}
+
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return input, err
+	}
+
+	head = head[:n]
+
+	// a seeker is rewound rather than wrapped, so storages can still use its other interfaces, like io.ReaderAt
+	if seeker, ok := input.Data.(io.Seeker); ok {
+		if _, err = seeker.Seek(0, io.SeekStart); err != nil {
+			return input, err
+		}
+	} else {
+		input.Data = io.MultiReader(bytes.NewReader(head), input.Data)
+	}
+
+	input.ContentType = detectContentType(head)
+
+	if !containsContentType(input.AllowedTypes, input.ContentType) {
+		return input, domain.ErrUnsupportedMediaType
+	}
+
+	if input.Size > s.maxSize(input.ContentType) {
+		return input, domain.ErrFileTooLarge
+	}
+
+	return input, nil
+}
+
+// stripMetadata removes the metadata of photos, which may tell where and with what device they were taken.
+// Images are bounded by the size limit, so they are stripped in memory.
+func (s *mediaService) stripMetadata(input CreateMediaInput) (CreateMediaInput, error) {
+	if s.options.KeepMetadata || !isImageContentType(input.ContentType) {
+		return input, nil
+	}
+
+	data, err := ioutil.ReadAll(storage.LimitReader(input.Data, input.Size))
+
+	if err != nil {
+		return input, err
+	}
+
+	if data, err = stripImageMetadata(input.ContentType, data); err != nil {
+		if err == errInvalidImage {
+			return input, domain.ErrUnsupportedMediaType
+		}
+
+		return input, err
+	}
+
+	input.Data = bytes.NewReader(data)
+	input.Size = int64(len(data))
+	return input, nil
+}
+
+func (s *mediaService) maxSize(contentType string) int64 {
+	if isImageContentType(contentType) {
+		return s.options.MaxImageSize
+	}
+
+	return s.options.MaxVideoSize
+}
+
+func (s *mediaService) createVariants(ctx context.Context, id string, contentType string) ([]domain.MediaVariant, error) {
+	if !isImageContentType(contentType) {
+		return nil, nil
+	}
+
+	file, err := s.storage.Get(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	variants, err := resizeImage(file, contentType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.MediaVariant, 0, len(variants))
+
+	for _, variant := range variants {
+		err = s.storage.Upload(ctx, variantId(id, variant.info.Size), variant.info.ContentType, bytes.NewReader(variant.data), int64(len(variant.data)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, variant.info)
+	}
+
+	return result, nil
+}
+
+func (s *mediaService) deleteVariants(ctx context.Context, id string, variants []domain.MediaVariant) error {
+	for _, variant := range variants {
+		if err := s.storage.Delete(ctx, variantId(id, variant.Size)); err != nil && err != domain.ErrMediaNotFound {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// findVariant returns the smallest variant not smaller than the requested size,
+// nothing when the size is not set or the original is the best fit.
+func findVariant(variants []domain.MediaVariant, size int) (domain.MediaVariant, bool) {
+	var result domain.MediaVariant
+	found := false
+
+	if size <= 0 {
+		return result, false
+	}
+
+	for _, variant := range variants {
+		if variant.Size >= size && (!found || variant.Size < result.Size) {
+			result = variant
+			found = true
+		}
+	}
+
+	return result, found
+}
+
+func variantId(id string, size int) string {
+	return id + "_" + strconv.Itoa(size)
+}
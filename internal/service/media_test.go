@@ -0,0 +1,486 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/storage"
+)
+
+func TestMediaVariants(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileStorage, err := storage.NewLocalFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	media := newMediaService(repositories.Media, fileStorage, MediaOptions{})
+	data := newTestPNG(t, 2000, 1000)
+	mediaId, err := media.Create(ctx, CreateMediaInput{Name: "photo.png", ContentType: "image/png", Size: int64(len(data)), Data: bytes.NewReader(data), AllowedTypes: ImageContentTypes})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := media.GetMetadata(ctx, mediaId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertVariants(t, metadata.Variants, "1024x512", "256x128", "64x32")
+	assertFile(t, media, mediaId, 100, "image/jpeg", metadata.Variants[1].FileSize)
+	assertFile(t, media, mediaId, 64, "image/jpeg", metadata.Variants[2].FileSize)
+	assertFile(t, media, mediaId, 2000, "image/png", int64(len(data)))
+	assertFile(t, media, mediaId, 0, "image/png", int64(len(data)))
+
+	data = newTestPNG(t, 100, 200)
+	if err = media.Update(ctx, mediaId, CreateMediaInput{Name: "small.png", ContentType: "image/png", Size: int64(len(data)), Data: bytes.NewReader(data), AllowedTypes: ImageContentTypes}); err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata, err = media.GetMetadata(ctx, mediaId); err != nil {
+		t.Fatal(err)
+	}
+
+	assertVariants(t, metadata.Variants, "32x64")
+	assertFile(t, media, mediaId, 256, "image/png", int64(len(data)))
+
+	for _, size := range []int{1024, 256} {
+		if _, err = fileStorage.Get(ctx, variantId(mediaId, size)); err != domain.ErrMediaNotFound {
+			t.Fatalf("Expected error for stale variant %d: %v\nActual error: %v", size, domain.ErrMediaNotFound, err)
+		}
+	}
+
+	if err = media.Delete(ctx, mediaId); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{mediaId, variantId(mediaId, 64)} {
+		if _, err = fileStorage.Get(ctx, id); err != domain.ErrMediaNotFound {
+			t.Fatalf("Expected error for %s: %v\nActual error: %v", id, domain.ErrMediaNotFound, err)
+		}
+	}
+
+	video := testMP4Header + "video"
+	mediaId, err = media.Create(ctx, CreateMediaInput{Name: "video.mp4", ContentType: "video/mp4", Size: int64(len(video)), Data: strings.NewReader(video), AllowedTypes: MediaContentTypes})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata, err = media.GetMetadata(ctx, mediaId); err != nil || len(metadata.Variants) != 0 {
+		t.Fatalf("Expected no variants, got %+v, error: %v", metadata.Variants, err)
+	}
+}
+
+func TestMediaFileURL(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileStorage, err := storage.NewLocalFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingStorage := &testSigningStorage{FileStorage: fileStorage, contentTypes: make(map[string]string)}
+	media := newMediaService(repositories.Media, signingStorage, MediaOptions{})
+	data := newTestPNG(t, 100, 50)
+	mediaId, err := media.Create(ctx, CreateMediaInput{Name: "photo.png", ContentType: "text/plain", Size: int64(len(data)), Data: bytes.NewReader(data), AllowedTypes: ImageContentTypes})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, expected := range map[string]string{mediaId: "image/png", variantId(mediaId, 64): "image/jpeg"} {
+		if contentType := signingStorage.contentTypes[id]; contentType != expected {
+			t.Fatalf("Incorrect content type of %s\nExpected: %s\nActual: %s", id, expected, contentType)
+		}
+	}
+
+	for size, expected := range map[int]string{0: mediaId + " image/png", 64: variantId(mediaId, 64) + " image/jpeg"} {
+		if url, err := media.GetFileURL(ctx, mediaId, size); err != nil || url != expected {
+			t.Fatalf("Incorrect url for size %d\nExpected: %s\nActual: %s, error: %v", size, expected, url, err)
+		}
+	}
+
+	legacyId, err := repositories.Media.CreateMedia(ctx, domain.Media{Name: "page.html", ContentType: "text/html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if url, err := media.GetFileURL(ctx, legacyId, 0); err != nil || url != "" {
+		t.Fatalf("Expected no url for legacy file, got %s, error: %v", url, err)
+	}
+}
+
+func TestMediaUploadChecks(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileStorage, err := storage.NewLocalFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	media := newMediaService(repositories.Media, fileStorage, MediaOptions{MaxImageSize: 1000, MaxVideoSize: 2000})
+	png := string(newTestPNG(t, 10, 10))
+	tests := []struct {
+		name         string
+		data         string
+		allowedTypes []string
+		err          error
+		contentType  string
+	}{
+		{name: "declared type is ignored", data: png, allowedTypes: ImageContentTypes, contentType: "image/png"},
+		{name: "html", data: "<html><script>alert(1)</script></html>", allowedTypes: MediaContentTypes, err: domain.ErrUnsupportedMediaType},
+		{name: "video as image", data: testMP4Header, allowedTypes: ImageContentTypes, err: domain.ErrUnsupportedMediaType},
+		{name: "quicktime video", data: "\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00qt  ", allowedTypes: MediaContentTypes, contentType: "video/quicktime"},
+		{name: "image too large", data: png + strings.Repeat("0", 1000), allowedTypes: ImageContentTypes, err: domain.ErrFileTooLarge},
+		{name: "video under its limit", data: testMP4Header + strings.Repeat("0", 1500), allowedTypes: MediaContentTypes, contentType: "video/mp4"},
+		{name: "empty", data: "", allowedTypes: MediaContentTypes, err: domain.ErrFileIsEmpty},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mediaId, err := media.Create(ctx, CreateMediaInput{
+				Name:         "file",
+				ContentType:  "image/jpeg",
+				Size:         int64(len(test.data)),
+				Data:         strings.NewReader(test.data),
+				AllowedTypes: test.allowedTypes,
+			})
+			if err != test.err {
+				t.Fatalf("Expected error: %v\nActual error: %v", test.err, err)
+			}
+
+			if test.err != nil {
+				return
+			}
+
+			file, err := media.GetFile(ctx, mediaId, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer file.Data.Close()
+			data, err := ioutil.ReadAll(file.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if file.ContentType != test.contentType || string(data) != test.data {
+				t.Fatalf("Expected content type: %s\nActual content type: %s, data is equal: %v", test.contentType, file.ContentType, string(data) == test.data)
+			}
+		})
+	}
+
+	if size := media.MaxSize(ImageContentTypes); size != 1000 {
+		t.Fatalf("Expected max size: 1000\nActual max size: %d", size)
+	}
+
+	if size := media.MaxSize(MediaContentTypes); size != 2000 {
+		t.Fatalf("Expected max size: 2000\nActual max size: %d", size)
+	}
+}
+
+func TestMediaStripMetadata(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileStorage, err := storage.NewLocalFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	media := newMediaService(repositories.Media, fileStorage, MediaOptions{})
+	tests := []struct {
+		name        string
+		data        []byte
+		orientation int
+		variants    []string
+		topLeftRed  bool
+	}{
+		{name: "jpeg", data: newTestJPEGWithMetadata(t, 6), orientation: 6, variants: []string{"512x1024", "128x256", "32x64"}, topLeftRed: true},
+		{name: "jpeg without orientation", data: newTestJPEGWithMetadata(t, 1), orientation: 1, variants: []string{"1024x512", "256x128", "64x32"}, topLeftRed: true},
+		{name: "png", data: newTestPNGWithMetadata(t, 8), orientation: 8, variants: []string{"512x1024", "128x256", "32x64"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mediaId, err := media.Create(ctx, CreateMediaInput{Name: "photo", Size: int64(len(test.data)), Data: bytes.NewReader(test.data), AllowedTypes: ImageContentTypes})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			metadata, err := media.GetMetadata(ctx, mediaId)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := media.GetFile(ctx, mediaId, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer file.Data.Close()
+			data, err := ioutil.ReadAll(file.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if bytes.Contains(data, []byte("SECRET")) || metadata.Size != int64(len(data)) {
+				t.Fatalf("Metadata is not stripped, stored size: %d, file size: %d", metadata.Size, len(data))
+			}
+
+			if orientation := imageOrientation(metadata.ContentType, data); orientation != test.orientation {
+				t.Fatalf("Expected orientation: %d\nActual orientation: %d", test.orientation, orientation)
+			}
+
+			if _, _, err = image.Decode(bytes.NewReader(data)); err != nil {
+				t.Fatalf("Stripped image can't be decoded: %v", err)
+			}
+
+			assertVariants(t, metadata.Variants, test.variants...)
+			variant, err := media.GetFile(ctx, mediaId, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer variant.Data.Close()
+			img, _, err := image.Decode(variant.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the left half of the test images is red, it's on top after turning clockwise and at the bottom after turning back
+			red, _, blue, _ := img.At(1, 1).RGBA()
+			if test.topLeftRed != (red > blue) {
+				t.Fatalf("Variant is turned incorrectly, top left pixel: %v", img.At(1, 1))
+			}
+		})
+	}
+
+	t.Run("invalid structure", func(t *testing.T) {
+		data := []byte("\xFF\xD8\xFF\xE1\x10\x00Exif")
+		_, err := media.Create(ctx, CreateMediaInput{Name: "photo", Size: int64(len(data)), Data: bytes.NewReader(data), AllowedTypes: ImageContentTypes})
+		if err != domain.ErrUnsupportedMediaType {
+			t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrUnsupportedMediaType, err)
+		}
+	})
+
+	t.Run("keep metadata", func(t *testing.T) {
+		media := newMediaService(repositories.Media, fileStorage, MediaOptions{KeepMetadata: true})
+		data := newTestJPEGWithMetadata(t, 6)
+		mediaId, err := media.Create(ctx, CreateMediaInput{Name: "photo", Size: int64(len(data)), Data: bytes.NewReader(data), AllowedTypes: ImageContentTypes})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertFile(t, media, mediaId, 0, "image/jpeg", int64(len(data)))
+	})
+}
+
+func TestStripContainerMetadata(t *testing.T) {
+	heic := newTestHEICWithMetadata()
+	stripped, err := stripImageMetadata("image/heic", heic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the exif item is zeroed in place, so nothing else moves
+	if len(stripped) != len(heic) || bytes.Contains(stripped, []byte("SECRET")) || !bytes.Equal(stripped[:len(heic)-12], heic[:len(heic)-12]) {
+		t.Fatalf("Incorrect stripped heic: %q", stripped)
+	}
+
+	webp := newTestWebPWithMetadata()
+	if stripped, err = stripImageMetadata("image/webp", webp); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte("RIFF\x20\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00VP8L\x01\x00\x00\x00\x2f\x00")
+	if !bytes.Equal(stripped, expected) {
+		t.Fatalf("Expected webp: %q\nActual webp: %q", expected, stripped)
+	}
+
+	if _, err = stripImageMetadata("image/heic", []byte("\x00\x00\x00\x18ftypheic")); err != errInvalidImage {
+		t.Fatalf("Expected error: %v\nActual error: %v", errInvalidImage, err)
+	}
+}
+
+const testMP4Header = "\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"
+
+// testSigningStorage records the uploaded content types and signs a link as the file id with the content type.
+type testSigningStorage struct {
+	storage.FileStorage
+	contentTypes map[string]string
+}
+
+func (s *testSigningStorage) Upload(ctx context.Context, id string, contentType string, data io.Reader, size int64) error {
+	s.contentTypes[id] = contentType
+	return s.FileStorage.Upload(ctx, id, contentType, data, size)
+}
+
+func (s *testSigningStorage) GetURL(_ context.Context, id string, contentType string) (string, error) {
+	return id + " " + contentType, nil
+}
+
+func newTestPNG(t *testing.T, width int, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.NRGBA{R: uint8(x), A: 255})
+	}
+
+	buffer := bytes.Buffer{}
+	if err := png.Encode(&buffer, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// newTestImage returns a 2000x1000 image with the red left half and the blue right half.
+func newTestImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 1000))
+	draw.Draw(img, image.Rect(0, 0, 1000, 1000), image.NewUniform(color.RGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(1000, 0, 2000, 1000), image.NewUniform(color.RGBA{B: 255, A: 255}), image.Point{}, draw.Src)
+	return img
+}
+
+// newTestExif returns little endian tiff data with the orientation and a secret camera model.
+func newTestExif(orientation int) []byte {
+	buffer := bytes.Buffer{}
+	buffer.WriteString("II*\x00")
+	for _, value := range []interface{}{
+		uint32(8), uint16(2),
+		uint16(0x0110), uint16(2), uint32(7), uint32(38),
+		uint16(0x0112), uint16(3), uint32(1), uint16(orientation), uint16(0),
+		uint32(0),
+	} {
+		_ = binary.Write(&buffer, binary.LittleEndian, value)
+	}
+
+	buffer.WriteString("SECRET\x00")
+	return buffer.Bytes()
+}
+
+func newTestJPEGWithMetadata(t *testing.T, orientation int) []byte {
+	buffer := bytes.Buffer{}
+	if err := jpeg.Encode(&buffer, newTestImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	result := bytes.Buffer{}
+	result.Write([]byte{0xFF, 0xD8})
+	writeJPEGSegment(&result, 0xE1, append([]byte("Exif\x00\x00"), newTestExif(orientation)...))
+	writeJPEGSegment(&result, 0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<gps>SECRET</gps>"))
+	writeJPEGSegment(&result, 0xED, []byte("Photoshop 3.0\x00SECRET"))
+	writeJPEGSegment(&result, 0xFE, []byte("SECRET"))
+	result.Write(buffer.Bytes()[2:])
+	result.WriteString("MPF SECRET")
+	return result.Bytes()
+}
+
+func newTestPNGWithMetadata(t *testing.T, orientation int) []byte {
+	buffer := bytes.Buffer{}
+	if err := png.Encode(&buffer, newTestImage()); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, err := readPNGChunks(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := bytes.Buffer{}
+	result.Write(pngSignature)
+	writePNGChunk(&result, chunks[0].kind, chunks[0].data)
+	writePNGChunk(&result, "eXIf", newTestExif(orientation))
+	writePNGChunk(&result, "tEXt", []byte("Comment\x00SECRET"))
+
+	for _, chunk := range chunks[1:] {
+		writePNGChunk(&result, chunk.kind, chunk.data)
+	}
+
+	return result.Bytes()
+}
+
+// newTestHEICWithMetadata returns a heic structure with the exif item at the end of the file.
+func newTestHEICWithMetadata() []byte {
+	box := func(kind string, data ...[]byte) []byte {
+		body := bytes.Join(data, nil)
+		result := make([]byte, 4, 8+len(body))
+		binary.BigEndian.PutUint32(result, uint32(8+len(body)))
+		return append(append(result, kind...), body...)
+	}
+
+	infe := box("infe", []byte("\x02\x00\x00\x00\x00\x01\x00\x00Exif\x00"))
+	iinf := box("iinf", []byte("\x00\x00\x00\x00\x00\x01"), infe)
+	iloc := func(offset int) []byte {
+		data := []byte("\x00\x00\x00\x00\x44\x00\x00\x01\x00\x01\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x0c")
+		binary.BigEndian.PutUint32(data[14:], uint32(offset))
+		return box("iloc", data)
+	}
+	ftyp := box("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	meta := box("meta", []byte("\x00\x00\x00\x00"), iinf, iloc(0))
+	offset := len(ftyp) + len(meta) + 8
+	return bytes.Join([][]byte{ftyp, box("meta", []byte("\x00\x00\x00\x00"), iinf, iloc(offset)), box("mdat", []byte("Exif\x00\x00SECRET"))}, nil)
+}
+
+func newTestWebPWithMetadata() []byte {
+	return []byte("RIFF\x2e\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x08\x00\x00\x00\x00\x00\x00\x00\x00\x00VP8L\x01\x00\x00\x00\x2f\x00EXIF\x06\x00\x00\x00SECRET")
+}
+
+func assertVariants(t *testing.T, variants []domain.MediaVariant, expected ...string) {
+	actual := make([]string, len(variants))
+	for i, variant := range variants {
+		actual[i] = fmt.Sprintf("%dx%d", variant.Width, variant.Height)
+		if variant.ContentType != "image/jpeg" || variant.FileSize == 0 {
+			t.Fatalf("Incorrect variant: %+v", variant)
+		}
+	}
+
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected variants: %v\nActual variants: %v", expected, actual)
+	}
+}
+
+func assertFile(t *testing.T, media Media, id string, size int, contentType string, fileSize int64) {
+	file, err := media.GetFile(context.Background(), id, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Data.Close()
+	data, err := ioutil.ReadAll(file.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if file.ContentType != contentType || file.Size != fileSize || int64(len(data)) != fileSize {
+		t.Fatalf("Incorrect file for size %d: %s, %d bytes, %d read", size, file.ContentType, file.Size, len(data))
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+type eventPermission int
+
+const (
+	permissionUpdateEvent eventPermission = iota
+	permissionCloseEvent
+	permissionAddMedia
+	permissionRemoveMedia
+	permissionManageAccess
+	permissionManageRoles
+	permissionModerateChat
+	permissionRemoveAttendees
+)
+
+// permissionRoles maps every event permission to the lowest role it is granted to.
+var permissionRoles = map[eventPermission]domain.EventRole{
+	permissionUpdateEvent:     domain.EventRoleCoHost,
+	permissionCloseEvent:      domain.EventRoleOwner,
+	permissionAddMedia:        domain.EventRoleCoHost,
+	permissionRemoveMedia:     domain.EventRoleModerator,
+	permissionManageAccess:    domain.EventRoleCoHost,
+	permissionManageRoles:     domain.EventRoleOwner,
+	permissionModerateChat:    domain.EventRoleModerator,
+	permissionRemoveAttendees: domain.EventRoleModerator,
+}
+
+// permissionError keeps UserIsNotOwner for the actions that were owner-only before the roles.
+func permissionError(permission eventPermission) error {
+	switch permission {
+	case permissionUpdateEvent, permissionCloseEvent, permissionAddMedia, permissionRemoveMedia, permissionManageAccess:
+		return domain.ErrUserIsNotOwner
+	default:
+		return domain.ErrPermissionDenied
+	}
+}
+
+func roleOf(event domain.Event, userId string) domain.EventRole {
+	if userId == "" {
+		return domain.EventRoleGuest
+	}
+
+	if event.OwnerID == userId {
+		return domain.EventRoleOwner
+	}
+
+	for _, roleInfo := range event.Roles {
+		if roleInfo.UserID == userId {
+			return roleInfo.Role
+		}
+	}
+
+	return domain.EventRoleGuest
+}
+
+func (s *eventService) authorize(ctx context.Context, eventId string, userId string, permission eventPermission) (domain.Event, error) {
+	event, err := s.repository.GetEventById(ctx, eventId)
+
+	if err != nil {
+		return domain.Event{}, err
+	}
+
+	if roleOf(event, userId) < permissionRoles[permission] {
+		return domain.Event{}, permissionError(permission)
+	}
+
+	return event, nil
+}
+
+// authorizeOver is authorize for actions aimed at another attendee, whose role must be lower than the user's one.
+func (s *eventService) authorizeOver(ctx context.Context, eventId string, userId string, targetUserId string, permission eventPermission) (domain.Event, error) {
+	event, err := s.authorize(ctx, eventId, userId, permission)
+
+	if err != nil {
+		return domain.Event{}, err
+	}
+
+	if roleOf(event, targetUserId) >= roleOf(event, userId) {
+		return domain.Event{}, domain.ErrPermissionDenied
+	}
+
+	return event, nil
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/broker"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+)
+
+const defaultTypingInterval = time.Second * 3
+
+type presenceEntry struct {
+	user        domain.PresenceUser
+	connections int
+	typedAt     time.Time
+}
+
+type presenceKey struct {
+	eventId string
+	userId  string
+}
+
+// presenceService counts the connections of the users to the event websockets in the broker presence,
+// which is shared by all the instances, so only the first connection of a user adds them to the event
+// and only the last one removes. A user whose last connection dropped stays in the event for
+// the grace period, so a reconnect to any instance neither removes nor re-adds them.
+// The connections of this instance are kept in memory for the typing notifications.
+type presenceService struct {
+	mutex          sync.Mutex
+	logger         logger.Logger
+	publisher      Publisher
+	store          broker.Presence
+	eventService   Events
+	typingInterval time.Duration
+	gracePeriod    time.Duration
+	events         map[string]map[string]*presenceEntry
+	removals       map[presenceKey]*time.Timer
+	closed         bool
+}
+
+func newPresenceService(logger logger.Logger, publisher Publisher, store broker.Presence, eventService Events,
+	typingInterval time.Duration, gracePeriod time.Duration) Presence {
+	if typingInterval <= 0 {
+		typingInterval = defaultTypingInterval
+	}
+
+	return &presenceService{
+		logger:         logger,
+		publisher:      publisher,
+		store:          store,
+		eventService:   eventService,
+		typingInterval: typingInterval,
+		gracePeriod:    gracePeriod,
+		events:         make(map[string]map[string]*presenceEntry),
+		removals:       make(map[presenceKey]*time.Timer),
+	}
+}
+
+// Join adds the user to the event on the first connection and keeps the existing entry on a reconnect.
+func (s *presenceService) Join(ctx context.Context, eventId string, user domain.PresenceUser) error {
+	data, err := json.Marshal(user)
+
+	if err != nil {
+		return err
+	}
+
+	added, err := s.store.Connect(eventId, user.UserID, data)
+
+	if err != nil {
+		return err
+	}
+
+	if added {
+		err = s.eventService.AddUserInfo(ctx, AddUserInfoInput{
+			EventID: eventId,
+			UserID:  user.UserID,
+		})
+
+		if err != nil {
+			s.disconnect(eventId, user.UserID)
+			return err
+		}
+	}
+
+	s.attach(eventId, user)
+
+	if !added {
+		return nil
+	}
+
+	return s.publish(eventId, "userJoined/", user)
+}
+
+func (s *presenceService) Leave(eventId string, userId string) error {
+	s.mutex.Lock()
+	entry, ok := s.events[eventId][userId]
+
+	if !ok {
+		s.mutex.Unlock()
+		return nil
+	}
+
+	entry.connections--
+
+	if entry.connections == 0 {
+		s.delete(eventId, userId)
+	}
+
+	s.mutex.Unlock()
+	connections, err := s.store.Disconnect(eventId, userId)
+
+	if err != nil || connections > 0 {
+		return err
+	}
+
+	s.mutex.Lock()
+
+	if s.gracePeriod > 0 && !s.closed {
+		key := presenceKey{eventId: eventId, userId: userId}
+
+		if removal, ok := s.removals[key]; ok {
+			removal.Stop()
+		}
+
+		s.removals[key] = time.AfterFunc(s.gracePeriod, func() {
+			s.expire(key)
+		})
+		s.mutex.Unlock()
+		return nil
+	}
+
+	s.mutex.Unlock()
+	return s.remove(eventId, userId)
+}
+
+func (s *presenceService) GetUsers(eventId string) ([]domain.PresenceUser, error) {
+	members, err := s.store.Members(eventId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.PresenceUser, len(members))
+
+	for i, data := range members {
+		if err = json.Unmarshal(data, &result[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Typing broadcasts that the user is typing at most once per typing interval.
+func (s *presenceService) Typing(eventId string, userId string) error {
+	now := time.Now()
+	s.mutex.Lock()
+	entry, ok := s.events[eventId][userId]
+
+	if !ok || now.Sub(entry.typedAt) < s.typingInterval {
+		s.mutex.Unlock()
+		return nil
+	}
+
+	entry.typedAt = now
+	user := entry.user
+	s.mutex.Unlock()
+	return s.publish(eventId, "userTyping/", user)
+}
+
+// Close drops all the connections to this instance and removes the users left without connections
+// or waiting for a reconnect right away, the leaves of the dropped connections that come later are ignored.
+func (s *presenceService) Close() {
+	s.mutex.Lock()
+	s.closed = true
+	pending := make([]presenceKey, 0, len(s.removals))
+
+	for key, removal := range s.removals {
+		removal.Stop()
+		pending = append(pending, key)
+	}
+
+	s.removals = make(map[presenceKey]*time.Timer)
+	events := s.events
+	s.events = make(map[string]map[string]*presenceEntry)
+	s.mutex.Unlock()
+
+	for eventId, users := range events {
+		for userId, entry := range users {
+			if s.disconnectAll(eventId, userId, entry.connections) {
+				pending = append(pending, presenceKey{eventId: eventId, userId: userId})
+			}
+		}
+	}
+
+	for _, key := range pending {
+		s.logRemoveError(s.remove(key.eventId, key.userId))
+	}
+}
+
+// attach counts one more connection of the user to this instance.
+func (s *presenceService) attach(eventId string, user domain.PresenceUser) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	users, ok := s.events[eventId]
+
+	if !ok {
+		users = make(map[string]*presenceEntry)
+		s.events[eventId] = users
+	}
+
+	if entry, ok := users[user.UserID]; ok {
+		entry.connections++
+		return
+	}
+
+	users[user.UserID] = &presenceEntry{user: user, connections: 1}
+}
+
+// disconnect rolls back the connection of a user who couldn't be added to the event.
+func (s *presenceService) disconnect(eventId string, userId string) {
+	if _, err := s.store.Disconnect(eventId, userId); err != nil {
+		s.logger.LogError(err)
+		return
+	}
+
+	if _, err := s.store.Remove(eventId, userId); err != nil {
+		s.logger.LogError(err)
+	}
+}
+
+// disconnectAll drops the connections of the user to this instance, true means no connections are left.
+func (s *presenceService) disconnectAll(eventId string, userId string, connections int) bool {
+	left := int64(0)
+
+	for i := 0; i < connections; i++ {
+		var err error
+
+		if left, err = s.store.Disconnect(eventId, userId); err != nil {
+			s.logger.LogError(err)
+			return false
+		}
+	}
+
+	return left == 0
+}
+
+func (s *presenceService) expire(key presenceKey) {
+	s.mutex.Lock()
+	delete(s.removals, key)
+	s.mutex.Unlock()
+	s.logRemoveError(s.remove(key.eventId, key.userId))
+}
+
+func (s *presenceService) delete(eventId string, userId string) {
+	users := s.events[eventId]
+	delete(users, userId)
+
+	if len(users) == 0 {
+		delete(s.events, eventId)
+	}
+}
+
+// remove takes the user out of the event unless they connected again to any instance.
+func (s *presenceService) remove(eventId string, userId string) error {
+	removed, err := s.store.Remove(eventId, userId)
+
+	if err != nil || !removed {
+		return err
+	}
+
+	if err = s.publish(eventId, "userLeft/", userRemoved{UserID: userId}); err != nil {
+		return err
+	}
+
+	return s.eventService.RemoveUserInfo(context.Background(), eventId, userId)
+}
+
+func (s *presenceService) logRemoveError(err error) {
+	if err != nil && domain.IsInternalError(err) {
+		s.logger.LogError(err)
+	}
+}
+
+func (s *presenceService) publish(eventId string, prefix string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	s.publisher.Publish(eventId, []byte(prefix+string(data)))
+	return nil
+}
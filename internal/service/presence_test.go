@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/broker"
+)
+
+// testUserInfoEvents records the users added to and removed from events by the presence service.
+type testUserInfoEvents struct {
+	Events
+	added   chan string
+	removed chan string
+}
+
+func newTestUserInfoEvents() *testUserInfoEvents {
+	return &testUserInfoEvents{
+		added:   make(chan string, 10),
+		removed: make(chan string, 10),
+	}
+}
+
+func (e *testUserInfoEvents) AddUserInfo(_ context.Context, input AddUserInfoInput) error {
+	e.added <- input.UserID
+	return nil
+}
+
+func (e *testUserInfoEvents) RemoveUserInfo(_ context.Context, _ string, userId string) error {
+	e.removed <- userId
+	return nil
+}
+
+func TestPresence(t *testing.T) {
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	subscriber := newTestSubscriber()
+	pub.Subscribe("event_id", subscriber)
+	events := newTestUserInfoEvents()
+	presence := newPresenceService(testLogger{t: t}, pub, broker.NewMemoryBroker().Presence(), events, time.Hour, 0)
+	first := domain.PresenceUser{UserID: "first", UserName: "first_name", UserImageID: "image_id"}
+	second := domain.PresenceUser{UserID: "second", UserName: "second_name"}
+
+	for _, user := range []domain.PresenceUser{first, second, first} {
+		if err := presence.Join(context.Background(), "event_id", user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertReceived(t, subscriber, `userJoined/{"userId":"first","userName":"first_name","userImageId":"image_id"}`)
+	assertReceived(t, subscriber, `userJoined/{"userId":"second","userName":"second_name","userImageId":""}`)
+	assertPresence(t, presence, "event_id", []domain.PresenceUser{first, second})
+
+	if err := presence.Typing("event_id", "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := presence.Typing("event_id", "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := presence.Typing("event_id", "unknown"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, subscriber, `userTyping/{"userId":"first","userName":"first_name","userImageId":"image_id"}`)
+
+	if err := presence.Leave("event_id", "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPresence(t, presence, "event_id", []domain.PresenceUser{first, second})
+
+	if err := presence.Leave("event_id", "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, subscriber, `userLeft/{"userId":"first"}`)
+	assertPresence(t, presence, "event_id", []domain.PresenceUser{second})
+	assertPresence(t, presence, "other_event_id", []domain.PresenceUser{})
+	assertUserIds(t, events.added, "first", "second")
+	assertUserIds(t, events.removed, "first")
+
+	select {
+	case message := <-subscriber.messages:
+		t.Fatalf("Unexpected message: %s", message)
+	default:
+	}
+}
+
+func TestPresenceReconnect(t *testing.T) {
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	subscriber := newTestSubscriber()
+	pub.Subscribe("event_id", subscriber)
+	events := newTestUserInfoEvents()
+	presence := newPresenceService(testLogger{t: t}, pub, broker.NewMemoryBroker().Presence(), events, time.Hour, time.Millisecond*100)
+	user := domain.PresenceUser{UserID: "user_id", UserName: "user_name"}
+
+	if err := presence.Join(context.Background(), "event_id", user); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, subscriber, `userJoined/{"userId":"user_id","userName":"user_name","userImageId":""}`)
+
+	if err := presence.Leave("event_id", "user_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPresence(t, presence, "event_id", []domain.PresenceUser{user})
+
+	if err := presence.Join(context.Background(), "event_id", user); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond * 200)
+	assertPresence(t, presence, "event_id", []domain.PresenceUser{user})
+	assertUserIds(t, events.added, "user_id")
+	assertUserIds(t, events.removed)
+
+	if err := presence.Leave("event_id", "user_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, subscriber, `userLeft/{"userId":"user_id"}`)
+	assertPresence(t, presence, "event_id", []domain.PresenceUser{})
+	assertUserIds(t, events.removed, "user_id")
+}
+
+func TestPresenceClose(t *testing.T) {
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	events := newTestUserInfoEvents()
+	presence := newPresenceService(testLogger{t: t}, pub, broker.NewMemoryBroker().Presence(), events, time.Hour, time.Hour)
+	user := domain.PresenceUser{UserID: "user_id"}
+
+	if err := presence.Join(context.Background(), "event_id", user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := presence.Leave("event_id", "user_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertUserIds(t, events.removed)
+	presence.Close()
+	assertUserIds(t, events.removed, "user_id")
+	assertPresence(t, presence, "event_id", []domain.PresenceUser{})
+
+	if err := presence.Join(context.Background(), "event_id", user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := presence.Leave("event_id", "user_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertUserIds(t, events.removed, "user_id")
+}
+
+func TestPresenceReplicas(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer server.Close()
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	subscriber := newTestSubscriber()
+	pub.Subscribe("event_id", subscriber)
+	events := newTestUserInfoEvents()
+	first := newTestRedisPresence(t, server.Addr(), pub, events, 0)
+	second := newTestRedisPresence(t, server.Addr(), pub, events, 0)
+	user := domain.PresenceUser{UserID: "user_id", UserName: "user_name"}
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		replica := first
+		if i%2 == 1 {
+			replica = second
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := replica.Join(context.Background(), "event_id", user); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	assertReceived(t, subscriber, `userJoined/{"userId":"user_id","userName":"user_name","userImageId":""}`)
+	assertUserIds(t, events.added, "user_id")
+	assertPresence(t, first, "event_id", []domain.PresenceUser{user})
+
+	for i := 0; i < 5; i++ {
+		if err = first.Leave("event_id", "user_id"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertUserIds(t, events.removed)
+	assertPresence(t, first, "event_id", []domain.PresenceUser{user})
+
+	if err = first.Typing("event_id", "user_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = second.Typing("event_id", "user_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, subscriber, `userTyping/{"userId":"user_id","userName":"user_name","userImageId":""}`)
+
+	for i := 0; i < 5; i++ {
+		if err = second.Leave("event_id", "user_id"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertReceived(t, subscriber, `userLeft/{"userId":"user_id"}`)
+	assertUserIds(t, events.removed, "user_id")
+	assertPresence(t, first, "event_id", []domain.PresenceUser{})
+
+	select {
+	case message := <-subscriber.messages:
+		t.Fatalf("Unexpected message: %s", message)
+	default:
+	}
+}
+
+func TestPresenceReplicasReconnect(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer server.Close()
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	events := newTestUserInfoEvents()
+	first := newTestRedisPresence(t, server.Addr(), pub, events, time.Millisecond*100)
+	second := newTestRedisPresence(t, server.Addr(), pub, events, time.Millisecond*100)
+	user := domain.PresenceUser{UserID: "user_id"}
+
+	if err = first.Join(context.Background(), "event_id", user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = first.Leave("event_id", "user_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = second.Join(context.Background(), "event_id", user); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond * 200)
+	assertPresence(t, first, "event_id", []domain.PresenceUser{user})
+	assertUserIds(t, events.added, "user_id")
+	assertUserIds(t, events.removed)
+
+	if err = second.Leave("event_id", "user_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertUserIds(t, events.removed, "user_id")
+	assertPresence(t, second, "event_id", []domain.PresenceUser{})
+}
+
+func TestPresenceReplicasClose(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer server.Close()
+	pub := newTestPublisher(t, PublisherOptions{})
+	defer pub.CloseAll()
+	subscriber := newTestSubscriber()
+	pub.Subscribe("event_id", subscriber)
+	events := newTestUserInfoEvents()
+	first := newTestRedisPresence(t, server.Addr(), pub, events, time.Hour)
+	second := newTestRedisPresence(t, server.Addr(), pub, events, time.Hour)
+	shared := domain.PresenceUser{UserID: "shared_id"}
+	local := domain.PresenceUser{UserID: "local_id"}
+
+	for _, join := range []struct {
+		presence Presence
+		user     domain.PresenceUser
+	}{{first, shared}, {first, shared}, {first, local}, {second, shared}} {
+		if err = join.presence.Join(context.Background(), "event_id", join.user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertUserIds(t, events.added, "shared_id", "local_id")
+	assertReceived(t, subscriber, `userJoined/{"userId":"shared_id","userName":"","userImageId":""}`)
+	assertReceived(t, subscriber, `userJoined/{"userId":"local_id","userName":"","userImageId":""}`)
+	first.Close()
+	assertReceived(t, subscriber, `userLeft/{"userId":"local_id"}`)
+	assertUserIds(t, events.removed, "local_id")
+	assertPresence(t, second, "event_id", []domain.PresenceUser{shared})
+
+	if err = first.Leave("event_id", "shared_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPresence(t, second, "event_id", []domain.PresenceUser{shared})
+}
+
+func newTestRedisPresence(t *testing.T, addr string, pub Publisher, events Events, gracePeriod time.Duration) Presence {
+	t.Helper()
+	messageBroker, err := broker.NewRedisBroker("redis://"+addr, "vpiska:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = messageBroker.Close()
+	})
+	return newPresenceService(testLogger{t: t}, pub, messageBroker.Presence(), events, time.Hour, gracePeriod)
+}
+
+func assertUserIds(t *testing.T, actual <-chan string, expected ...string) {
+	t.Helper()
+	for _, userId := range expected {
+		select {
+		case id := <-actual:
+			if id != userId {
+				t.Fatalf("Incorrect user\nExpected: %s\nActual: %s", userId, id)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("User %s was not received", userId)
+		}
+	}
+
+	select {
+	case id := <-actual:
+		t.Fatalf("Unexpected user: %s", id)
+	default:
+	}
+}
+
+func assertPresence(t *testing.T, presence Presence, eventId string, expected []domain.PresenceUser) {
+	t.Helper()
+	actual, err := presence.GetUsers(eventId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actual) != len(expected) {
+		t.Fatalf("Incorrect presence\nExpected: %+v\nActual: %+v", expected, actual)
+	}
+
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("Incorrect presence\nExpected: %+v\nActual: %+v", expected, actual)
+		}
+	}
+}
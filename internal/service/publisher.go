@@ -1,64 +1,404 @@
 package service
 
-import "sync"
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	OverflowPolicyDropOldest = "drop_oldest"
+	OverflowPolicyDisconnect = "disconnect"
+)
+
+const (
+	defaultQueueSize  = 64
+	defaultReplaySize = 256
+	defaultReplayTTL  = time.Minute * 10
+)
+
+// resyncMessage replaces the replay when the messages after the last seen sequence are no longer buffered,
+// the client has to reload the event state.
+const resyncMessage = "resync/"
+
+var ErrUnknownOverflowPolicy = errors.New("unknown publisher overflow policy")
+
+// PublisherOptions ReplayTTL is how long the messages of a topic without subscribers are kept for replay
+// after the last of them was published.
+type PublisherOptions struct {
+	QueueSize      int
+	OverflowPolicy string
+	ReplaySize     int
+	ReplayTTL      time.Duration
+}
+
+type PublisherMetrics struct {
+	DroppedMessages         uint64 `json:"droppedMessages"`
+	DisconnectedSubscribers uint64 `json:"disconnectedSubscribers"`
+}
+
+type sequencedMessage struct {
+	seq  uint64
+	data []byte
+}
+
+// eventStream keeps the number of the last message published to an event and the latest messages for replay.
+type eventStream struct {
+	seq         uint64
+	replay      []sequencedMessage
+	publishedAt time.Time
+}
+
+// append starts the replay over when the message doesn't follow the last one,
+// so the buffered messages always go one after another.
+func (s *eventStream) append(message sequencedMessage, replaySize int) {
+	if len(s.replay) > 0 && s.replay[len(s.replay)-1].seq+1 != message.seq {
+		s.replay = nil
+	}
+
+	s.replay = append(s.replay, message)
+
+	if len(s.replay) > replaySize {
+		s.replay = s.replay[len(s.replay)-replaySize:]
+	}
+}
+
+// since returns the messages published after lastSeq or false when some of them are no longer buffered.
+func (s *eventStream) since(lastSeq uint64) ([]sequencedMessage, bool) {
+	if lastSeq == 0 || lastSeq > s.seq {
+		return nil, false
+	}
+
+	if lastSeq == s.seq {
+		return nil, true
+	}
+
+	if len(s.replay) == 0 || s.replay[0].seq > lastSeq+1 {
+		return nil, false
+	}
+
+	missed := s.replay[lastSeq+1-s.replay[0].seq:]
+	result := make([]sequencedMessage, len(missed))
+	copy(result, missed)
+	return result, true
+}
 
 type publisher struct {
-	mutex         sync.RWMutex
-	subscriptions map[string][]Subscriber
+	mutex                   sync.RWMutex
+	subscriptions           map[string][]*subscription
+	streams                 map[string]*eventStream
+	queueSize               int
+	replaySize              int
+	replayTTL               time.Duration
+	sweptAt                 time.Time
+	overflowPolicy          string
+	droppedMessages         uint64
+	disconnectedSubscribers uint64
 }
 
-func newPublisher() Publisher {
-	return &publisher{
-		mutex:         sync.RWMutex{},
-		subscriptions: make(map[string][]Subscriber),
+func newPublisher(options PublisherOptions) (*publisher, error) {
+	queueSize := options.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+
+	replaySize := options.ReplaySize
+	if replaySize <= 0 {
+		replaySize = defaultReplaySize
+	}
+
+	replayTTL := options.ReplayTTL
+	if replayTTL <= 0 {
+		replayTTL = defaultReplayTTL
+	}
+
+	overflowPolicy := options.OverflowPolicy
+	switch overflowPolicy {
+	case "":
+		overflowPolicy = OverflowPolicyDropOldest
+	case OverflowPolicyDropOldest, OverflowPolicyDisconnect:
+	default:
+		return nil, ErrUnknownOverflowPolicy
 	}
+
+	return &publisher{
+		mutex:          sync.RWMutex{},
+		subscriptions:  make(map[string][]*subscription),
+		streams:        make(map[string]*eventStream),
+		queueSize:      queueSize,
+		replaySize:     replaySize,
+		replayTTL:      replayTTL,
+		sweptAt:        time.Now(),
+		overflowPolicy: overflowPolicy,
+	}, nil
 }
 
 func (p *publisher) Subscribe(eventId string, subscriber Subscriber) {
+	sub := newSubscription(subscriber, p.queueSize)
 	p.mutex.Lock()
-	p.subscriptions[eventId] = append(p.subscriptions[eventId], subscriber)
+	p.subscriptions[eventId] = append(p.subscriptions[eventId], sub)
 	p.mutex.Unlock()
+	go sub.run()
 }
 
-func (p *publisher) Unsubscribe(eventId string, subscriber Subscriber) {
-	p.mutex.RLock()
-	subs := p.subscriptions[eventId]
-	p.mutex.RUnlock()
-	for i, sub := range subs {
-		if sub == subscriber {
-			p.mutex.Lock()
-			p.subscriptions[eventId] = append(p.subscriptions[eventId][:i], p.subscriptions[eventId][i+1:]...)
-			p.mutex.Unlock()
-			return
+// Resume subscribes to the event and first delivers the messages published after lastSeq,
+// when they are no longer buffered the subscriber receives a resync message with the current sequence instead.
+func (p *publisher) Resume(eventId string, lastSeq uint64, subscriber Subscriber) {
+	p.resume(eventId, lastSeq, 0, subscriber)
+}
+
+// resume is Resume that sends the resync message with currentSeq when this instance hasn't seen that message yet.
+func (p *publisher) resume(eventId string, lastSeq uint64, currentSeq uint64, subscriber Subscriber) {
+	p.mutex.Lock()
+	stream := p.stream(eventId)
+	missed, ok := stream.since(lastSeq)
+
+	if !ok {
+		if currentSeq < stream.seq {
+			currentSeq = stream.seq
 		}
+
+		missed = []sequencedMessage{{seq: currentSeq, data: []byte(resyncMessage)}}
+	}
+
+	sub := newSubscription(subscriber, p.queueSize+len(missed))
+	sub.queue = missed
+	p.subscriptions[eventId] = append(p.subscriptions[eventId], sub)
+	p.mutex.Unlock()
+	go sub.run()
+	sub.signal()
+}
+
+func (p *publisher) Unsubscribe(eventId string, subscriber Subscriber) {
+	if sub := p.remove(eventId, subscriber); sub != nil {
+		sub.stop()
 	}
 }
 
 func (p *publisher) Publish(eventId string, message []byte) {
-	p.mutex.RLock()
-	subs := p.subscriptions[eventId]
-	p.mutex.RUnlock()
-	for _, subscriber := range subs {
-		subscriber.OnReceive(message)
+	p.publish(eventId, 0, message)
+}
+
+// publish sends the message numbered by the broker with seq, 0 numbers it after the last message of the event.
+func (p *publisher) publish(eventId string, seq uint64, message []byte) {
+	dropOldest := p.overflowPolicy == OverflowPolicyDropOldest
+	var overflowed []*subscription
+
+	// messages are numbered and queued under the lock, so every subscriber gets them in sequence order
+	p.mutex.Lock()
+	p.sweep()
+	stream := p.stream(eventId)
+
+	if seq == 0 {
+		seq = stream.seq + 1
+	}
+
+	stream.seq = seq
+	stream.publishedAt = time.Now()
+	sequenced := sequencedMessage{seq: seq, data: message}
+	stream.append(sequenced, p.replaySize)
+
+	for _, sub := range p.subscriptions[eventId] {
+		if sub.push(sequenced, dropOldest) {
+			continue
+		}
+
+		if dropOldest {
+			atomic.AddUint64(&p.droppedMessages, 1)
+			continue
+		}
+
+		overflowed = append(overflowed, sub)
+	}
+
+	p.mutex.Unlock()
+
+	for _, sub := range overflowed {
+		if p.remove(eventId, sub.subscriber) != nil {
+			atomic.AddUint64(&p.disconnectedSubscribers, 1)
+			sub.stop()
+			sub.subscriber.OnClose()
+		}
 	}
 }
 
 func (p *publisher) Close(eventId string) {
-	p.mutex.RLock()
+	p.mutex.Lock()
 	subs := p.subscriptions[eventId]
-	p.mutex.RUnlock()
+	delete(p.subscriptions, eventId)
+	delete(p.streams, eventId)
+	p.mutex.Unlock()
 
-	for _, subscriber := range subs {
-		subscriber.OnClose()
+	for _, sub := range subs {
+		sub.close()
 	}
+}
 
+func (p *publisher) CloseAll() {
 	p.mutex.Lock()
-	delete(p.subscriptions, eventId)
+	subscriptions := p.subscriptions
+	p.subscriptions = make(map[string][]*subscription)
+	p.streams = make(map[string]*eventStream)
 	p.mutex.Unlock()
+
+	for _, subs := range subscriptions {
+		for _, sub := range subs {
+			sub.close()
+		}
+	}
 }
 
-func (p *publisher) CloseAll() {
-	for eventId := range p.subscriptions {
-		p.Close(eventId)
+func (p *publisher) Metrics() PublisherMetrics {
+	return PublisherMetrics{
+		DroppedMessages:         atomic.LoadUint64(&p.droppedMessages),
+		DisconnectedSubscribers: atomic.LoadUint64(&p.disconnectedSubscribers),
+	}
+}
+
+func (p *publisher) stream(eventId string) *eventStream {
+	stream, ok := p.streams[eventId]
+
+	if !ok {
+		stream = &eventStream{publishedAt: time.Now()}
+		p.streams[eventId] = stream
+	}
+
+	return stream
+}
+
+// sweep drops the streams of the topics without subscribers that got no messages for the replay ttl,
+// like the notifications of users who went offline. It runs at most once per the ttl, the caller holds the lock.
+func (p *publisher) sweep() {
+	now := time.Now()
+
+	if now.Sub(p.sweptAt) < p.replayTTL {
+		return
+	}
+
+	p.sweptAt = now
+
+	for topic, stream := range p.streams {
+		if len(p.subscriptions[topic]) == 0 && now.Sub(stream.publishedAt) >= p.replayTTL {
+			delete(p.streams, topic)
+		}
+	}
+}
+
+func (p *publisher) remove(eventId string, subscriber Subscriber) *subscription {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	subs := p.subscriptions[eventId]
+
+	for i, sub := range subs {
+		if sub.subscriber == subscriber {
+			updated := make([]*subscription, 0, len(subs)-1)
+			updated = append(updated, subs[:i]...)
+			updated = append(updated, subs[i+1:]...)
+
+			if len(updated) == 0 {
+				delete(p.subscriptions, eventId)
+			} else {
+				p.subscriptions[eventId] = updated
+			}
+
+			return sub
+		}
+	}
+
+	return nil
+}
+
+type subscription struct {
+	subscriber Subscriber
+	mutex      sync.Mutex
+	queue      []sequencedMessage
+	queueSize  int
+	closing    bool
+	stopped    bool
+	notify     chan struct{}
+}
+
+func newSubscription(subscriber Subscriber, queueSize int) *subscription {
+	return &subscription{
+		subscriber: subscriber,
+		mutex:      sync.Mutex{},
+		queueSize:  queueSize,
+		notify:     make(chan struct{}, 1),
+	}
+}
+
+func (s *subscription) push(message sequencedMessage, dropOldest bool) bool {
+	s.mutex.Lock()
+	if s.stopped || s.closing {
+		s.mutex.Unlock()
+		return true
+	}
+
+	isAccepted := true
+	if len(s.queue) >= s.queueSize {
+		if !dropOldest {
+			s.mutex.Unlock()
+			return false
+		}
+
+		s.queue = s.queue[1:]
+		isAccepted = false
+	}
+
+	s.queue = append(s.queue, message)
+	s.mutex.Unlock()
+	s.signal()
+	return isAccepted
+}
+
+func (s *subscription) close() {
+	s.mutex.Lock()
+	s.closing = true
+	s.mutex.Unlock()
+	s.signal()
+}
+
+func (s *subscription) stop() {
+	s.mutex.Lock()
+	s.stopped = true
+	s.queue = nil
+	s.mutex.Unlock()
+	s.signal()
+}
+
+func (s *subscription) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscription) run() {
+	for range s.notify {
+		for {
+			s.mutex.Lock()
+			if s.stopped {
+				s.mutex.Unlock()
+				return
+			}
+
+			if len(s.queue) == 0 {
+				closing := s.closing
+				s.mutex.Unlock()
+
+				if closing {
+					s.subscriber.OnClose()
+					return
+				}
+
+				break
+			}
+
+			message := s.queue[0]
+			s.queue = s.queue[1:]
+			s.mutex.Unlock()
+			s.subscriber.OnReceive(message.seq, message.data)
+		}
 	}
 }
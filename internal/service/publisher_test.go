@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type blockingSubscriber struct {
+	release chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newBlockingSubscriber() *blockingSubscriber {
+	return &blockingSubscriber{
+		release: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (s *blockingSubscriber) OnReceive(uint64, []byte) {
+	select {
+	case <-s.release:
+	case <-s.closed:
+	}
+}
+
+func (s *blockingSubscriber) OnClose() {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+}
+
+func TestPublisherSlowSubscriberDropOldest(t *testing.T) {
+	pub := newTestPublisher(t, PublisherOptions{QueueSize: 2, OverflowPolicy: OverflowPolicyDropOldest})
+	slow := newBlockingSubscriber()
+	fast := newTestSubscriber()
+	pub.Subscribe("event_id", slow)
+	pub.Subscribe("event_id", fast)
+
+	for i := 0; i < 6; i++ {
+		pub.Publish("event_id", []byte("message"))
+		assertReceived(t, fast, "message")
+	}
+
+	metrics := pub.Metrics()
+	if metrics.DroppedMessages < 3 || metrics.DisconnectedSubscribers != 0 {
+		t.Fatalf("Incorrect metrics: %+v", metrics)
+	}
+
+	close(slow.release)
+}
+
+func TestPublisherSlowSubscriberDisconnect(t *testing.T) {
+	pub := newTestPublisher(t, PublisherOptions{QueueSize: 1, OverflowPolicy: OverflowPolicyDisconnect})
+	slow := newBlockingSubscriber()
+	pub.Subscribe("event_id", slow)
+
+	for i := 0; i < 3; i++ {
+		pub.Publish("event_id", []byte("message"))
+	}
+
+	select {
+	case <-slow.closed:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Slow subscriber was not disconnected")
+	}
+
+	metrics := pub.Metrics()
+	if metrics.DisconnectedSubscribers != 1 || metrics.DroppedMessages != 0 {
+		t.Fatalf("Incorrect metrics: %+v", metrics)
+	}
+
+	pub.Publish("event_id", []byte("message"))
+}
+
+func TestPublisherCloseDeliversQueuedMessages(t *testing.T) {
+	pub := newTestPublisher(t, PublisherOptions{})
+	sub := newTestSubscriber()
+	pub.Subscribe("event_id", sub)
+	pub.Publish("event_id", []byte("closeEvent/"))
+	pub.Close("event_id")
+	assertReceived(t, sub, "closeEvent/")
+	assertClosed(t, sub)
+}
+
+func TestPublisherResume(t *testing.T) {
+	pub := newTestPublisher(t, PublisherOptions{ReplaySize: 3})
+	defer pub.CloseAll()
+	live := newTestSequencedSubscriber()
+	pub.Subscribe("event_id", live)
+
+	for i := 1; i <= 5; i++ {
+		message := fmt.Sprintf("chatMessage/%d", i)
+		pub.Publish("event_id", []byte(message))
+		assertReceivedSeq(t, live, message, uint64(i))
+	}
+
+	resumed := newTestSequencedSubscriber()
+	pub.Resume("event_id", 3, resumed)
+	assertReceivedSeq(t, resumed, "chatMessage/4", 4)
+	assertReceivedSeq(t, resumed, "chatMessage/5", 5)
+
+	upToDate := newTestSequencedSubscriber()
+	pub.Resume("event_id", 5, upToDate)
+	pub.Publish("event_id", []byte("chatMessage/6"))
+	assertReceivedSeq(t, live, "chatMessage/6", 6)
+	assertReceivedSeq(t, resumed, "chatMessage/6", 6)
+	assertReceivedSeq(t, upToDate, "chatMessage/6", 6)
+
+	for _, lastSeq := range []uint64{0, 2, 7} {
+		sub := newTestSequencedSubscriber()
+		pub.Resume("event_id", lastSeq, sub)
+		assertReceivedSeq(t, sub, resyncMessage, 6)
+	}
+
+	pub.Close("event_id")
+	sub := newTestSequencedSubscriber()
+	pub.Resume("event_id", 6, sub)
+	assertReceivedSeq(t, sub, resyncMessage, 0)
+}
+
+func TestPublisherDropsIdleStreams(t *testing.T) {
+	pub, err := newPublisher(PublisherOptions{ReplayTTL: time.Millisecond * 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer pub.CloseAll()
+	live := newTestSequencedSubscriber()
+	pub.Subscribe("event_id", live)
+
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 1000; i++ {
+			pub.Publish(UserTopic(fmt.Sprintf("user_%d_%d", round, i)), []byte("roleChanged/{}"))
+		}
+
+		time.Sleep(time.Millisecond * 60)
+		pub.Publish("event_id", []byte(fmt.Sprintf("chatMessage/%d", round+1)))
+		assertReceivedSeq(t, live, fmt.Sprintf("chatMessage/%d", round+1), uint64(round+1))
+
+		pub.mutex.RLock()
+		streams := len(pub.streams)
+		pub.mutex.RUnlock()
+
+		if streams != 1 {
+			t.Fatalf("Incorrect streams count after round %d\nExpected: 1\nActual: %d", round, streams)
+		}
+	}
+}
+
+func TestPublisherConcurrentSubscriptions(t *testing.T) {
+	pub := newTestPublisher(t, PublisherOptions{})
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sub := newTestSubscriber()
+			pub.Subscribe("event_id", sub)
+			pub.Publish("event_id", []byte("message"))
+			pub.Unsubscribe("event_id", sub)
+		}()
+	}
+
+	wg.Wait()
+	pub.CloseAll()
+}
+
+func TestPublisherUnknownOverflowPolicy(t *testing.T) {
+	if _, err := newPublisher(PublisherOptions{OverflowPolicy: "unknown"}); !errors.Is(err, ErrUnknownOverflowPolicy) {
+		t.Fatalf("Expected error: %v\nActual error: %v", ErrUnknownOverflowPolicy, err)
+	}
+}
+
+func assertReceivedSeq(t *testing.T, subscriber *testSubscriber, expected string, expectedSeq uint64) {
+	t.Helper()
+	assertReceived(t, subscriber, expected)
+
+	if seq := <-subscriber.seqs; seq != expectedSeq {
+		t.Fatalf("Incorrect sequence of %s\nExpected: %d\nActual: %d", expected, expectedSeq, seq)
+	}
+}
+
+func newTestSequencedSubscriber() *testSubscriber {
+	subscriber := newTestSubscriber()
+	subscriber.seqs = make(chan uint64, 10)
+	return subscriber
+}
+
+func newTestPublisher(t *testing.T, options PublisherOptions) Publisher {
+	t.Helper()
+	pub, err := newPublisher(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pub
+}
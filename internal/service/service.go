@@ -2,27 +2,40 @@ package service
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/broker"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/hash"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/sms"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/storage"
 )
 
+// CreateMediaInput ContentType is only the declared type, the stored one is detected from Data,
+// which must be one of AllowedTypes. OwnerID is the uploading user, empty for anonymous uploads.
 type CreateMediaInput struct {
-	Name        string
-	ContentType string
-	Size        int64
-	Data        []byte
+	Name         string
+	ContentType  string
+	OwnerID      string
+	Size         int64
+	Data         io.Reader
+	AllowedTypes []string
 }
 
 type Media interface {
 	Create(ctx context.Context, input CreateMediaInput) (string, error)
 	Update(ctx context.Context, mediaId string, input CreateMediaInput) error
 	GetMetadata(ctx context.Context, id string) (domain.Media, error)
-	GetFile(ctx context.Context, id string) (domain.FileData, error)
+	// MaxSize is the largest upload allowed for any of the content types.
+	MaxSize(contentTypes []string) int64
+	// GetFile opens the smallest variant of the image not smaller than size or the original when size is 0.
+	GetFile(ctx context.Context, id string, size int) (domain.FileData, error)
+	// GetFileURL returns a temporary direct link to the file or an empty string when files are served through the API.
+	GetFileURL(ctx context.Context, id string, size int) (string, error)
 	Delete(ctx context.Context, id string) error
 }
 
@@ -30,30 +43,41 @@ type CreateUserInput struct {
 	Name     string
 	Phone    string
 	Password string
+	Device   domain.DeviceInfo
 }
 
 type LoginUserInput struct {
 	Phone    string
 	Password string
+	Device   domain.DeviceInfo
 }
 
 type ChangePasswordInput struct {
-	ID       string
-	Password string
+	ID        string
+	SessionID string
+	Password  string
 }
 
 type UpdateUserInput struct {
-	ID    string
-	Name  string
-	Phone string
+	ID        string
+	SessionID string
+	Name      string
+	Phone     string
+}
+
+type ResetPasswordInput struct {
+	Phone    string
+	Code     string
+	Password string
 }
 
 type SetUserImageInput struct {
 	UserID      string
+	SessionID   string
 	FileName    string
 	ContentType string
 	Size        int64
-	FileData    []byte
+	FileData    io.Reader
 }
 
 type Users interface {
@@ -62,6 +86,24 @@ type Users interface {
 	Update(ctx context.Context, input UpdateUserInput) (string, error)
 	ChangePassword(ctx context.Context, input ChangePasswordInput) (string, error)
 	SetUserImage(ctx context.Context, input SetUserImageInput) (imageId string, accessToken string, err error)
+	RequestPasswordReset(ctx context.Context, phone string) error
+	ResetPassword(ctx context.Context, input ResetPasswordInput) error
+}
+
+type CreateSessionInput struct {
+	UserID      string
+	UserName    string
+	UserImageID string
+	Device      domain.DeviceInfo
+}
+
+type Sessions interface {
+	Create(ctx context.Context, input CreateSessionInput) (domain.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (domain.TokenPair, error)
+	Check(ctx context.Context, userId string, sessionId string) error
+	GetUserSessions(ctx context.Context, userId string, currentSessionId string) ([]domain.SessionInfo, error)
+	Revoke(ctx context.Context, userId string, sessionId string) error
+	RevokeAll(ctx context.Context, userId string) error
 }
 
 type CreateEventInput struct {
@@ -69,6 +111,15 @@ type CreateEventInput struct {
 	Name        string
 	Address     string
 	Coordinates domain.Coordinates
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	Visibility  domain.EventVisibility
+}
+
+type EventAccessInput struct {
+	EventID     string
+	UserID      string
+	InviteToken string
 }
 
 type GetByRangeInput struct {
@@ -76,17 +127,94 @@ type GetByRangeInput struct {
 	VerticalRange   float64
 	Coordinates     domain.Coordinates
 }
+
+type GetClustersInput struct {
+	HorizontalRange float64
+	VerticalRange   float64
+	Coordinates     domain.Coordinates
+	Zoom            int
+}
+
+type GetNearbyInput struct {
+	Coordinates domain.Coordinates
+	Distance    float64
+}
+
 type AddUserInfoInput struct {
 	EventID string
 	UserID  string
 }
 
 type ChatMessageInput struct {
+	EventID       string
+	UserID        string
+	UserName      string
+	UserImageID   string
+	Message       string
+	ReplyToID     string
+	AttachmentIDs []string
+}
+
+type EditChatMessageInput struct {
+	EventID   string
+	UserID    string
+	MessageID string
+	Message   string
+}
+
+type ChatReactionInput struct {
+	EventID   string
+	UserID    string
+	MessageID string
+	Emoji     string
+}
+
+type ChatHistoryInput struct {
+	EventID     string
+	UserID      string
+	InviteToken string
+	BeforeID    string
+	AfterID     string
+	Limit       int
+}
+
+type JoinRequestInput struct {
 	EventID     string
 	UserID      string
 	UserName    string
 	UserImageID string
-	Message     string
+}
+
+type EventRoleInput struct {
+	EventID      string
+	UserID       string
+	TargetUserID string
+	Role         domain.EventRole
+}
+
+type DeleteChatMessageInput struct {
+	EventID   string
+	UserID    string
+	MessageID string
+}
+
+type MuteUserInput struct {
+	EventID      string
+	UserID       string
+	TargetUserID string
+	Duration     time.Duration
+}
+
+type ModerateUserInput struct {
+	EventID      string
+	UserID       string
+	TargetUserID string
+}
+
+type ReviewJoinRequestInput struct {
+	EventID     string
+	UserID      string
+	RequesterID string
 }
 
 type AddMediaInput struct {
@@ -95,7 +223,7 @@ type AddMediaInput struct {
 	FileName    string
 	ContentType string
 	FileSize    int64
-	FileData    []byte
+	FileData    io.Reader
 }
 
 type UpdateEventInput struct {
@@ -115,32 +243,63 @@ type Events interface {
 	Create(ctx context.Context, input CreateEventInput) (domain.EventInfo, error)
 	Close(ctx context.Context, eventId string, userId string) error
 	Update(ctx context.Context, input UpdateEventInput) error
-	GetByID(ctx context.Context, id string) (domain.EventInfo, error)
+	GetByID(ctx context.Context, input EventAccessInput) (domain.EventInfo, error)
+	CheckAccess(ctx context.Context, input EventAccessInput) error
 	GetByRange(ctx context.Context, input GetByRangeInput) ([]domain.EventRangeData, error)
+	GetNearby(ctx context.Context, input GetNearbyInput) ([]domain.EventRangeData, error)
+	GetClusters(ctx context.Context, input GetClustersInput) (domain.EventClusters, error)
+	GetChatHistory(ctx context.Context, input ChatHistoryInput) (domain.ChatHistory, error)
 	AddUserInfo(ctx context.Context, input AddUserInfoInput) error
 	RemoveUserInfo(ctx context.Context, eventId string, userId string) error
 	SendChatMessage(ctx context.Context, input ChatMessageInput) error
+	EditChatMessage(ctx context.Context, input EditChatMessageInput) error
+	ToggleChatReaction(ctx context.Context, input ChatReactionInput) error
 	AddMedia(ctx context.Context, input AddMediaInput) error
 	RemoveMedia(ctx context.Context, input RemoveMediaInput) error
+	CreateInvite(ctx context.Context, eventId string, userId string) (string, error)
+	RequestJoin(ctx context.Context, input JoinRequestInput) error
+	GetJoinRequests(ctx context.Context, eventId string, userId string) ([]domain.JoinRequest, error)
+	ApproveJoinRequest(ctx context.Context, input ReviewJoinRequestInput) error
+	RejectJoinRequest(ctx context.Context, input ReviewJoinRequestInput) error
+	GrantRole(ctx context.Context, input EventRoleInput) error
+	RevokeRole(ctx context.Context, input EventRoleInput) error
+	DeleteChatMessage(ctx context.Context, input DeleteChatMessageInput) error
+	MuteUser(ctx context.Context, input MuteUserInput) error
+	KickUser(ctx context.Context, input ModerateUserInput) error
+	BanUser(ctx context.Context, input ModerateUserInput) error
+	OpenScheduled(ctx context.Context, now time.Time) error
+	CloseExpired(ctx context.Context, now time.Time, inactivityTimeout time.Duration) error
+}
+
+type Presence interface {
+	Join(ctx context.Context, eventId string, user domain.PresenceUser) error
+	Leave(eventId string, userId string) error
+	GetUsers(eventId string) ([]domain.PresenceUser, error)
+	Typing(eventId string, userId string) error
+	Close()
 }
 
 type Subscriber interface {
-	OnReceive(message []byte)
+	OnReceive(seq uint64, message []byte)
 	OnClose()
 }
 
 type Publisher interface {
 	Subscribe(eventId string, subscriber Subscriber)
+	Resume(eventId string, lastSeq uint64, subscriber Subscriber)
 	Unsubscribe(eventId string, subscriber Subscriber)
 	Publish(eventId string, message []byte)
 	Close(eventId string)
 	CloseAll()
+	Metrics() PublisherMetrics
 }
 
 type Services struct {
 	Media     Media
 	Users     Users
+	Sessions  Sessions
 	Events    Events
+	Presence  Presence
 	Publisher Publisher
 }
 
@@ -149,14 +308,28 @@ func NewServices(
 	repositories *repository.Repositories,
 	hashManager hash.PasswordHashManager,
 	auth auth.TokenManager,
-	storage storage.FileStorage) (*Services, error) {
-	media := newMediaService(repositories.Media, storage)
-	pub := newPublisher()
+	storage storage.FileStorage,
+	smsSender sms.SMSSender,
+	refreshTokenTTL time.Duration,
+	messageBroker broker.Broker,
+	publisherOptions PublisherOptions,
+	mediaOptions MediaOptions,
+	reconnectGracePeriod time.Duration) (*Services, error) {
+	media := newMediaService(repositories.Media, storage, mediaOptions)
+	sessions := newSessionService(repositories.Sessions, repositories.Users, auth, refreshTokenTTL)
+	pub, err := newBrokerPublisher(logger, messageBroker, publisherOptions)
+
+	if err != nil {
+		return nil, err
+	}
 
+	events := NewEventService(logger, repositories.Events, pub, media, auth)
 	return &Services{
 		Media:     media,
-		Users:     newUserService(repositories.Users, repositories.Events, hashManager, auth, media),
-		Events:    NewEventService(logger, repositories.Events, pub, media),
+		Users:     newUserService(logger, repositories.Users, repositories.Events, repositories.OtpCodes, hashManager, auth, media, sessions, smsSender),
+		Sessions:  sessions,
+		Events:    events,
+		Presence:  newPresenceService(logger, pub, messageBroker.Presence(), events, defaultTypingInterval, reconnectGracePeriod),
 		Publisher: pub,
 	}, nil
 }
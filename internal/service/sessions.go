@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
+)
+
+type sessionService struct {
+	repository      repository.Sessions
+	usersRepository repository.Users
+	auth            auth.TokenManager
+	refreshTokenTTL time.Duration
+}
+
+func newSessionService(
+	repository repository.Sessions,
+	usersRepository repository.Users,
+	auth auth.TokenManager,
+	refreshTokenTTL time.Duration) Sessions {
+	return &sessionService{
+		repository:      repository,
+		usersRepository: usersRepository,
+		auth:            auth,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+func (s *sessionService) Create(ctx context.Context, input CreateSessionInput) (domain.TokenPair, error) {
+	refreshToken, err := s.auth.GetRefreshToken()
+
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	now := time.Now()
+	sessionId, err := s.repository.CreateSession(ctx, domain.Session{
+		UserID:       input.UserID,
+		RefreshToken: hashRefreshToken(refreshToken),
+		Device:       input.Device,
+		CreatedAt:    now,
+		LastUsedAt:   now,
+		ExpiresAt:    now.Add(s.refreshTokenTTL),
+	})
+
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	accessToken, err := s.auth.GetAccessToken(auth.TokenData{
+		ID:        input.UserID,
+		Name:      input.UserName,
+		ImageID:   input.UserImageID,
+		SessionID: sessionId,
+	})
+
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	return domain.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *sessionService) Refresh(ctx context.Context, refreshToken string) (domain.TokenPair, error) {
+	hashedToken := hashRefreshToken(refreshToken)
+	session, err := s.repository.GetSessionByRefreshToken(ctx, hashedToken)
+
+	if err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			return domain.TokenPair{}, domain.ErrInvalidRefreshToken
+		}
+		return domain.TokenPair{}, err
+	}
+
+	now := time.Now()
+
+	if !now.Before(session.ExpiresAt) {
+		if err = s.repository.RemoveSession(ctx, session.ID); err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+			return domain.TokenPair{}, err
+		}
+		return domain.TokenPair{}, domain.ErrInvalidRefreshToken
+	}
+
+	user, err := s.usersRepository.GetUserByID(ctx, session.UserID)
+
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	newRefreshToken, err := s.auth.GetRefreshToken()
+
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	err = s.repository.UpdateRefreshToken(ctx, session.ID, hashedToken, hashRefreshToken(newRefreshToken), now, now.Add(s.refreshTokenTTL))
+
+	if err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			return domain.TokenPair{}, domain.ErrInvalidRefreshToken
+		}
+		return domain.TokenPair{}, err
+	}
+
+	accessToken, err := s.auth.GetAccessToken(auth.TokenData{
+		ID:        user.ID,
+		Name:      user.Name,
+		ImageID:   user.ImageID,
+		SessionID: session.ID,
+	})
+
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+
+	return domain.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+	}, nil
+}
+
+func (s *sessionService) Check(ctx context.Context, userId string, sessionId string) error {
+	if sessionId == "" {
+		return domain.ErrSessionNotFound
+	}
+
+	session, err := s.repository.GetSessionByID(ctx, sessionId)
+
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != userId || !time.Now().Before(session.ExpiresAt) {
+		return domain.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (s *sessionService) GetUserSessions(ctx context.Context, userId string, currentSessionId string) ([]domain.SessionInfo, error) {
+	sessions, err := s.repository.GetUserSessions(ctx, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]domain.SessionInfo, 0, len(sessions))
+
+	for _, session := range sessions {
+		if !now.Before(session.ExpiresAt) {
+			continue
+		}
+
+		result = append(result, domain.SessionInfo{
+			ID:         session.ID,
+			Device:     session.Device,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			IsCurrent:  session.ID == currentSessionId,
+		})
+	}
+
+	return result, nil
+}
+
+func (s *sessionService) Revoke(ctx context.Context, userId string, sessionId string) error {
+	session, err := s.repository.GetSessionByID(ctx, sessionId)
+
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != userId {
+		return domain.ErrSessionNotFound
+	}
+
+	return s.repository.RemoveSession(ctx, sessionId)
+}
+
+func (s *sessionService) RevokeAll(ctx context.Context, userId string) error {
+	return s.repository.RemoveUserSessions(ctx, userId)
+}
+
+func hashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
@@ -2,34 +2,71 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
 
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
 	"github.com/iamsorryprincess/vpiska-backend-go/pkg/hash"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/sms"
 )
 
+const (
+	otpCodeLength      = 6
+	otpCodeTTL         = time.Minute * 10
+	otpRequestInterval = time.Minute
+	otpRequestsWindow  = time.Hour
+	otpRequestsLimit   = 5
+	otpAttemptsLimit   = 5
+)
+
+var otpRequestsLimits = domain.OtpRequestsLimit{
+	Interval: otpRequestInterval,
+	Window:   otpRequestsWindow,
+	Count:    otpRequestsLimit,
+}
+
 type userService struct {
+	logger          logger.Logger
 	repository      repository.Users
 	eventRepository repository.Events
+	otpCodes        repository.OtpCodes
 	hashManager     hash.PasswordHashManager
 	auth            auth.TokenManager
 	fileStorage     Media
+	sessions        Sessions
+	smsSender       sms.SMSSender
 }
 
 func newUserService(
+	logger logger.Logger,
 	repository repository.Users,
 	eventRepository repository.Events,
+	otpCodes repository.OtpCodes,
 	hashManager hash.PasswordHashManager,
 	auth auth.TokenManager,
-	fileStorage Media) Users {
+	fileStorage Media,
+	sessions Sessions,
+	smsSender sms.SMSSender) Users {
 	return &userService{
+		logger:          logger,
 		repository:      repository,
 		eventRepository: eventRepository,
+		otpCodes:        otpCodes,
 		hashManager:     hashManager,
 		auth:            auth,
 		fileStorage:     fileStorage,
+		sessions:        sessions,
+		smsSender:       smsSender,
 	}
 }
 
@@ -77,7 +114,7 @@ func (s *userService) Create(ctx context.Context, input CreateUserInput) (domain
 		return domain.UserLogin{}, err
 	}
 
-	return s.createLogin(userId, model.Name, model.Phone, model.ImageID, "")
+	return s.createLogin(ctx, userId, model.Name, model.Phone, model.ImageID, "", input.Device)
 }
 
 func (s *userService) Login(ctx context.Context, input LoginUserInput) (domain.UserLogin, error) {
@@ -97,13 +134,17 @@ func (s *userService) Login(ctx context.Context, input LoginUserInput) (domain.U
 		return domain.UserLogin{}, domain.ErrInvalidPassword
 	}
 
+	if s.hashManager.NeedsRehash(model.Password) {
+		s.rehashPassword(ctx, model.ID, input.Password)
+	}
+
 	event, err := s.eventRepository.GetEventByOwnerId(ctx, model.ID)
 
 	if err != nil && !errors.Is(err, domain.ErrEventNotFound) {
 		return domain.UserLogin{}, err
 	}
 
-	return s.createLogin(model.ID, model.Name, model.Phone, model.ImageID, event.ID)
+	return s.createLogin(ctx, model.ID, model.Name, model.Phone, model.ImageID, event.ID, input.Device)
 }
 
 func (s *userService) Update(ctx context.Context, input UpdateUserInput) (string, error) {
@@ -115,21 +156,22 @@ func (s *userService) Update(ctx context.Context, input UpdateUserInput) (string
 
 	if input.Name == "" && input.Phone == "" {
 		return s.auth.GetAccessToken(auth.TokenData{
-			ID:      model.ID,
-			Name:    model.Name,
-			ImageID: model.ImageID,
+			ID:        model.ID,
+			Name:      model.Name,
+			ImageID:   model.ImageID,
+			SessionID: input.SessionID,
 		})
 	}
 
 	if input.Name != "" && input.Phone != "" {
-		return s.updateNameAndPhone(ctx, input.ID, input.Name, input.Phone, model.ImageID)
+		return s.updateNameAndPhone(ctx, input.ID, input.SessionID, input.Name, input.Phone, model.ImageID)
 	}
 
 	if input.Name != "" {
-		return s.updateName(ctx, input.ID, input.Name, model.ImageID)
+		return s.updateName(ctx, input.ID, input.SessionID, input.Name, model.ImageID)
 	}
 
-	return s.updatePhone(ctx, input.ID, model.Name, input.Phone, model.ImageID)
+	return s.updatePhone(ctx, input.ID, input.SessionID, model.Name, input.Phone, model.ImageID)
 }
 
 func (s *userService) ChangePassword(ctx context.Context, input ChangePasswordInput) (string, error) {
@@ -139,7 +181,7 @@ func (s *userService) ChangePassword(ctx context.Context, input ChangePasswordIn
 		return "", err
 	}
 
-	hashedPassword, err := s.hashManager.HashPassword(model.Password)
+	hashedPassword, err := s.hashManager.HashPassword(input.Password)
 
 	if err != nil {
 		return "", err
@@ -150,9 +192,10 @@ func (s *userService) ChangePassword(ctx context.Context, input ChangePasswordIn
 	}
 
 	return s.auth.GetAccessToken(auth.TokenData{
-		ID:      model.ID,
-		Name:    model.Name,
-		ImageID: model.ImageID,
+		ID:        model.ID,
+		Name:      model.Name,
+		ImageID:   model.ImageID,
+		SessionID: input.SessionID,
 	})
 }
 
@@ -165,10 +208,12 @@ func (s *userService) SetUserImage(ctx context.Context, input SetUserImageInput)
 
 	if user.ImageID == "" {
 		imageId, err = s.fileStorage.Create(ctx, CreateMediaInput{
-			Name:        input.FileName,
-			ContentType: input.ContentType,
-			Size:        input.Size,
-			Data:        input.FileData,
+			Name:         input.FileName,
+			ContentType:  input.ContentType,
+			OwnerID:      input.UserID,
+			Size:         input.Size,
+			Data:         input.FileData,
+			AllowedTypes: ImageContentTypes,
 		})
 
 		if err != nil {
@@ -182,9 +227,10 @@ func (s *userService) SetUserImage(ctx context.Context, input SetUserImageInput)
 		}
 
 		accessToken, err = s.auth.GetAccessToken(auth.TokenData{
-			ID:      input.UserID,
-			Name:    user.Name,
-			ImageID: imageId,
+			ID:        input.UserID,
+			Name:      user.Name,
+			ImageID:   imageId,
+			SessionID: input.SessionID,
 		})
 
 		if err != nil {
@@ -195,10 +241,11 @@ func (s *userService) SetUserImage(ctx context.Context, input SetUserImageInput)
 	}
 
 	err = s.fileStorage.Update(ctx, user.ImageID, CreateMediaInput{
-		Name:        input.FileName,
-		ContentType: input.ContentType,
-		Size:        input.Size,
-		Data:        input.FileData,
+		Name:         input.FileName,
+		ContentType:  input.ContentType,
+		Size:         input.Size,
+		Data:         input.FileData,
+		AllowedTypes: ImageContentTypes,
 	})
 
 	if err != nil {
@@ -206,9 +253,10 @@ func (s *userService) SetUserImage(ctx context.Context, input SetUserImageInput)
 	}
 
 	accessToken, err = s.auth.GetAccessToken(auth.TokenData{
-		ID:      input.UserID,
-		Name:    user.Name,
-		ImageID: imageId,
+		ID:        input.UserID,
+		Name:      user.Name,
+		ImageID:   user.ImageID,
+		SessionID: input.SessionID,
 	})
 
 	if err != nil {
@@ -218,11 +266,114 @@ func (s *userService) SetUserImage(ctx context.Context, input SetUserImageInput)
 	return user.ImageID, accessToken, nil
 }
 
-func (s *userService) createLogin(id string, name string, phone string, imageId string, eventId string) (domain.UserLogin, error) {
-	token, err := s.auth.GetAccessToken(auth.TokenData{
-		ID:      id,
-		Name:    name,
-		ImageID: imageId,
+func (s *userService) RequestPasswordReset(ctx context.Context, phone string) error {
+	now := time.Now()
+
+	// unknown phones are limited and get a code the same way, so the responses don't tell which phones are registered
+	if err := s.otpCodes.AddOtpRequest(ctx, phone, now, otpRequestsLimits); err != nil {
+		return err
+	}
+
+	user, err := s.repository.GetUserByPhone(ctx, phone)
+
+	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+
+	code, err := generateOtpCode()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.otpCodes.CreateOtpCode(ctx, domain.OtpCode{
+		Phone:     phone,
+		Code:      hashOtpCode(phone, code),
+		CreatedAt: now,
+		ExpiresAt: now.Add(otpCodeTTL),
+	})
+
+	if err != nil || user.ID == "" {
+		return err
+	}
+
+	return s.smsSender.Send(ctx, user.PhoneCode+user.Phone, "Код для восстановления пароля: "+code)
+}
+
+func (s *userService) ResetPassword(ctx context.Context, input ResetPasswordInput) error {
+	otpCode, err := s.otpCodes.GetLastOtpCode(ctx, input.Phone)
+
+	if err != nil {
+		if errors.Is(err, domain.ErrOtpCodeNotFound) {
+			return domain.ErrOtpCodeInvalid
+		}
+		return err
+	}
+
+	if !time.Now().Before(otpCode.ExpiresAt) {
+		return domain.ErrOtpCodeExpired
+	}
+
+	// the attempt is taken before the comparison, so parallel guesses can't use more attempts than the limit
+	if err = s.otpCodes.IncrementOtpAttempts(ctx, otpCode.ID, otpAttemptsLimit); err != nil {
+		if errors.Is(err, domain.ErrOtpCodeNotFound) {
+			return domain.ErrOtpCodeInvalid
+		}
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(otpCode.Code), []byte(hashOtpCode(input.Phone, input.Code))) != 1 {
+		return domain.ErrOtpCodeInvalid
+	}
+
+	if err = s.otpCodes.RemoveOtpCode(ctx, otpCode.ID); err != nil {
+		if errors.Is(err, domain.ErrOtpCodeNotFound) {
+			return domain.ErrOtpCodeInvalid
+		}
+		return err
+	}
+
+	user, err := s.repository.GetUserByPhone(ctx, input.Phone)
+
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return domain.ErrOtpCodeInvalid
+		}
+		return err
+	}
+
+	hashedPassword, err := s.hashManager.HashPassword(input.Password)
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.repository.ChangePassword(ctx, user.ID, hashedPassword); err != nil {
+		return err
+	}
+
+	return s.sessions.RevokeAll(ctx, user.ID)
+}
+
+func (s *userService) rehashPassword(ctx context.Context, userId string, password string) {
+	hashedPassword, err := s.hashManager.HashPassword(password)
+
+	if err != nil {
+		s.logger.LogError(err)
+		return
+	}
+
+	if err = s.repository.ChangePassword(ctx, userId, hashedPassword); err != nil {
+		s.logger.LogError(err)
+	}
+}
+
+func (s *userService) createLogin(ctx context.Context, id string, name string, phone string, imageId string, eventId string, device domain.DeviceInfo) (domain.UserLogin, error) {
+	tokens, err := s.sessions.Create(ctx, CreateSessionInput{
+		UserID:      id,
+		UserName:    name,
+		UserImageID: imageId,
+		Device:      device,
 	})
 
 	if err != nil {
@@ -230,10 +381,11 @@ func (s *userService) createLogin(id string, name string, phone string, imageId
 	}
 
 	result := domain.UserLogin{
-		ID:          id,
-		Name:        name,
-		Phone:       phone,
-		AccessToken: token,
+		ID:           id,
+		Name:         name,
+		Phone:        phone,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}
 
 	if imageId != "" {
@@ -247,7 +399,7 @@ func (s *userService) createLogin(id string, name string, phone string, imageId
 	return result, nil
 }
 
-func (s *userService) updateNameAndPhone(ctx context.Context, userId string, name string, phone string, imageId string) (string, error) {
+func (s *userService) updateNameAndPhone(ctx context.Context, userId string, sessionId string, name string, phone string, imageId string) (string, error) {
 	namesCount, err := s.repository.GetNamesCount(ctx, name)
 
 	if err != nil {
@@ -277,13 +429,14 @@ func (s *userService) updateNameAndPhone(ctx context.Context, userId string, nam
 	}
 
 	return s.auth.GetAccessToken(auth.TokenData{
-		ID:      userId,
-		Name:    name,
-		ImageID: imageId,
+		ID:        userId,
+		Name:      name,
+		ImageID:   imageId,
+		SessionID: sessionId,
 	})
 }
 
-func (s *userService) updateName(ctx context.Context, userId string, name string, imageId string) (string, error) {
+func (s *userService) updateName(ctx context.Context, userId string, sessionId string, name string, imageId string) (string, error) {
 	namesCount, err := s.repository.GetNamesCount(ctx, name)
 
 	if err != nil {
@@ -299,13 +452,14 @@ func (s *userService) updateName(ctx context.Context, userId string, name string
 	}
 
 	return s.auth.GetAccessToken(auth.TokenData{
-		ID:      userId,
-		Name:    name,
-		ImageID: imageId,
+		ID:        userId,
+		Name:      name,
+		ImageID:   imageId,
+		SessionID: sessionId,
 	})
 }
 
-func (s *userService) updatePhone(ctx context.Context, userId string, name string, phone string, imageId string) (string, error) {
+func (s *userService) updatePhone(ctx context.Context, userId string, sessionId string, name string, phone string, imageId string) (string, error) {
 	phonesCount, err := s.repository.GetPhonesCount(ctx, phone)
 
 	if err != nil {
@@ -321,8 +475,25 @@ func (s *userService) updatePhone(ctx context.Context, userId string, name strin
 	}
 
 	return s.auth.GetAccessToken(auth.TokenData{
-		ID:      userId,
-		Name:    name,
-		ImageID: imageId,
+		ID:        userId,
+		Name:      name,
+		ImageID:   imageId,
+		SessionID: sessionId,
 	})
 }
+
+func generateOtpCode() (string, error) {
+	max := big.NewInt(int64(math.Pow10(otpCodeLength)))
+	value, err := rand.Int(rand.Reader, max)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", otpCodeLength, value.Int64()), nil
+}
+
+func hashOtpCode(phone string, code string) string {
+	hash := sha256.Sum256([]byte(phone + ":" + code))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/repository"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/auth"
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/hash"
+)
+
+func TestLoginRehashesLegacyPassword(t *testing.T) {
+	ctx := context.Background()
+	repositories, _, err := repository.NewRepositories(repository.DriverMemory, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := hash.NewHMACPasswordHashManager("fbac497e4b44564f831f78d539b81a0c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyPassword, err := legacy.HashPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userId, err := repositories.Users.CreateUser(ctx, domain.User{Name: "legacy", Phone: "1111111111", Password: legacyPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenManager := auth.NewJwtManager("key", "issuer", "audience", time.Minute)
+	hashManager := hash.NewArgon2idPasswordHashManager(hash.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}, legacy)
+	sessions := newSessionService(repositories.Sessions, repositories.Users, tokenManager, time.Hour)
+	users := newUserService(testLogger{t: t}, repositories.Users, repositories.Events, repositories.OtpCodes, hashManager, tokenManager, nil, sessions, nil)
+
+	if _, err = users.Login(ctx, LoginUserInput{Phone: "1111111111", Password: "wrong_password"}); err != domain.ErrInvalidPassword {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrInvalidPassword, err)
+	}
+
+	assertStoredPassword(t, repositories.Users, userId, legacyPassword)
+
+	if _, err = users.Login(ctx, LoginUserInput{Phone: "1111111111", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := repositories.Users.GetUserByID(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(user.Password, "$argon2id$") {
+		t.Fatalf("Password was not rehashed: %s", user.Password)
+	}
+
+	if _, err = users.Login(ctx, LoginUserInput{Phone: "1111111111", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertStoredPassword(t, repositories.Users, userId, user.Password)
+}
+
+func assertStoredPassword(t *testing.T, users repository.Users, userId string, expected string) {
+	t.Helper()
+	user, err := users.GetUserByID(context.Background(), userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.Password != expected {
+		t.Fatalf("Incorrect stored password\nExpected: %s\nActual: %s", expected, user.Password)
+	}
+}
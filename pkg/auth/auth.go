@@ -1,16 +1,23 @@
 package auth
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var ErrInvalidToken = errors.New("unauthorized")
 
 type TokenData struct {
-	ID      string
-	Name    string
-	ImageID string
+	ID        string
+	Name      string
+	ImageID   string
+	SessionID string
 }
 
 type TokenManager interface {
 	GetAccessToken(input TokenData) (string, error)
 	ParseToken(token string) (TokenData, error)
+	GetRefreshToken() (string, error)
+	GetInviteToken(eventId string, ttl time.Duration) (string, error)
+	ParseInviteToken(token string) (string, error)
 }
@@ -1,11 +1,17 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 )
 
+const refreshTokenSize = 32
+
+const inviteTokenSubject = "invite"
+
 type jwtManager struct {
 	key      string
 	issuer   string
@@ -24,9 +30,15 @@ func NewJwtManager(key string, issuer string, audience string, ttl time.Duration
 
 type userClaims struct {
 	jwt.StandardClaims
-	ID      string
-	Name    string
-	ImageID string
+	ID        string
+	Name      string
+	ImageID   string
+	SessionID string
+}
+
+type inviteClaims struct {
+	jwt.StandardClaims
+	EventID string
 }
 
 func (m *jwtManager) GetAccessToken(input TokenData) (string, error) {
@@ -41,6 +53,7 @@ func (m *jwtManager) GetAccessToken(input TokenData) (string, error) {
 		ID:             input.ID,
 		Name:           input.Name,
 		ImageID:        input.ImageID,
+		SessionID:      input.SessionID,
 	})
 
 	return token.SignedString([]byte(m.key))
@@ -61,13 +74,60 @@ func (m *jwtManager) ParseToken(token string) (TokenData, error) {
 
 	claims, ok := data.Claims.(*userClaims)
 
-	if !ok || !data.Valid {
+	if !ok || !data.Valid || claims.Subject == inviteTokenSubject {
 		return TokenData{}, ErrInvalidToken
 	}
 
 	return TokenData{
-		ID:      claims.ID,
-		Name:    claims.Name,
-		ImageID: claims.ImageID,
+		ID:        claims.ID,
+		Name:      claims.Name,
+		ImageID:   claims.ImageID,
+		SessionID: claims.SessionID,
 	}, nil
 }
+
+func (m *jwtManager) GetInviteToken(eventId string, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, inviteClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    m.issuer,
+			Audience:  m.audience,
+			Subject:   inviteTokenSubject,
+			ExpiresAt: time.Now().Add(ttl).Unix(),
+		},
+		EventID: eventId,
+	})
+
+	return token.SignedString([]byte(m.key))
+}
+
+func (m *jwtManager) ParseInviteToken(token string) (string, error) {
+	data, err := jwt.ParseWithClaims(token, &inviteClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+
+		return []byte(m.key), nil
+	})
+
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	claims, ok := data.Claims.(*inviteClaims)
+
+	if !ok || !data.Valid || claims.Subject != inviteTokenSubject || claims.EventID == "" {
+		return "", ErrInvalidToken
+	}
+
+	return claims.EventID, nil
+}
+
+func (m *jwtManager) GetRefreshToken() (string, error) {
+	data := make([]byte, refreshTokenSize)
+
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
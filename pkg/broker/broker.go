@@ -0,0 +1,51 @@
+package broker
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	DriverMemory = "memory"
+	DriverRedis  = "redis"
+)
+
+// streamTTL bounds how long the number of an idle stream is kept, a stream published again later starts from one.
+const streamTTL = time.Hour * 24
+
+var ErrUnknownDriver = errors.New("unknown broker driver")
+
+type Broker interface {
+	// Publish numbers the message within the stream and sends it to all the listeners. The numbers of a stream
+	// grow by one whichever instance publishes and the listeners receive the messages of a stream in their order.
+	Publish(stream string, message []byte) error
+	Listen(handler func(seq uint64, message []byte)) error
+	// Seq returns the number of the last message published to the stream.
+	Seq(stream string) (uint64, error)
+	// Presence is shared by all the instances connected to the same broker.
+	Presence() Presence
+	Close() error
+}
+
+// Presence counts the connections of the room members across the instances.
+type Presence interface {
+	// Connect counts one more connection, added is true only if the member was not present yet.
+	Connect(room string, member string, data []byte) (added bool, err error)
+	// Disconnect counts one connection less and returns the connections left on all the instances.
+	Disconnect(room string, member string) (int64, error)
+	// Remove deletes the member without connections, false means the member is connected again or already removed.
+	Remove(room string, member string) (bool, error)
+	// Members returns the data of the present members in the order they were added.
+	Members(room string) ([][]byte, error)
+}
+
+func NewBroker(driver string, connectionString string, channel string) (Broker, error) {
+	switch driver {
+	case DriverMemory:
+		return NewMemoryBroker(), nil
+	case DriverRedis:
+		return NewRedisBroker(connectionString, channel)
+	default:
+		return nil, ErrUnknownDriver
+	}
+}
@@ -0,0 +1,87 @@
+package broker
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryStream struct {
+	seq         uint64
+	publishedAt time.Time
+}
+
+type memoryBroker struct {
+	mutex    sync.RWMutex
+	handlers []func(seq uint64, message []byte)
+	// publishMutex keeps the messages of a stream in the order of their numbers
+	publishMutex sync.Mutex
+	streams      map[string]memoryStream
+	sweptAt      time.Time
+	presence     *memoryPresence
+}
+
+func NewMemoryBroker() Broker {
+	return &memoryBroker{
+		mutex:    sync.RWMutex{},
+		streams:  make(map[string]memoryStream),
+		sweptAt:  time.Now(),
+		presence: newMemoryPresence(),
+	}
+}
+
+func (b *memoryBroker) Publish(stream string, message []byte) error {
+	b.publishMutex.Lock()
+	defer b.publishMutex.Unlock()
+	now := time.Now()
+	b.sweep(now)
+	seq := b.streams[stream].seq + 1
+	b.streams[stream] = memoryStream{seq: seq, publishedAt: now}
+	b.mutex.RLock()
+	handlers := b.handlers
+	b.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(seq, message)
+	}
+
+	return nil
+}
+
+func (b *memoryBroker) Listen(handler func(seq uint64, message []byte)) error {
+	b.mutex.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mutex.Unlock()
+	return nil
+}
+
+func (b *memoryBroker) Seq(stream string) (uint64, error) {
+	b.publishMutex.Lock()
+	defer b.publishMutex.Unlock()
+	return b.streams[stream].seq, nil
+}
+
+// sweep forgets the streams idle for the stream ttl like redis expires their keys, it runs at most once per the ttl.
+func (b *memoryBroker) sweep(now time.Time) {
+	if now.Sub(b.sweptAt) < streamTTL {
+		return
+	}
+
+	b.sweptAt = now
+
+	for stream, state := range b.streams {
+		if now.Sub(state.publishedAt) >= streamTTL {
+			delete(b.streams, stream)
+		}
+	}
+}
+
+func (b *memoryBroker) Presence() Presence {
+	return b.presence
+}
+
+func (b *memoryBroker) Close() error {
+	b.mutex.Lock()
+	b.handlers = nil
+	b.mutex.Unlock()
+	return nil
+}
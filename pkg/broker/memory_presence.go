@@ -0,0 +1,100 @@
+package broker
+
+import (
+	"sort"
+	"sync"
+)
+
+type memoryMember struct {
+	data        []byte
+	seq         uint64
+	connections int64
+}
+
+type memoryPresence struct {
+	mutex sync.Mutex
+	rooms map[string]map[string]*memoryMember
+	seq   uint64
+}
+
+func newMemoryPresence() *memoryPresence {
+	return &memoryPresence{
+		rooms: make(map[string]map[string]*memoryMember),
+	}
+}
+
+func (p *memoryPresence) Connect(room string, member string, data []byte) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	members, ok := p.rooms[room]
+
+	if !ok {
+		members = make(map[string]*memoryMember)
+		p.rooms[room] = members
+	}
+
+	if item, ok := members[member]; ok {
+		item.connections++
+		return false, nil
+	}
+
+	p.seq++
+	members[member] = &memoryMember{data: data, seq: p.seq, connections: 1}
+	return true, nil
+}
+
+func (p *memoryPresence) Disconnect(room string, member string) (int64, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	item, ok := p.rooms[room][member]
+
+	if !ok {
+		return 0, nil
+	}
+
+	if item.connections > 0 {
+		item.connections--
+	}
+
+	return item.connections, nil
+}
+
+func (p *memoryPresence) Remove(room string, member string) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	members := p.rooms[room]
+	item, ok := members[member]
+
+	if !ok || item.connections > 0 {
+		return false, nil
+	}
+
+	delete(members, member)
+
+	if len(members) == 0 {
+		delete(p.rooms, room)
+	}
+
+	return true, nil
+}
+
+func (p *memoryPresence) Members(room string) ([][]byte, error) {
+	p.mutex.Lock()
+	items := make([]*memoryMember, 0, len(p.rooms[room]))
+
+	for _, item := range p.rooms[room] {
+		items = append(items, item)
+	}
+
+	p.mutex.Unlock()
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].seq < items[j].seq
+	})
+	result := make([][]byte, len(items))
+
+	for i, item := range items {
+		result[i] = item.data
+	}
+
+	return result, nil
+}
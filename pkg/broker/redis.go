@@ -0,0 +1,139 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var errInvalidMessage = errors.New("invalid broker message")
+
+// publishScript numbers and publishes the message at once, so the messages of a stream reach the listeners
+// in the order of their numbers. KEYS: stream sequence. ARGV: channel, message, ttl in milliseconds.
+var publishScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+redis.call('PUBLISH', ARGV[1], seq .. ':' .. ARGV[2])
+return seq
+`)
+
+type redisBroker struct {
+	client  *redis.Client
+	channel string
+	mutex   sync.Mutex
+	subs    []*redis.PubSub
+}
+
+func NewRedisBroker(connectionString string, channel string) (Broker, error) {
+	options, err := redis.ParseURL(connectionString)
+
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(options)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err = client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return &redisBroker{
+		client:  client,
+		channel: channel,
+		mutex:   sync.Mutex{},
+	}, nil
+}
+
+func (b *redisBroker) Publish(stream string, message []byte) error {
+	keys := []string{b.seqKey(stream)}
+	return publishScript.Run(context.Background(), b.client, keys, b.channel, message, streamTTL.Milliseconds()).Err()
+}
+
+func (b *redisBroker) Listen(handler func(seq uint64, message []byte)) error {
+	ctx := context.Background()
+	sub := b.client.Subscribe(ctx, b.channel)
+
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return err
+	}
+
+	b.mutex.Lock()
+	b.subs = append(b.subs, sub)
+	b.mutex.Unlock()
+
+	go func() {
+		for message := range sub.Channel() {
+			seq, payload, err := parseMessage(message.Payload)
+
+			if err != nil {
+				continue
+			}
+
+			handler(seq, payload)
+		}
+	}()
+
+	return nil
+}
+
+func (b *redisBroker) Seq(stream string) (uint64, error) {
+	seq, err := b.client.Get(context.Background(), b.seqKey(stream)).Uint64()
+
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+
+	return seq, err
+}
+
+func (b *redisBroker) Presence() Presence {
+	return &redisPresence{
+		client: b.client,
+		prefix: b.channel + ":presence:",
+	}
+}
+
+func (b *redisBroker) Close() error {
+	b.mutex.Lock()
+	subs := b.subs
+	b.subs = nil
+	b.mutex.Unlock()
+
+	for _, sub := range subs {
+		if err := sub.Close(); err != nil {
+			return err
+		}
+	}
+
+	return b.client.Close()
+}
+
+func (b *redisBroker) seqKey(stream string) string {
+	return b.channel + ":seq:" + stream
+}
+
+// parseMessage splits the payload published by publishScript into the number and the message.
+func parseMessage(payload string) (uint64, []byte, error) {
+	seqValue, message, ok := strings.Cut(payload, ":")
+
+	if !ok {
+		return 0, nil, errInvalidMessage
+	}
+
+	seq, err := strconv.ParseUint(seqValue, 10, 64)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return seq, []byte(message), nil
+}
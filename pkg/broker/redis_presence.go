@@ -0,0 +1,130 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// presenceTTL bounds how long the members of an instance that stopped without disconnecting them
+// stay present: the room keys expire when nobody connects or disconnects for that long.
+const presenceTTL = time.Hour * 24
+
+var errInvalidMember = errors.New("invalid presence member")
+
+// KEYS: connections hash, members hash, members sequence. ARGV: member, data, ttl in milliseconds.
+var connectScript = redis.NewScript(`
+redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+local added = 0
+if redis.call('HEXISTS', KEYS[2], ARGV[1]) == 0 then
+	local seq = redis.call('INCR', KEYS[3])
+	redis.call('HSET', KEYS[2], ARGV[1], seq .. ':' .. ARGV[2])
+	added = 1
+end
+for i = 1, 3 do
+	redis.call('PEXPIRE', KEYS[i], ARGV[3])
+end
+return added
+`)
+
+var disconnectScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+local connections = redis.call('HINCRBY', KEYS[1], ARGV[1], -1)
+if connections < 0 then
+	redis.call('HSET', KEYS[1], ARGV[1], 0)
+	connections = 0
+end
+for i = 1, 3 do
+	redis.call('PEXPIRE', KEYS[i], ARGV[2])
+end
+return connections
+`)
+
+var removeScript = redis.NewScript(`
+if tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0') > 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[1], ARGV[1])
+return redis.call('HDEL', KEYS[2], ARGV[1])
+`)
+
+// redisPresence keeps the connections count and the data of every member in hashes,
+// the scripts make the check and the update of both hashes atomic.
+type redisPresence struct {
+	client *redis.Client
+	prefix string
+}
+
+func (p *redisPresence) Connect(room string, member string, data []byte) (bool, error) {
+	added, err := connectScript.Run(context.Background(), p.client, p.keys(room), member, data, presenceTTL.Milliseconds()).Int()
+
+	if err != nil {
+		return false, err
+	}
+
+	return added == 1, nil
+}
+
+func (p *redisPresence) Disconnect(room string, member string) (int64, error) {
+	return disconnectScript.Run(context.Background(), p.client, p.keys(room), member, presenceTTL.Milliseconds()).Int64()
+}
+
+func (p *redisPresence) Remove(room string, member string) (bool, error) {
+	removed, err := removeScript.Run(context.Background(), p.client, p.keys(room), member).Int()
+
+	if err != nil {
+		return false, err
+	}
+
+	return removed == 1, nil
+}
+
+func (p *redisPresence) Members(room string) ([][]byte, error) {
+	values, err := p.client.HGetAll(context.Background(), p.keys(room)[1]).Result()
+
+	if err != nil {
+		return nil, err
+	}
+
+	seqs := make([]uint64, 0, len(values))
+	items := make(map[uint64][]byte, len(values))
+
+	for _, value := range values {
+		seqValue, data, ok := strings.Cut(value, ":")
+
+		if !ok {
+			return nil, errInvalidMember
+		}
+
+		seq, err := strconv.ParseUint(seqValue, 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		seqs = append(seqs, seq)
+		items[seq] = []byte(data)
+	}
+
+	sort.Slice(seqs, func(i, j int) bool {
+		return seqs[i] < seqs[j]
+	})
+	result := make([][]byte, len(seqs))
+
+	for i, seq := range seqs {
+		result[i] = items[seq]
+	}
+
+	return result, nil
+}
+
+func (p *redisPresence) keys(room string) []string {
+	return []string{p.prefix + room + ":connections", p.prefix + room + ":members", p.prefix + room + ":seq"}
+}
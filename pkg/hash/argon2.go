@@ -0,0 +1,167 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+const (
+	minArgon2idSaltLength = 8
+	minArgon2idKeyLength  = 16
+)
+
+var (
+	ErrInvalidHashFormat = errors.New("invalid password hash format")
+	ErrInvalidParams     = errors.New("invalid argon2id params")
+)
+
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Validate rejects the params argon2 panics or silently weakens the hash with.
+func (p Argon2idParams) Validate() error {
+	switch {
+	case p.Parallelism == 0:
+		return fmt.Errorf("%w: parallelism must be at least 1", ErrInvalidParams)
+	case p.Iterations == 0:
+		return fmt.Errorf("%w: iterations must be at least 1", ErrInvalidParams)
+	case p.Memory < 8*uint32(p.Parallelism):
+		return fmt.Errorf("%w: memory must be at least 8 KB per thread, %d KB for %d threads", ErrInvalidParams, 8*uint32(p.Parallelism), p.Parallelism)
+	case p.SaltLength < minArgon2idSaltLength:
+		return fmt.Errorf("%w: salt length must be at least %d bytes", ErrInvalidParams, minArgon2idSaltLength)
+	case p.KeyLength < minArgon2idKeyLength:
+		return fmt.Errorf("%w: key length must be at least %d bytes", ErrInvalidParams, minArgon2idKeyLength)
+	default:
+		return nil
+	}
+}
+
+type argon2idPasswordHashManager struct {
+	params Argon2idParams
+	legacy PasswordHashManager
+}
+
+// NewArgon2idPasswordHashManager hashes passwords with argon2id and stores them in the PHC string format,
+// e.g. $argon2id$v=19$m=65536,t=3,p=2$salt$key. Hashes in any other format are verified by legacy
+// (if it is not nil) and always reported as needing a rehash.
+func NewArgon2idPasswordHashManager(params Argon2idParams, legacy PasswordHashManager) PasswordHashManager {
+	return &argon2idPasswordHashManager{
+		params: params,
+		legacy: legacy,
+	}
+}
+
+func (m *argon2idPasswordHashManager) HashPassword(password string) (string, error) {
+	salt := make([]byte, m.params.SaltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, m.params.Iterations, m.params.Memory, m.params.Parallelism, m.params.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		m.params.Memory,
+		m.params.Iterations,
+		m.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (m *argon2idPasswordHashManager) VerifyPassword(password string, hashedPassword string) (bool, error) {
+	if !strings.HasPrefix(hashedPassword, argon2idPrefix) {
+		if m.legacy == nil {
+			return false, ErrInvalidHashFormat
+		}
+
+		return m.legacy.VerifyPassword(password, hashedPassword)
+	}
+
+	params, salt, key, err := decodeArgon2idHash(hashedPassword)
+
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (m *argon2idPasswordHashManager) NeedsRehash(hashedPassword string) bool {
+	if !strings.HasPrefix(hashedPassword, argon2idPrefix) {
+		return true
+	}
+
+	params, salt, _, err := decodeArgon2idHash(hashedPassword)
+
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != m.params.Memory ||
+		params.Iterations != m.params.Iterations ||
+		params.Parallelism != m.params.Parallelism ||
+		params.KeyLength != m.params.KeyLength ||
+		uint32(len(salt)) != m.params.SaltLength
+}
+
+func decodeArgon2idHash(hashedPassword string) (Argon2idParams, []byte, []byte, error) {
+	parts := strings.Split(hashedPassword, "$")
+
+	if len(parts) != 6 {
+		return Argon2idParams{}, nil, nil, ErrInvalidHashFormat
+	}
+
+	var version int
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, ErrInvalidHashFormat
+	}
+
+	params := Argon2idParams{}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil ||
+		params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return Argon2idParams{}, nil, nil, ErrInvalidHashFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+
+	if err != nil {
+		return Argon2idParams{}, nil, nil, ErrInvalidHashFormat
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+
+	if err != nil || len(key) == 0 {
+		return Argon2idParams{}, nil, nil, ErrInvalidHashFormat
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
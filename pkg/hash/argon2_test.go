@@ -0,0 +1,94 @@
+package hash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestArgon2idPasswordHashManager(t *testing.T) {
+	legacy, err := NewHMACPasswordHashManager("fbac497e4b44564f831f78d539b81a0c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	manager := NewArgon2idPasswordHashManager(params, legacy)
+	hashed, err := manager.HashPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(hashed, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("Incorrect hash format: %s", hashed)
+	}
+
+	assertVerify(t, manager, "password", hashed, true)
+	assertVerify(t, manager, "wrong_password", hashed, false)
+
+	if manager.NeedsRehash(hashed) {
+		t.Fatal("Hash with current params should not need rehash")
+	}
+
+	stronger := NewArgon2idPasswordHashManager(Argon2idParams{Memory: 2048, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}, legacy)
+	assertVerify(t, stronger, "password", hashed, true)
+
+	if !stronger.NeedsRehash(hashed) {
+		t.Fatal("Hash with outdated params should need rehash")
+	}
+
+	legacyHashed, err := legacy.HashPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertVerify(t, manager, "password", legacyHashed, true)
+	assertVerify(t, manager, "wrong_password", legacyHashed, false)
+
+	if !manager.NeedsRehash(legacyHashed) {
+		t.Fatal("Legacy hash should need rehash")
+	}
+
+	if _, err = NewArgon2idPasswordHashManager(params, nil).VerifyPassword("password", legacyHashed); err != ErrInvalidHashFormat {
+		t.Fatalf("Expected error: %v\nActual error: %v", ErrInvalidHashFormat, err)
+	}
+
+	if _, err = manager.VerifyPassword("password", "$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5"); err != ErrInvalidHashFormat {
+		t.Fatalf("Expected error: %v\nActual error: %v", ErrInvalidHashFormat, err)
+	}
+}
+
+func assertVerify(t *testing.T, manager PasswordHashManager, password string, hashed string, expected bool) {
+	t.Helper()
+	actual, err := manager.VerifyPassword(password, hashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual != expected {
+		t.Fatalf("Incorrect verify result for %s\nExpected: %v\nActual: %v", password, expected, actual)
+	}
+}
+
+func TestArgon2idParamsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Argon2idParams
+		valid  bool
+	}{
+		{name: "default", params: DefaultArgon2idParams(), valid: true},
+		{name: "zero parallelism", params: Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 0, SaltLength: 16, KeyLength: 32}},
+		{name: "zero iterations", params: Argon2idParams{Memory: 1024, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32}},
+		{name: "memory below 8 KB per thread", params: Argon2idParams{Memory: 15, Iterations: 1, Parallelism: 2, SaltLength: 16, KeyLength: 32}},
+		{name: "short salt", params: Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 4, KeyLength: 32}},
+		{name: "short key", params: Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.params.Validate(); (err == nil) != test.valid || err != nil && !errors.Is(err, ErrInvalidParams) {
+				t.Fatalf("Expected valid: %v\nActual error: %v", test.valid, err)
+			}
+		})
+	}
+}
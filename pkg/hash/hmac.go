@@ -0,0 +1,57 @@
+package hash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+type hmacPasswordHashManager struct {
+	key []byte
+}
+
+func NewHMACPasswordHashManager(key string) (PasswordHashManager, error) {
+	bytes, err := hex.DecodeString(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &hmacPasswordHashManager{
+		key: bytes,
+	}, nil
+}
+
+func (m *hmacPasswordHashManager) HashPassword(password string) (string, error) {
+	h := hmac.New(sha256.New, m.key)
+	_, err := h.Write([]byte(password))
+
+	if err != nil {
+		return "", err
+	}
+
+	result := h.Sum(nil)
+	return hex.EncodeToString(result), nil
+}
+
+func (m *hmacPasswordHashManager) VerifyPassword(password string, hashedPassword string) (bool, error) {
+	hashedPasswordBytes, err := hex.DecodeString(hashedPassword)
+
+	if err != nil {
+		return false, err
+	}
+
+	h := hmac.New(sha256.New, m.key)
+	_, err = h.Write([]byte(password))
+
+	if err != nil {
+		return false, err
+	}
+
+	result := h.Sum(nil)
+	return hmac.Equal(result, hashedPasswordBytes), nil
+}
+
+func (m *hmacPasswordHashManager) NeedsRehash(hashedPassword string) bool {
+	return true
+}
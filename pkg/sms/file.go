@@ -0,0 +1,43 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type fileSender struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func NewFileSender(path string) (SMSSender, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return nil, err
+	}
+
+	return &fileSender{
+		path:  path,
+		mutex: sync.Mutex{},
+	}, nil
+}
+
+func (s *fileSender) Send(ctx context.Context, phone string, message string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintf(file, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), phone, message); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
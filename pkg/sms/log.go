@@ -0,0 +1,23 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+)
+
+type logSender struct {
+	logger logger.Logger
+}
+
+func NewLogSender(logger logger.Logger) SMSSender {
+	return &logSender{
+		logger: logger,
+	}
+}
+
+func (s *logSender) Send(ctx context.Context, phone string, message string) error {
+	s.logger.LogInfo(fmt.Sprintf("sms to %s: %s", phone, message))
+	return nil
+}
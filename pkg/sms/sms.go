@@ -0,0 +1,30 @@
+package sms
+
+import (
+	"context"
+	"errors"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/pkg/logger"
+)
+
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+)
+
+var ErrUnknownDriver = errors.New("unknown sms driver")
+
+type SMSSender interface {
+	Send(ctx context.Context, phone string, message string) error
+}
+
+func NewSMSSender(driver string, filePath string, logger logger.Logger) (SMSSender, error) {
+	switch driver {
+	case DriverLog:
+		return NewLogSender(logger), nil
+	case DriverFile:
+		return NewFileSender(filePath)
+	default:
+		return nil, ErrUnknownDriver
+	}
+}
@@ -1,16 +1,15 @@
 package storage
 
 import (
+	"context"
+	"io"
 	"os"
-	"sync"
 
 	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
 )
 
 type localFileStorage struct {
-	path    string
-	mutex   sync.RWMutex
-	mutexes map[string]*sync.RWMutex
+	path string
 }
 
 func NewLocalFileStorage(path string) (FileStorage, error) {
@@ -21,17 +20,12 @@ func NewLocalFileStorage(path string) (FileStorage, error) {
 	}
 
 	return &localFileStorage{
-		path:    path,
-		mutex:   sync.RWMutex{},
-		mutexes: make(map[string]*sync.RWMutex),
+		path: path,
 	}, nil
 }
 
-func (s *localFileStorage) Get(id string) ([]byte, error) {
-	mutex := s.getMutex(id)
-	mutex.RLock()
-	defer mutex.RUnlock()
-	file, err := os.OpenFile(s.path+"/"+id, os.O_RDONLY, 0777)
+func (s *localFileStorage) Get(_ context.Context, id string) (io.ReadSeekCloser, error) {
+	file, err := os.Open(s.path + "/" + id)
 
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -40,76 +34,49 @@ func (s *localFileStorage) Get(id string) ([]byte, error) {
 		return nil, err
 	}
 
-	defer file.Close()
-	stat, err := file.Stat()
-
-	if err != nil {
-		return nil, err
-	}
+	return file, nil
+}
 
-	result := make([]byte, stat.Size())
-	_, err = file.Read(result)
+// Upload writes to a temporary file and renames it, so readers of the old file are not affected.
+func (s *localFileStorage) Upload(_ context.Context, id string, _ string, data io.Reader, _ int64) error {
+	file, err := os.CreateTemp(s.path, ".upload-*")
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return result, nil
-}
-
-func (s *localFileStorage) Upload(id string, data []byte) error {
-	mutex := s.getMutex(id)
-	mutex.Lock()
-	defer mutex.Unlock()
-	file, err := os.OpenFile(s.path+"/"+id, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777)
-
-	if err != nil {
+	if _, err = io.Copy(file, data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
 		return err
 	}
 
-	defer file.Close()
-	_, err = file.Write(data)
+	if err = file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
 
-	if err != nil {
+	if err = os.Rename(file.Name(), s.path+"/"+id); err != nil {
+		_ = os.Remove(file.Name())
 		return err
 	}
 
 	return nil
 }
 
-func (s *localFileStorage) Delete(id string) error {
-	mutex := s.getMutex(id)
-	mutex.Lock()
-	defer mutex.Unlock()
+func (s *localFileStorage) Delete(_ context.Context, id string) error {
 	err := os.Remove(s.path + "/" + id)
 
 	if err != nil {
 		if os.IsNotExist(err) {
-			delete(s.mutexes, id)
 			return domain.ErrMediaNotFound
 		}
 		return err
 	}
 
-	delete(s.mutexes, id)
 	return nil
 }
 
-func (s *localFileStorage) getMutex(id string) *sync.RWMutex {
-	s.mutex.RLock()
-	mutex := s.mutexes[id]
-	s.mutex.RUnlock()
-
-	if mutex == nil {
-		mutex = &sync.RWMutex{}
-		s.mutex.Lock()
-		s.mutexes[id] = mutex
-		s.mutex.Unlock()
-	}
-
-	return mutex
-}
-
 func initFileDir(path string) error {
 	_, err := os.Stat(path)
 
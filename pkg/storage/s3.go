@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type S3Options struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool
+	// PresignExpiration enables redirects to presigned urls instead of serving files through the API.
+	PresignExpiration time.Duration
+}
+
+type s3FileStorage struct {
+	client            *minio.Client
+	bucket            string
+	presignExpiration time.Duration
+}
+
+// NewS3FileStorage works with any S3-compatible storage, like MinIO, and creates the bucket if it doesn't exist.
+func NewS3FileStorage(options S3Options) (FileStorage, error) {
+	client, err := minio.New(options.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(options.AccessKey, options.SecretKey, ""),
+		Secure:       options.UseSSL,
+		Region:       options.Region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, options.Bucket)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		if err = client.MakeBucket(ctx, options.Bucket, minio.MakeBucketOptions{Region: options.Region}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &s3FileStorage{
+		client:            client,
+		bucket:            options.Bucket,
+		presignExpiration: options.PresignExpiration,
+	}, nil
+}
+
+func (s *s3FileStorage) Get(ctx context.Context, id string) (io.ReadSeekCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, id, minio.GetObjectOptions{})
+
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+
+	// the object is fetched lazily, stat reports a missing one right away
+	if _, err = object.Stat(); err != nil {
+		_ = object.Close()
+		return nil, mapS3Error(err)
+	}
+
+	return object, nil
+}
+
+func (s *s3FileStorage) Upload(ctx context.Context, id string, contentType string, data io.Reader, size int64) error {
+	_, err := s.client.PutObject(ctx, s.bucket, id, data, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *s3FileStorage) Delete(ctx context.Context, id string) error {
+	return s.client.RemoveObject(ctx, s.bucket, id, minio.RemoveObjectOptions{})
+}
+
+// GetURL overrides the stored content type in the response, so the files uploaded without it are served right too.
+func (s *s3FileStorage) GetURL(ctx context.Context, id string, contentType string) (string, error) {
+	if s.presignExpiration <= 0 {
+		return "", nil
+	}
+
+	params := url.Values{}
+	params.Set("response-content-type", contentType)
+	presignedURL, err := s.client.PresignedGetObject(ctx, s.bucket, id, s.presignExpiration, params)
+
+	if err != nil {
+		return "", err
+	}
+
+	return presignedURL.String(), nil
+}
+
+func mapS3Error(err error) error {
+	if response := minio.ToErrorResponse(err); response.Code == "NoSuchKey" || response.StatusCode == http.StatusNotFound {
+		return domain.ErrMediaNotFound
+	}
+
+	return err
+}
@@ -1,7 +1,72 @@
 package storage
 
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var ErrUnknownDriver = errors.New("unknown storage driver")
+
 type FileStorage interface {
-	Get(id string) ([]byte, error)
-	Upload(id string, data []byte) error
-	Delete(id string) error
+	// Get opens the file for reading, the caller closes it.
+	Get(ctx context.Context, id string) (io.ReadSeekCloser, error)
+	// Upload streams size bytes of data into the file, replacing the old content only when the whole data is read.
+	// The content type is kept with the file by the storages that serve it to clients directly.
+	Upload(ctx context.Context, id string, contentType string, data io.Reader, size int64) error
+	Delete(ctx context.Context, id string) error
+}
+
+// URLSigner is implemented by storages that can give clients a temporary direct link to a file,
+// the link serves the file with contentType. GetURL returns an empty string when the links are disabled.
+type URLSigner interface {
+	GetURL(ctx context.Context, id string, contentType string) (string, error)
+}
+
+func NewFileStorage(driver string, localPath string, s3Options S3Options) (FileStorage, error) {
+	switch driver {
+	case DriverLocal:
+		return NewLocalFileStorage(localPath)
+	case DriverS3:
+		return NewS3FileStorage(s3Options)
+	default:
+		return nil, ErrUnknownDriver
+	}
+}
+
+type limitedReader struct {
+	reader io.Reader
+	left   int64
+}
+
+// LimitReader fails with domain.ErrFileTooLarge once more than maxSize bytes are read.
+func LimitReader(reader io.Reader, maxSize int64) io.Reader {
+	return &limitedReader{reader: reader, left: maxSize}
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.left < 0 {
+		return 0, domain.ErrFileTooLarge
+	}
+
+	// one byte over the limit tells a file of exactly maxSize bytes from a larger one
+	if int64(len(p)) > r.left+1 {
+		p = p[:r.left+1]
+	}
+
+	n, err := r.reader.Read(p)
+	r.left -= int64(n)
+
+	if r.left < 0 {
+		return 0, domain.ErrFileTooLarge
+	}
+
+	return n, err
 }
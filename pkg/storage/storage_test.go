@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/vpiska-backend-go/internal/domain"
+)
+
+func TestLocalFileStorage(t *testing.T) {
+	storage, err := NewLocalFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testFileStorage(t, storage)
+}
+
+func TestS3FileStorage(t *testing.T) {
+	s3 := newFakeS3()
+	server := httptest.NewServer(s3)
+	defer server.Close()
+	storage, err := NewS3FileStorage(S3Options{
+		Endpoint:          strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:         "access",
+		SecretKey:         "secret",
+		Bucket:            "media",
+		Region:            "us-east-1",
+		PresignExpiration: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testFileStorage(t, storage)
+	if contentType := s3.contentTypes["media/file_id"]; contentType != "text/plain" {
+		t.Fatalf("Expected content type: text/plain\nActual content type: %s", contentType)
+	}
+
+	url, err := storage.(URLSigner).GetURL(context.Background(), "file_id", "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(url, server.URL+"/media/file_id?") || !strings.Contains(url, "X-Amz-Signature=") ||
+		!strings.Contains(url, "response-content-type=image%2Fpng") {
+		t.Fatalf("Incorrect presigned url: %s", url)
+	}
+}
+
+func TestLimitReader(t *testing.T) {
+	data, err := ioutil.ReadAll(LimitReader(strings.NewReader("12345"), 5))
+	if err != nil || string(data) != "12345" {
+		t.Fatalf("Expected data: 12345\nActual data: %s, error: %v", data, err)
+	}
+
+	if _, err = ioutil.ReadAll(LimitReader(strings.NewReader("123456"), 5)); err != domain.ErrFileTooLarge {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrFileTooLarge, err)
+	}
+}
+
+func testFileStorage(t *testing.T, storage FileStorage) {
+	ctx := context.Background()
+	if _, err := storage.Get(ctx, "file_id"); err != domain.ErrMediaNotFound {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrMediaNotFound, err)
+	}
+
+	for _, data := range []string{"old content", "0123456789"} {
+		if err := storage.Upload(ctx, "file_id", "text/plain", strings.NewReader(data), int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := storage.Get(ctx, "file_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = file.Seek(5, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "56789" {
+		t.Fatalf("Expected data: 56789\nActual data: %s", data)
+	}
+
+	if err = storage.Delete(ctx, "file_id"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = storage.Get(ctx, "file_id"); err != domain.ErrMediaNotFound {
+		t.Fatalf("Expected error: %v\nActual error: %v", domain.ErrMediaNotFound, err)
+	}
+}
+
+// fakeS3 is a MinIO stand-in serving the path-style bucket and object requests the storage makes.
+type fakeS3 struct {
+	mutex        sync.Mutex
+	buckets      map[string]bool
+	objects      map[string][]byte
+	contentTypes map[string]string
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		buckets:      make(map[string]bool),
+		objects:      make(map[string][]byte),
+		contentTypes: make(map[string]string),
+	}
+}
+
+func (s *fakeS3) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	path := strings.TrimPrefix(request.URL.Path, "/")
+
+	if !strings.Contains(path, "/") {
+		switch request.Method {
+		case http.MethodHead:
+			if !s.buckets[path] {
+				writer.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodPut:
+			s.buckets[path] = true
+		default:
+			writer.WriteHeader(http.StatusNotImplemented)
+		}
+		return
+	}
+
+	switch request.Method {
+	case http.MethodPut:
+		data, err := readS3Body(request)
+		if err != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.objects[path] = data
+		s.contentTypes[path] = request.Header.Get("Content-Type")
+		writer.Header().Set("ETag", `"`+strconv.Itoa(len(data))+`"`)
+	case http.MethodGet, http.MethodHead:
+		data, ok := s.objects[path]
+		if !ok {
+			writer.Header().Set("Content-Type", "application/xml")
+			writer.WriteHeader(http.StatusNotFound)
+			_, _ = writer.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`))
+			return
+		}
+
+		writer.Header().Set("ETag", `"`+strconv.Itoa(len(data))+`"`)
+		http.ServeContent(writer, request, "", time.Now(), bytes.NewReader(data))
+	case http.MethodDelete:
+		delete(s.objects, path)
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		writer.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// readS3Body decodes the aws-chunked body the client sends over plain http.
+func readS3Body(request *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(request.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return ioutil.ReadAll(request.Body)
+	}
+
+	reader := bufio.NewReader(request.Body)
+	var result []byte
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.ParseInt(strings.SplitN(strings.TrimSpace(line), ";", 2)[0], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+
+		if size == 0 {
+			return result, nil
+		}
+
+		result = append(result, chunk[:size]...)
+	}
+}